@@ -10,45 +10,61 @@
 package v4l
 
 const (
-	vidioc_querycap           = 0x80685600
-	vidioc_gFmt               = 0xc0cc5604
-	vidioc_sFmt               = 0xc0cc5605
-	vidioc_gParm              = 0xc0cc5615
-	vidioc_sParm              = 0xc0cc5616
-	vidioc_reqbufs            = 0xc0145608
-	vidioc_querybuf           = 0xc0445609
-	vidioc_qbuf               = 0xc044560f
-	vidioc_dqbuf              = 0xc0445611
-	vidioc_streamon           = 0x40045612
-	vidioc_streamoff          = 0x40045613
-	vidioc_cropcap            = 0xc02c563a
-	vidioc_sCrop              = 0x4014563c
-	vidioc_enumstd            = 0xc0485619
-	vidioc_enumFmt            = 0xc0405602
-	vidioc_enumFramesizes     = 0xc02c564a
-	vidioc_enumFrameintervals = 0xc034564b
-	vidioc_queryctrl          = 0xc0445624
-	vidioc_querymenu          = 0xc02c5625
-	vidioc_gCtrl              = 0xc008561b
-	vidioc_sCtrl              = 0xc008561c
-)
-
-const (
-	size_capability     = 104
-	size_format         = 204
-	size_streamparm     = 204
-	size_requestbuffers = 20
-	size_buffer         = 68
-	size_int            = 4
-	size_cropcap        = 44
-	size_crop           = 20
-	size_standard       = 72
-	size_fmtdesc        = 64
-	size_frmsizeenum    = 44
-	size_frmivalenum    = 52
-	size_queryctrl      = 68
-	size_querymenu      = 44
-	size_control        = 8
+	vidioc_querycap             = 0x80685600
+	vidioc_gFmt                 = 0xc0cc5604
+	vidioc_sFmt                 = 0xc0cc5605
+	vidioc_gParm                = 0xc0cc5615
+	vidioc_sParm                = 0xc0cc5616
+	vidioc_reqbufs              = 0xc0145608
+	vidioc_querybuf             = 0xc0445609
+	vidioc_qbuf                 = 0xc044560f
+	vidioc_dqbuf                = 0xc0445611
+	vidioc_streamon             = 0x40045612
+	vidioc_streamoff            = 0x40045613
+	vidioc_cropcap              = 0xc02c563a
+	vidioc_sCrop                = 0x4014563c
+	vidioc_enumstd              = 0xc0485619
+	vidioc_enumFmt              = 0xc0405602
+	vidioc_enumFramesizes       = 0xc02c564a
+	vidioc_enumFrameintervals   = 0xc034564b
+	vidioc_queryctrl            = 0xc0445624
+	vidioc_querymenu            = 0xc02c5625
+	vidioc_gCtrl                = 0xc008561b
+	vidioc_sCtrl                = 0xc008561c
+	vidioc_gPriority            = 0x80045643
+	vidioc_sPriority            = 0x40045644
+	vidioc_gJpegcomp            = 0x808c563d
+	vidioc_sJpegcomp            = 0x408c563e
+	vidioc_subdevGFmt           = 0xc0585604
+	vidioc_subdevSFmt           = 0xc0585605
+	vidioc_subdevGFrameInterval = 0xc0305615
+	vidioc_subdevSFrameInterval = 0xc0305616
+	vidioc_subdevGSelection     = 0xc040563d
+	vidioc_subdevSSelection     = 0xc040563e
+	vidioc_subdevEnumMbusCode   = 0xc0305602
+)
+
+const (
+	size_capability          = 104
+	size_format              = 204
+	size_streamparm          = 204
+	size_requestbuffers      = 20
+	size_buffer              = 68
+	size_int                 = 4
+	size_cropcap             = 44
+	size_crop                = 20
+	size_standard            = 72
+	size_fmtdesc             = 64
+	size_frmsizeenum         = 44
+	size_frmivalenum         = 52
+	size_queryctrl           = 68
+	size_querymenu           = 44
+	size_control             = 8
+	size_jpegcompression     = 140
+	size_subdevFormat        = 88
+	size_subdevFrameInterval = 48
+	size_subdevSelection     = 64
+	size_subdevMbusCodeEnum  = 48
 )
 
 const (
@@ -229,3 +245,49 @@ const (
 	offs_control_id    = 0
 	offs_control_value = 4
 )
+
+const (
+	offs_jpegcompression_quality     = 0
+	offs_jpegcompression_appn        = 4
+	offs_jpegcompression_appLen      = 8
+	offs_jpegcompression_appData     = 12
+	size_jpegcompression_appData     = 60
+	offs_jpegcompression_comLen      = 72
+	offs_jpegcompression_comData     = 76
+	size_jpegcompression_comData     = 60
+	offs_jpegcompression_jpegMarkers = 136
+)
+
+const (
+	offs_subdevFormat_which  = 0
+	offs_subdevFormat_pad    = 4
+	offs_subdevFormat_format = 8
+)
+
+const (
+	offs_mbusFramefmt_width      = 0
+	offs_mbusFramefmt_height     = 4
+	offs_mbusFramefmt_code       = 8
+	offs_mbusFramefmt_field      = 12
+	offs_mbusFramefmt_colorspace = 16
+)
+
+const (
+	offs_subdevFrameInterval_pad      = 0
+	offs_subdevFrameInterval_interval = 4
+)
+
+const (
+	offs_subdevSelection_which  = 0
+	offs_subdevSelection_pad    = 4
+	offs_subdevSelection_target = 8
+	offs_subdevSelection_flags  = 12
+	offs_subdevSelection_r      = 16
+)
+
+const (
+	offs_subdevMbusCodeEnum_pad   = 0
+	offs_subdevMbusCodeEnum_index = 4
+	offs_subdevMbusCodeEnum_code  = 8
+	offs_subdevMbusCodeEnum_which = 12
+)
@@ -0,0 +1,80 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import "syscall"
+
+// A backend carries out the operations a device needs from the system. It's
+// normally a file descriptor, but it can also be a fake device. (see OpenFake)
+type backend interface {
+	// ioctl performs an IOCTL. It returns errors as they are, e.g. as a
+	// syscall.Errno; the package level ioctl function wraps them in an
+	// OpError.
+	ioctl(request uint, argp ioctlArg) error
+
+	// mmap maps a buffer, as given by VIDIOC_QUERYBUF, into memory.
+	mmap(offset int64, length int) ([]byte, error)
+
+	// munmap unmaps a buffer returned by mmap.
+	munmap(b []byte)
+
+	// pollFd returns a file descriptor that becomes readable when a buffer
+	// can be dequeued.
+	pollFd() int
+
+	// sysfsInfo fills in the fields of info that come from sysfs.
+	sysfsInfo(info *DeviceInfo) error
+
+	// close releases the backend.
+	close()
+}
+
+// fileBackend is the backend of a device node.
+type fileBackend int
+
+func (f fileBackend) ioctl(request uint, argp ioctlArg) error {
+	return sysIoctl(int(f), request, argp)
+}
+
+func (f fileBackend) mmap(offset int64, length int) ([]byte, error) {
+	return syscall.Mmap(int(f), offset, length, syscall.PROT_READ, syscall.MAP_SHARED)
+}
+
+func (f fileBackend) munmap(b []byte) {
+	syscall.Munmap(b)
+}
+
+func (f fileBackend) pollFd() int {
+	return int(f)
+}
+
+func (f fileBackend) sysfsInfo(info *DeviceInfo) error {
+	var stat syscall.Stat_t
+	if err := syscall.Fstat(int(f), &stat); err != nil {
+		return &OpError{Op: "fstat", Err: err}
+	}
+	major, minor := devNumbers(uint64(stat.Rdev))
+	readSysfsInfo(info, major, minor)
+	return nil
+}
+
+func (f fileBackend) close() {
+	syscall.Close(int(f))
+}
@@ -36,12 +36,16 @@ type Buffer struct {
 // available, the return value is constant and unaffected by calls to the
 // methods of Buffer. If the data is no longer available, it returns 0.
 func (b *Buffer) Size() int64 {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
 	return int64(len(b.source()))
 }
 
 // Len returns the number of unread bytes in the buffer. If the data is no
 // longer available, it returns 0.
 func (b *Buffer) Len() int {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
 	src := b.source()
 	if src == nil {
 		return 0
@@ -58,6 +62,8 @@ func (b *Buffer) SeqNum() uint32 {
 // Read reads up to len(dst) bytes into dst, and returns the number of bytes
 // read, along with any error encountered.
 func (b *Buffer) Read(dst []byte) (int, error) {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
 	src := b.source()
 	if src == nil {
 		return 0, ErrBufferGone
@@ -74,6 +80,8 @@ func (b *Buffer) Read(dst []byte) (int, error) {
 // and returns the number of bytes read, along with any error encountered.
 // The seek offset is unaffected by ReadAt.
 func (b *Buffer) ReadAt(dst []byte, offset int64) (int, error) {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
 	src := b.source()
 	if src == nil {
 		return 0, ErrBufferGone
@@ -93,6 +101,8 @@ func (b *Buffer) ReadAt(dst []byte, offset int64) (int, error) {
 
 // ReadByte returns the next byte in the buffer.
 func (b *Buffer) ReadByte() (byte, error) {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
 	src := b.source()
 	if src == nil {
 		return 0, ErrBufferGone
@@ -107,6 +117,8 @@ func (b *Buffer) ReadByte() (byte, error) {
 
 // Seek sets the seek offset.
 func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
 	src := b.source()
 	if src == nil {
 		return 0, ErrBufferGone
@@ -133,7 +145,8 @@ func (b *Buffer) Seek(offset int64, whence int) (int64, error) {
 }
 
 // source returns the underlying byte slice of the buffer, or nil, if it's no
-// longer available.
+// longer available. It must be called with b.d.mu held, and the slice must not
+// be used after releasing it, as Close may unmap the memory.
 func (b *Buffer) source() []byte {
 	if b.d.nCaptures != b.n || b.d.bufIndex == noBuffer {
 		return nil
@@ -0,0 +1,199 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+// Package decode turns raw image data captured from a device into images.
+//
+// The format packages under fmt register decoders for their formats, so they
+// have to be imported for Decode to support them, e.g.:
+//
+//	import _ "github.com/korandiz/v4l/fmt/yuyv"
+//
+// The returned images never reference the raw data, so a Buffer or Frame can be
+// reused after decoding.
+package decode
+
+import (
+	"image"
+	"sync"
+
+	"github.com/korandiz/v4l"
+)
+
+// A Layout describes how raw image data is laid out in memory.
+type Layout struct {
+	// FourCC is the code of the pixel format.
+	FourCC v4l.FourCC
+
+	// Width and Height are the image dimensions.
+	Width  int
+	Height int
+
+	// Stride is the distance in bytes between the leftmost pixels of adjacent
+	// lines (of the first plane, for planar formats). It's ignored for
+	// compressed formats.
+	Stride int
+}
+
+// A WrapFunc wraps data laid out according to l in an image of the format's
+// own type, without copying it.
+type WrapFunc func(data []byte, l Layout) (image.Image, error)
+
+// A Func decodes data laid out according to l. If dst is not nil, it's the
+// result of a previous call, which the function may reuse instead of
+// allocating a new image.
+type Func func(dst image.Image, data []byte, l Layout) (image.Image, error)
+
+// An Error is an error message returned by Decode and DecodeInto.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
+}
+
+// ErrUnknownFormat means that no decoder is registered for the pixel format.
+const ErrUnknownFormat = Error("unknown pixel format")
+
+var (
+	mu    sync.RWMutex
+	funcs = make(map[v4l.FourCC]Func)
+)
+
+// Register makes fn the decoder of the format with the given FourCC, replacing
+// any previously registered one.
+func Register(fourcc v4l.FourCC, fn Func) {
+	mu.Lock()
+	funcs[fourcc] = fn
+	mu.Unlock()
+}
+
+// Supported tells whether a decoder is registered for the format.
+func Supported(fourcc v4l.FourCC) bool {
+	mu.RLock()
+	defer mu.RUnlock()
+	return funcs[fourcc] != nil
+}
+
+// DecodeLayout decodes data laid out according to l. The result may reuse dst,
+// if it is compatible with the format and dimensions.
+func DecodeLayout(dst image.Image, data []byte, l Layout) (image.Image, error) {
+	mu.RLock()
+	fn := funcs[l.FourCC]
+	mu.RUnlock()
+	if fn == nil {
+		return nil, ErrUnknownFormat
+	}
+	return fn(dst, data, l)
+}
+
+// RGBA returns dst if it is an *image.RGBA with the given dimensions and
+// origin at (0, 0), or a new one otherwise.
+func RGBA(dst image.Image, width, height int) *image.RGBA {
+	if img, ok := dst.(*image.RGBA); ok && img.Rect == image.Rect(0, 0, width, height) {
+		return img
+	}
+	return image.NewRGBA(image.Rect(0, 0, width, height))
+}
+
+// Gray is like RGBA, except for *image.Gray.
+func Gray(dst image.Image, width, height int) *image.Gray {
+	if img, ok := dst.(*image.Gray); ok && img.Rect == image.Rect(0, 0, width, height) {
+		return img
+	}
+	return image.NewGray(image.Rect(0, 0, width, height))
+}
+
+// Gray16 is like RGBA, except for *image.Gray16.
+func Gray16(dst image.Image, width, height int) *image.Gray16 {
+	if img, ok := dst.(*image.Gray16); ok && img.Rect == image.Rect(0, 0, width, height) {
+		return img
+	}
+	return image.NewGray16(image.Rect(0, 0, width, height))
+}
+
+// YCbCr is like RGBA, except for *image.YCbCr with the given subsampling
+// ratio.
+func YCbCr(dst image.Image, width, height int, ratio image.YCbCrSubsampleRatio) *image.YCbCr {
+	if img, ok := dst.(*image.YCbCr); ok && img.Rect == image.Rect(0, 0, width, height) &&
+		img.SubsampleRatio == ratio {
+		return img
+	}
+	return image.NewYCbCr(image.Rect(0, 0, width, height), ratio)
+}
+
+// RegisterRGBA registers a decoder for the formats with the given FourCCs,
+// which wraps the data with wrap, and converts the result to an *image.RGBA
+// with convert.
+func RegisterRGBA(wrap WrapFunc, convert func(dst *image.RGBA, src image.Image), fourccs ...v4l.FourCC) {
+	register(fourccs, func(dst image.Image, data []byte, l Layout) (image.Image, error) {
+		src, err := wrap(data, l)
+		if err != nil {
+			return nil, err
+		}
+		img := RGBA(dst, l.Width, l.Height)
+		convert(img, src)
+		return img, nil
+	})
+}
+
+// RegisterGray is like RegisterRGBA, except for *image.Gray.
+func RegisterGray(wrap WrapFunc, convert func(dst *image.Gray, src image.Image), fourccs ...v4l.FourCC) {
+	register(fourccs, func(dst image.Image, data []byte, l Layout) (image.Image, error) {
+		src, err := wrap(data, l)
+		if err != nil {
+			return nil, err
+		}
+		img := Gray(dst, l.Width, l.Height)
+		convert(img, src)
+		return img, nil
+	})
+}
+
+// RegisterGray16 is like RegisterRGBA, except for *image.Gray16.
+func RegisterGray16(wrap WrapFunc, convert func(dst *image.Gray16, src image.Image), fourccs ...v4l.FourCC) {
+	register(fourccs, func(dst image.Image, data []byte, l Layout) (image.Image, error) {
+		src, err := wrap(data, l)
+		if err != nil {
+			return nil, err
+		}
+		img := Gray16(dst, l.Width, l.Height)
+		convert(img, src)
+		return img, nil
+	})
+}
+
+// RegisterYCbCr is like RegisterRGBA, except for *image.YCbCr with the given
+// subsampling ratio.
+func RegisterYCbCr(wrap WrapFunc, convert func(dst *image.YCbCr, src image.Image), ratio image.YCbCrSubsampleRatio, fourccs ...v4l.FourCC) {
+	register(fourccs, func(dst image.Image, data []byte, l Layout) (image.Image, error) {
+		src, err := wrap(data, l)
+		if err != nil {
+			return nil, err
+		}
+		img := YCbCr(dst, l.Width, l.Height, ratio)
+		convert(img, src)
+		return img, nil
+	})
+}
+
+func register(fourccs []v4l.FourCC, fn Func) {
+	for _, fourcc := range fourccs {
+		Register(fourcc, fn)
+	}
+}
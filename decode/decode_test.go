@@ -0,0 +1,142 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package decode
+
+import (
+	"image"
+	"testing"
+
+	"github.com/korandiz/v4l"
+)
+
+func TestDecode(t *testing.T) {
+	const fourcc = 'T' | 'E'<<8 | 'S'<<16 | 'T'<<24
+	if Supported(fourcc) {
+		t.Fatalf("TEST: supported before registration\n")
+	}
+	var got Layout
+	Register(fourcc, func(dst image.Image, data []byte, l Layout) (image.Image, error) {
+		got = l
+		img := Gray(dst, l.Width, l.Height)
+		copy(img.Pix, data)
+		return img, nil
+	})
+	t.Cleanup(func() { unregister(fourcc) })
+	if !Supported(fourcc) {
+		t.Fatalf("TEST: not supported after registration\n")
+	}
+
+	cfg := v4l.DeviceConfig{Format: fourcc, Width: 2, Height: 1}
+	info := v4l.BufferInfo{BufferSize: 2, ImageStride: 2}
+	img, err := Decode([]byte{1, 2}, cfg, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Layout{fourcc, 2, 1, 2}); got != want {
+		t.Errorf("layout: got %+v, want %+v\n", got, want)
+	}
+	g, ok := img.(*image.Gray)
+	if !ok || g.Pix[0] != 1 || g.Pix[1] != 2 {
+		t.Errorf("got %#v\n", img)
+	}
+
+	img2, err := DecodeInto(img, []byte{3, 4}, cfg, info)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if img2 != img || g.Pix[0] != 3 || g.Pix[1] != 4 {
+		t.Errorf("DecodeInto: destination not reused\n")
+	}
+
+	cfg.Width = 1
+	if img3, _ := DecodeInto(img, []byte{5}, cfg, info); img3 == img {
+		t.Errorf("DecodeInto: destination of wrong size reused\n")
+	}
+
+	cfg.Format = 'N' | 'O'<<8 | 'N'<<16 | 'E'<<24
+	if _, err := Decode(nil, cfg, info); err != ErrUnknownFormat {
+		t.Errorf("unknown format: got %v, want %v\n", err, ErrUnknownFormat)
+	}
+}
+
+func TestRegisterRGBA(t *testing.T) {
+	const fourcc = 'T' | 'E'<<8 | 'S'<<16 | 'T'<<24
+	var wrapped Layout
+	wrap := func(data []byte, l Layout) (image.Image, error) {
+		wrapped = l
+		if len(data) < l.Width*l.Height {
+			return nil, Error("short")
+		}
+		return &image.Gray{Pix: data, Stride: l.Width, Rect: image.Rect(0, 0, l.Width, l.Height)}, nil
+	}
+	convert := func(dst *image.RGBA, src image.Image) {
+		for i, v := range src.(*image.Gray).Pix {
+			dst.Pix[4*i] = v
+		}
+	}
+	RegisterRGBA(wrap, convert, fourcc)
+	t.Cleanup(func() { unregister(fourcc) })
+
+	l := Layout{FourCC: fourcc, Width: 2, Height: 1, Stride: 2}
+	img, err := DecodeLayout(nil, []byte{1, 2}, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapped != l {
+		t.Errorf("layout: got %+v, want %+v\n", wrapped, l)
+	}
+	if rgba, ok := img.(*image.RGBA); !ok || rgba.Pix[0] != 1 || rgba.Pix[4] != 2 {
+		t.Errorf("got %#v\n", img)
+	}
+	if img2, _ := DecodeLayout(img, []byte{3, 4}, l); img2 != img {
+		t.Errorf("destination not reused\n")
+	}
+	if _, err := DecodeLayout(nil, []byte{1}, l); err != Error("short") {
+		t.Errorf("wrap error: got %v\n", err)
+	}
+}
+
+// unregister removes the decoder of a format registered by a test, so that
+// the tests can be run more than once.
+func unregister(fourcc v4l.FourCC) {
+	mu.Lock()
+	delete(funcs, fourcc)
+	mu.Unlock()
+}
+
+func TestReuse(t *testing.T) {
+	rgba := image.NewRGBA(image.Rect(0, 0, 4, 2))
+	if RGBA(rgba, 4, 2) != rgba {
+		t.Errorf("RGBA: not reused\n")
+	}
+	if RGBA(rgba, 2, 4) == rgba || RGBA(rgba.SubImage(image.Rect(1, 0, 4, 2)), 3, 2) == rgba {
+		t.Errorf("RGBA: reused with wrong bounds\n")
+	}
+	if Gray16(rgba, 4, 2) == nil || Gray(nil, 4, 2) == nil {
+		t.Errorf("nil image\n")
+	}
+	ycc := image.NewYCbCr(image.Rect(0, 0, 4, 2), image.YCbCrSubsampleRatio420)
+	if YCbCr(ycc, 4, 2, image.YCbCrSubsampleRatio420) != ycc {
+		t.Errorf("YCbCr: not reused\n")
+	}
+	if YCbCr(ycc, 4, 2, image.YCbCrSubsampleRatio422) == ycc {
+		t.Errorf("YCbCr: reused with wrong ratio\n")
+	}
+}
@@ -0,0 +1,44 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package decode
+
+import (
+	"image"
+
+	"github.com/korandiz/v4l"
+)
+
+// Decode decodes a frame captured from a device configured with cfg. The data
+// can be a Frame's Data, or the contents of a Buffer.
+func Decode(data []byte, cfg v4l.DeviceConfig, info v4l.BufferInfo) (image.Image, error) {
+	return DecodeInto(nil, data, cfg, info)
+}
+
+// DecodeInto is like Decode, except that it may reuse dst, which should be the
+// result of a previous call, e.g. to avoid allocating a new image per frame.
+// Decoders that can't reuse an image, like the one for MJPEG, ignore dst.
+func DecodeInto(dst image.Image, data []byte, cfg v4l.DeviceConfig, info v4l.BufferInfo) (image.Image, error) {
+	return DecodeLayout(dst, data, Layout{
+		FourCC: cfg.Format,
+		Width:  cfg.Width,
+		Height: cfg.Height,
+		Stride: info.ImageStride,
+	})
+}
@@ -3,3 +3,5 @@ module github.com/korandiz/v4l/demos/streamcam
 go 1.15
 
 require github.com/korandiz/v4l v1.1.0
+
+replace github.com/korandiz/v4l => ../..
@@ -14,7 +14,8 @@
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-// Command streamcam streams MJPEG from a V4L device over HTTP.
+// Command streamcam streams MJPEG from a V4L device over HTTP. If the device is
+// unplugged, it waits for it to come back, and then continues streaming.
 //
 // Command Line
 //
@@ -79,11 +80,11 @@ func main() {
 		*d = devs[0].Path
 	}
 	fmt.Fprintln(os.Stderr, "Using device", *d)
-	cam, err := v4l.Open(*d)
+	cam, err := v4l.OpenResilient(*d)
 	fatal("Open", err)
 
 	if *l {
-		configs, err := cam.ListConfigs()
+		configs, err := cam.Device().ListConfigs()
 		fatal("ListConfigs", err)
 		fmt.Fprintln(os.Stderr, "Supported device configs:")
 		found := false
@@ -100,7 +101,7 @@ func main() {
 		os.Exit(0)
 	}
 
-	cfg, err := cam.GetConfig()
+	cfg, err := cam.Device().GetConfig()
 	fatal("GetConfig", err)
 	cfg.Format = mjpeg.FourCC
 	if *w > 0 {
@@ -117,7 +118,7 @@ func main() {
 	fatal("SetConfig", err)
 	err = cam.TurnOn()
 	fatal("TurnOn", err)
-	cfg, err = cam.GetConfig()
+	cfg, err = cam.Device().GetConfig()
 	fatal("GetConfig", err)
 	if cfg.Format != mjpeg.FourCC {
 		fmt.Fprintln(os.Stderr, "Failed to set MJPEG format.")
@@ -126,7 +127,7 @@ func main() {
 	fmt.Fprintln(os.Stderr, "Actual device config:", cfg2str(cfg))
 
 	if *r {
-		ctrls, err := cam.ListControls()
+		ctrls, err := cam.Device().ListControls()
 		fatal("ListControls", err)
 		for _, ctrl := range ctrls {
 			cam.SetControl(ctrl.CID, ctrl.Default)
@@ -139,6 +140,7 @@ func main() {
 	blank = buf.Bytes()
 
 	go handleInterrupt()
+	go logStates(cam)
 	go stream(cam)
 
 	log.Println("Listening on address", *a)
@@ -217,7 +219,21 @@ func handleInterrupt() {
 	os.Exit(0)
 }
 
-func stream(cam *v4l.Device) {
+func logStates(cam *v4l.ResilientDevice) {
+	for sc := range cam.States() {
+		switch sc.State {
+		case v4l.StateDisconnected:
+			log.Println("Device disconnected:", sc.Err)
+		case v4l.StateReconnected:
+			log.Println("Device reconnected as", sc.Info.Path)
+			if sc.Err != nil {
+				log.Println("Failed to restore settings:", sc.Err)
+			}
+		}
+	}
+}
+
+func stream(cam *v4l.ResilientDevice) {
 	for {
 		buf, err := cam.Capture()
 		if err != nil {
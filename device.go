@@ -20,22 +20,27 @@
 // Package v4l is a facade to the Video4Linux video capture interface.
 package v4l
 
-import "syscall"
+import (
+	"errors"
+	"sync"
+	"syscall"
+)
 
 // Control IDs. Devices may have other controls than these, including custom
 // (driver specific) ones.
 const (
 	// Integer controls
-	CtrlBrightness            = 0x00980900
-	CtrlContrast              = 0x00980901
-	CtrlSaturation            = 0x00980902
-	CtrlHue                   = 0x00980903
-	CtrlGamma                 = 0x00980910
-	CtrlExposure              = 0x00980911
-	CtrlGain                  = 0x00980913
-	CtrlWhiteBalance          = 0x0098091a
-	CtrlSharpness             = 0x0098091b
-	CtrlBacklightCompensation = 0x0098091c
+	CtrlBrightness             = 0x00980900
+	CtrlContrast               = 0x00980901
+	CtrlSaturation             = 0x00980902
+	CtrlHue                    = 0x00980903
+	CtrlGamma                  = 0x00980910
+	CtrlExposure               = 0x00980911
+	CtrlGain                   = 0x00980913
+	CtrlWhiteBalance           = 0x0098091a
+	CtrlSharpness              = 0x0098091b
+	CtrlBacklightCompensation  = 0x0098091c
+	CtrlJPEGCompressionQuality = 0x009d0903
 
 	// Boolean controls
 	CtrlHFlip            = 0x00980914
@@ -56,20 +61,39 @@ const (
 	CtrlDoWhiteBalance = 0x0098090d
 )
 
-// A Device represents a V4L capture device.
+// A Device represents a V4L capture device. It's safe for concurrent use by
+// multiple goroutines, e.g. controls can be changed while another goroutine is
+// waiting in Capture.
 type Device struct {
 	*device
 }
 
 // device is the real representation of Device. The extra level of indirection
-// is there to prevent clients from tampering with the file descriptor and the
-// buffers.
+// is there to prevent clients from tampering with the backend and the buffers.
+//
+// All fields except path are protected by mu. Capture releases mu while it's
+// waiting for a frame; polling keeps track of such waits, so that Close doesn't
+// close the backend and the wake pipe under them. Close wakes them up through the wake
+// pipe.
 type device struct {
 	path      string
-	fd        int
+	mu        sync.Mutex
+	dev       backend
+	wakeR     int
+	wakeW     int
+	closed    bool
+	polling   sync.WaitGroup
 	buffers   [][]byte
 	bufIndex  uint32
 	nCaptures uint64
+	exclusive bool
+	prio      int // of this handle, not reported by VIDIOC_G_PRIORITY
+	prevPrio  int
+
+	// capMu serializes calls to capture. d.mu is released while waiting for
+	// a frame, and a second capture dequeuing a buffer meanwhile would lose
+	// the one dequeued by the first.
+	capMu sync.Mutex
 }
 
 // noBuffer is the value assinged to device.bufIndex when none of the buffers
@@ -98,13 +122,50 @@ type DeviceInfo struct {
 	// Camera tells if the device is a camera. If false, then this is some other
 	// kind of capture device, e.g. an analog TV tuner.
 	Camera bool
+
+	// The remaining fields are read from sysfs, and they are left empty if
+	// the information is not available.
+
+	// VendorID and ProductID are the USB vendor and product IDs of the
+	// device. (e.g. 0x046d and 0x0825)
+	VendorID  uint16
+	ProductID uint16
+
+	// Serial is the serial number of the USB device. Many cheap cameras don't
+	// have one.
+	Serial string
+
+	// Manufacturer is the manufacturer string of the USB device.
+	// (e.g. "Logitech")
+	Manufacturer string
+
+	// Port is the location of the device on the bus, which stays the same as
+	// long as the device is plugged into the same physical port. For USB
+	// devices it's the port path (e.g. "1-1.2"), otherwise it's the bus
+	// address of the parent device (e.g. "0000:05:06.0").
+	Port string
+
+	// Interface is the USB interface number of the device.
+	Interface int
+
+	// Index tells apart the nodes created for the same device. The main video
+	// node normally has index 0.
+	Index int
+
+	// MediaNode is the path of the media controller device the node belongs
+	// to. (e.g. /dev/media0)
+	MediaNode string
+
+	// ByID and ByPath are the persistent symlinks created by udev in
+	// /dev/v4l/by-id and /dev/v4l/by-path, respectively.
+	ByID   string
+	ByPath string
 }
 
 // A DeviceConfig encapsulates the configuration of a capture device.
 type DeviceConfig struct {
-	// Format is the four-character code (FourCC) of the pixel format, with the
-	// first character at the lowest byte.
-	Format uint32
+	// Format is the pixel format. Format.Info describes it, if known.
+	Format FourCC
 
 	// Width and Height specify the image dimensions.
 	Width  int
@@ -112,6 +173,12 @@ type DeviceConfig struct {
 
 	// FPS specifies the frame rate.
 	FPS Frac
+
+	// JPEGQuality is the compression quality of the JPEG based formats (e.g.
+	// mjpeg.FourCC), from 1 to 100. It's 0 for other formats, for devices
+	// without JPEG parameters, and in the results of ListConfigs. Setting it
+	// to 0 leaves the quality as it is. (see also JPEGParams)
+	JPEGQuality int
 }
 
 // A BufferInfo provides information about how image data is laid out in a
@@ -175,63 +242,152 @@ type ControlInfo struct {
 	}
 }
 
+// errCanceled is returned by Device.capture when it's canceled.
+const errCanceled = Error("capture canceled")
+
 // errBadControl is returned by Device.controlInfo when the control is disabled
 // or of unsupported type.
 const errBadControl = Error("control disabled or of unsupported type")
 
 // Open opens the capture device named by path. If the file is not a capture
 // device, it fails with ErrWrongDevice.
+//
+// If path names a recording made with OpenAndRecord, the device is replayed
+// from the recording. Recordings are recognized by their header, so other
+// regular files also fail with ErrWrongDevice.
 func Open(path string) (*Device, error) {
-	// Open the file.
-	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	// Recordings are regular files.
+	var stat syscall.Stat_t
+	if err := syscall.Stat(path, &stat); err == nil && stat.Mode&syscall.S_IFMT == syscall.S_IFREG {
+		b, err := openReplay(path)
+		if err != nil {
+			return nil, err
+		}
+		return openBackend(path, b)
+	}
+
+	b, err := openFile(path)
 	if err != nil {
 		return nil, err
 	}
+	return openBackend(path, b)
+}
+
+// openFile opens a V4L device node. It fails with ErrWrongDevice if the file is
+// something else.
+func openFile(path string) (fileBackend, error) {
+	// Open the file. It's opened in non-blocking mode, so that Capture can
+	// wait for frames and for Close at the same time.
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return -1, &OpError{Op: "open", Path: path, Err: err}
+	}
 
 	// Check if it's a V4L device.
 	var stat syscall.Stat_t
 	if err := syscall.Fstat(fd, &stat); err != nil {
 		syscall.Close(fd)
-		return nil, err
+		return -1, &OpError{Op: "fstat", Path: path, Err: err}
 	}
-	if stat.Mode&syscall.S_IFCHR == 0 || stat.Rdev>>8 != 81 {
+	if major, _ := devNumbers(uint64(stat.Rdev)); stat.Mode&syscall.S_IFCHR == 0 || major != 81 {
 		syscall.Close(fd)
-		return nil, ErrWrongDevice
+		return -1, ErrWrongDevice
 	}
+	return fileBackend(fd), nil
+}
 
+// openBackend creates a Device on top of b, provided that it's a capture
+// device. It takes ownership of b, even if it fails.
+func openBackend(path string, b backend) (*Device, error) {
 	// Check if it's a capture device.
 	var c v4l_capability
-	if err := ioctl_querycap(fd, &c); err != nil {
-		syscall.Close(fd)
-		return nil, err
+	if err := ioctl_querycap(b, &c); err != nil {
+		b.close()
+		return nil, withPath(err, path)
 	}
 	caps := c.capabilities
 	if caps&v4l_capDeviceCaps != 0 {
 		caps = c.deviceCaps
 	}
 	if caps&v4l_capVideoCapture == 0 {
-		syscall.Close(fd)
+		b.close()
 		return nil, ErrWrongDevice
 	}
 
-	d := device{path: path, fd: fd, bufIndex: noBuffer}
-	return &Device{&d}, nil
+	// Create the pipe used by Close to wake up Capture.
+	var wake [2]int
+	if err := syscall.Pipe2(wake[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		b.close()
+		return nil, &OpError{Op: "pipe2", Path: path, Err: err}
+	}
+
+	d := &device{
+		path:     path,
+		dev:      b,
+		wakeR:    wake[0],
+		wakeW:    wake[1],
+		bufIndex: noBuffer,
+		prio:     PriorityInteractive,
+	}
+	return &Device{d}, nil
 }
 
 // Close closes the device, freeing all native resources associated with it. It
 // stops any capture session in progress, and it may also render the contents of
-// previously captured buffers unavailable.
+// previously captured buffers unavailable. A Capture call in progress fails
+// with ErrClosed, and so do later calls to the methods of the device.
 func (d *Device) Close() {
-	d.TurnOff()
-	syscall.Close(d.fd)
-	d.fd = -1
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return
+	}
+	d.closed = true
+	syscall.Write(d.wakeW, []byte{0})
+	d.mu.Unlock()
+
+	d.polling.Wait()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.turnOff()
+	d.close()
+}
+
+// lock locks d.mu, unless the device is closed, in which case it fails with
+// ErrClosed.
+func (d *device) lock() error {
+	d.mu.Lock()
+	if d.closed {
+		d.mu.Unlock()
+		return ErrClosed
+	}
+	return nil
+}
+
+// close releases the backend and closes the wake pipe. It must be called with
+// d.mu held.
+func (d *device) close() {
+	d.closed = true
+	d.dev.close()
+	d.dev = nil
+	if d.wakeR >= 0 {
+		syscall.Close(d.wakeR)
+		syscall.Close(d.wakeW)
+		d.wakeR, d.wakeW = -1, -1
+	}
 }
 
 // DeviceInfo returns information about the device.
-func (d *Device) DeviceInfo() (DeviceInfo, error) {
+func (d *Device) DeviceInfo() (_ DeviceInfo, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return DeviceInfo{}, err
+	}
+	defer d.mu.Unlock()
+
 	// Query capabilities.
 	var c v4l_capability
-	if err := ioctl_querycap(d.fd, &c); err != nil {
+	if err := ioctl_querycap(d.dev, &c); err != nil {
 		return DeviceInfo{}, err
 	}
 
@@ -239,10 +395,10 @@ func (d *Device) DeviceInfo() (DeviceInfo, error) {
 	// standards.
 	s := v4l_standard{index: 0}
 	var cam bool
-	switch err := ioctl_enumstd(d.fd, &s); err {
-	case nil:
+	switch err := ioctl_enumstd(d.dev, &s); {
+	case err == nil:
 		cam = false
-	case syscall.ENOTTY, syscall.EINVAL:
+	case errors.Is(err, syscall.ENOTTY), errors.Is(err, syscall.EINVAL):
 		cam = true
 	default:
 		return DeviceInfo{}, err
@@ -260,42 +416,67 @@ func (d *Device) DeviceInfo() (DeviceInfo, error) {
 		},
 		Camera: cam,
 	}
+
+	// Add whatever sysfs knows about the device.
+	if err := d.dev.sysfsInfo(&info); err != nil {
+		return DeviceInfo{}, err
+	}
+
 	return info, nil
 }
 
 // TurnOn initiates a capture session with the device. It may fail with
 // ErrUnsupported. While the device is turned on, its configuration cannot be
 // changed.
-func (d *Device) TurnOn() error {
+func (d *Device) TurnOn() (err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return err
+	}
+	defer d.mu.Unlock()
+
+	// In exclusive mode, keep everyone else from changing the configuration
+	// while the device is on.
+	if d.exclusive {
+		if err := d.raisePriority(); err != nil {
+			return err
+		}
+		defer func() {
+			if err != nil {
+				d.restorePriority()
+			}
+		}()
+	}
+
 	// Switch to progressive format and reset the colorspace to device default.
 	f := v4l_format_pix{typ: v4l_bufTypeVideoCapture}
-	if err := ioctl_gFmt_pix(d.fd, &f); err != nil {
+	if err := ioctl_gFmt_pix(d.dev, &f); err != nil {
 		return err
 	}
 	f.fmt.field = v4l_fieldNone
 	f.fmt.colorspace = v4l_colorspaceDefault
 	f.fmt.priv = 0
-	if err := ioctl_sFmt_pix(d.fd, &f); err != nil {
+	if err := ioctl_sFmt_pix(d.dev, &f); err != nil {
 		return err
 	}
 
 	// Reset cropping.
 	cc := v4l_cropcap{typ: v4l_bufTypeVideoCapture}
-	switch err := ioctl_cropcap(d.fd, &cc); err {
-	case nil:
+	switch err := ioctl_cropcap(d.dev, &cc); {
+	case err == nil:
 		c := v4l_crop{
 			typ: v4l_bufTypeVideoCapture,
 			c:   cc.defrect,
 		}
-		switch err := ioctl_sCrop(d.fd, &c); err {
-		case nil:
+		switch err := ioctl_sCrop(d.dev, &c); {
+		case err == nil:
 			// Success.
-		case syscall.ENOTTY, syscall.EINVAL:
+		case errors.Is(err, syscall.ENOTTY), errors.Is(err, syscall.EINVAL):
 			// VIDIOC_S_CROP unsupported.
 		default:
 			return err
 		}
-	case syscall.ENOTTY:
+	case errors.Is(err, syscall.ENOTTY):
 		// No support for cropping. That's okay.
 	default:
 		return err
@@ -307,7 +488,7 @@ func (d *Device) TurnOn() error {
 	}
 
 	// Start streaming I/O.
-	if err := ioctl_streamon(d.fd, v4l_bufTypeVideoCapture); err != nil {
+	if err := ioctl_streamon(d.dev, v4l_bufTypeVideoCapture); err != nil {
 		d.freeBuffers()
 		return err
 	}
@@ -317,20 +498,30 @@ func (d *Device) TurnOn() error {
 // TurnOff ends the capture session in progress. It does not close the device,
 // so it can be reused for another session.
 func (d *Device) TurnOff() {
-	ioctl_streamoff(d.fd, v4l_bufTypeVideoCapture)
+	if err := d.lock(); err != nil {
+		return
+	}
+	defer d.mu.Unlock()
+	d.turnOff()
+}
+
+// turnOff is the implementation of TurnOff. It must be called with d.mu held.
+func (d *device) turnOff() {
+	ioctl_streamoff(d.dev, v4l_bufTypeVideoCapture)
 	d.freeBuffers()
+	d.restorePriority()
 }
 
 // allocBuffers allocates n buffers in device memory, and mmaps and queues them.
-func (d *Device) allocBuffers(n int) error {
+func (d *device) allocBuffers(n int) error {
 	// Request buffers.
 	rb := v4l_requestbuffers{
 		count:  uint32(n),
 		typ:    v4l_bufTypeVideoCapture,
 		memory: v4l_memoryMmap,
 	}
-	if err := ioctl_reqbufs(d.fd, &rb); err != nil {
-		if err == syscall.EINVAL {
+	if err := ioctl_reqbufs(d.dev, &rb); err != nil {
+		if errors.Is(err, syscall.EINVAL) {
 			// Memory-mapped I/O method unsupported.
 			err = ErrUnsupported
 		}
@@ -348,18 +539,17 @@ func (d *Device) allocBuffers(n int) error {
 			typ:    v4l_bufTypeVideoCapture,
 			memory: v4l_memoryMmap,
 		}
-		if err := ioctl_querybuf(d.fd, &b); err != nil {
+		if err := ioctl_querybuf(d.dev, &b); err != nil {
 			d.freeBuffers()
 			return err
 		}
-		buf, err := syscall.Mmap(d.fd, int64(b.offset), int(b.length),
-			syscall.PROT_READ, syscall.MAP_SHARED)
+		buf, err := d.dev.mmap(int64(b.offset), int(b.length))
 		if err != nil {
 			d.freeBuffers()
-			return err
+			return &OpError{Op: "mmap", Err: err}
 		}
 		d.buffers = append(d.buffers, buf)
-		if err := ioctl_qbuf(d.fd, &b); err != nil {
+		if err := ioctl_qbuf(d.dev, &b); err != nil {
 			d.freeBuffers()
 			return err
 		}
@@ -370,10 +560,10 @@ func (d *Device) allocBuffers(n int) error {
 
 // freeBuffers munmaps and frees any buffers allocated in device memory, and
 // removes all pointers to them.
-func (d *Device) freeBuffers() {
+func (d *device) freeBuffers() {
 	d.bufIndex = noBuffer
 	for i := range d.buffers {
-		syscall.Munmap(d.buffers[i])
+		d.dev.munmap(d.buffers[i])
 		d.buffers[i] = nil
 	}
 	d.buffers = nil
@@ -382,13 +572,30 @@ func (d *Device) freeBuffers() {
 		typ:    v4l_bufTypeVideoCapture,
 		memory: v4l_memoryMmap,
 	}
-	ioctl_reqbufs(d.fd, &rb)
+	ioctl_reqbufs(d.dev, &rb)
 }
 
 // Capture grabs the next frame, and returns a new Buffer holding the raw image
 // data. The device must be turned on for Capture to succeed. A call to Capture
-// may render the contents of previously captured buffers unavailable.
+// may render the contents of previously captured buffers unavailable. While
+// waiting for a frame, Capture doesn't prevent other goroutines from using the
+// device, but concurrent calls to Capture wait for each other.
 func (d *Device) Capture() (*Buffer, error) {
+	return d.capture(-1)
+}
+
+// capture is the implementation of Capture. If cancel is a valid file
+// descriptor, it fails with errCanceled when cancel becomes readable while
+// waiting for a frame.
+func (d *Device) capture(cancel int) (_ *Buffer, err error) {
+	defer d.annotate(&err)
+	d.capMu.Lock()
+	defer d.capMu.Unlock()
+	if err := d.lock(); err != nil {
+		return nil, err
+	}
+	defer d.mu.Unlock()
+
 	d.nCaptures++
 
 	// Enqueue the old buffer (if any).
@@ -399,18 +606,27 @@ func (d *Device) Capture() (*Buffer, error) {
 			index:  d.bufIndex,
 		}
 		d.bufIndex = noBuffer
-		if err := ioctl_qbuf(d.fd, &b); err != nil {
+		if err := ioctl_qbuf(d.dev, &b); err != nil {
 			return nil, err
 		}
 	}
 
-	// Dequeue a new buffer.
+	// Dequeue a new buffer, waiting for one if none is ready yet.
 	b := v4l_buffer{
 		typ:    v4l_bufTypeVideoCapture,
 		memory: v4l_memoryMmap,
 	}
-	if err := ioctl_dqbuf(d.fd, &b); err != nil {
-		return nil, err
+	for {
+		err := ioctl_dqbuf(d.dev, &b)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, syscall.EAGAIN) {
+			return nil, err
+		}
+		if err := d.wait(cancel); err != nil {
+			return nil, err
+		}
 	}
 	d.buffers[b.index] = d.buffers[b.index][:b.bytesused]
 	d.bufIndex = b.index
@@ -418,22 +634,49 @@ func (d *Device) Capture() (*Buffer, error) {
 	return &Buffer{d.device, d.nCaptures, 0, b.sequence}, nil
 }
 
+// wait blocks until the device becomes readable, the device is closed, or
+// cancel becomes readable. It must be called with d.mu held, which it releases
+// while waiting.
+func (d *device) wait(cancel int) error {
+	fd, wakeR := d.dev.pollFd(), d.wakeR
+	d.polling.Add(1)
+	d.mu.Unlock()
+	ready, err := poll(fd, wakeR, cancel)
+	d.polling.Done()
+	d.mu.Lock()
+	switch {
+	case d.closed:
+		return ErrClosed
+	case err != nil:
+		return err
+	case ready[2]:
+		return errCanceled
+	}
+	return nil
+}
+
 // GetConfig returns the current configuration of the device.
-func (d *Device) GetConfig() (DeviceConfig, error) {
+func (d *Device) GetConfig() (_ DeviceConfig, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return DeviceConfig{}, err
+	}
+	defer d.mu.Unlock()
+
 	// Get format.
 	f := v4l_format_pix{typ: v4l_bufTypeVideoCapture}
-	if err := ioctl_gFmt_pix(d.fd, &f); err != nil {
+	if err := ioctl_gFmt_pix(d.dev, &f); err != nil {
 		return DeviceConfig{}, err
 	}
 
 	// Get streaming parameters.
 	p := v4l_streamparm_capture{typ: v4l_bufTypeVideoCapture}
-	if err := ioctl_gParm_capture(d.fd, &p); err != nil {
+	if err := ioctl_gParm_capture(d.dev, &p); err != nil {
 		return DeviceConfig{}, err
 	}
 
 	cfg := DeviceConfig{
-		Format: f.fmt.pixelformat,
+		Format: FourCC(f.fmt.pixelformat),
 		Width:  int(f.fmt.width),
 		Height: int(f.fmt.height),
 		FPS: Frac{
@@ -441,6 +684,14 @@ func (d *Device) GetConfig() (DeviceConfig, error) {
 			p.parm.timeperframe.numerator,
 		},
 	}
+	if isJPEG(cfg.Format) {
+		switch jp, err := d.jpegParams(); {
+		case err == nil:
+			cfg.JPEGQuality = jp.Quality
+		case err != ErrUnsupported:
+			return DeviceConfig{}, err
+		}
+	}
 	return cfg, nil
 }
 
@@ -449,20 +700,26 @@ func (d *Device) GetConfig() (DeviceConfig, error) {
 // adjust the parameters against hardware capabilities (or even completely
 // ignore them). The configuration cannot be changed while the device is turned
 // on.
-func (d *Device) SetConfig(cfg DeviceConfig) error {
+func (d *Device) SetConfig(cfg DeviceConfig) (err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return err
+	}
+	defer d.mu.Unlock()
+
 	// Set format.
 	f := v4l_format_pix{
 		typ: v4l_bufTypeVideoCapture,
 		fmt: v4l_pixFormat{
 			width:       uint32(cfg.Width),
 			height:      uint32(cfg.Height),
-			pixelformat: cfg.Format,
+			pixelformat: uint32(cfg.Format),
 			field:       v4l_fieldNone,
 			colorspace:  v4l_colorspaceDefault,
 			priv:        0,
 		},
 	}
-	if err := ioctl_sFmt_pix(d.fd, &f); err != nil {
+	if err := ioctl_sFmt_pix(d.dev, &f); err != nil {
 		return err
 	}
 
@@ -474,18 +731,31 @@ func (d *Device) SetConfig(cfg DeviceConfig) error {
 			timeperframe: v4l_fract{cfg.FPS.D, cfg.FPS.N},
 		},
 	}
-	if err := ioctl_sParm_capture(d.fd, &p); err != nil {
+	if err := ioctl_sParm_capture(d.dev, &p); err != nil {
 		return err
 	}
 
+	// Set compression quality. The format the driver chose counts.
+	if cfg.JPEGQuality != 0 && isJPEG(FourCC(f.fmt.pixelformat)) {
+		if err := d.setJPEGQuality(cfg.JPEGQuality); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 // BufferInfo returns information about how image data is laid out in a buffer.
 // For the same device configuration it always returns the same value.
-func (d *Device) BufferInfo() (BufferInfo, error) {
+func (d *Device) BufferInfo() (_ BufferInfo, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return BufferInfo{}, err
+	}
+	defer d.mu.Unlock()
+
 	f := v4l_format_pix{typ: v4l_bufTypeVideoCapture}
-	if err := ioctl_gFmt_pix(d.fd, &f); err != nil {
+	if err := ioctl_gFmt_pix(d.dev, &f); err != nil {
 		return BufferInfo{}, err
 	}
 	info := BufferInfo{
@@ -496,15 +766,21 @@ func (d *Device) BufferInfo() (BufferInfo, error) {
 }
 
 // ListConfigs returns the configurations supported by the device.
-func (d *Device) ListConfigs() ([]DeviceConfig, error) {
+func (d *Device) ListConfigs() (_ []DeviceConfig, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return nil, err
+	}
+	defer d.mu.Unlock()
+
 	var cfgs []DeviceConfig
 	for fmt := 0; ; fmt++ {
 		fd := v4l_fmtdesc{
 			index: uint32(fmt),
 			typ:   v4l_bufTypeVideoCapture,
 		}
-		if err := ioctl_enumFmt(d.fd, &fd); err != nil {
-			if err != syscall.EINVAL {
+		if err := ioctl_enumFmt(d.dev, &fd); err != nil {
+			if !errors.Is(err, syscall.EINVAL) {
 				return nil, err
 			}
 			break
@@ -520,7 +796,7 @@ func (d *Device) ListConfigs() ([]DeviceConfig, error) {
 			}
 			for _, ival := range ivals {
 				cfg := DeviceConfig{
-					Format: fd.pixelformat,
+					Format: FourCC(fd.pixelformat),
 					Width:  int(sz.width),
 					Height: int(sz.height),
 					FPS: Frac{
@@ -566,8 +842,8 @@ loop:
 			index:       uint32(index),
 			pixelFormat: fmt,
 		}
-		if err := ioctl_enumFramesizes(d.fd, &fs); err != nil {
-			if err != syscall.EINVAL {
+		if err := ioctl_enumFramesizes(d.dev, &fs); err != nil {
+			if !errors.Is(err, syscall.EINVAL) {
 				return nil, err
 			}
 			return sizes, nil
@@ -639,8 +915,8 @@ loop:
 			width:       w,
 			height:      h,
 		}
-		if err := ioctl_enumFrameintervals(d.fd, &fi); err != nil {
-			if err != syscall.EINVAL {
+		if err := ioctl_enumFrameintervals(d.dev, &fi); err != nil {
+			if !errors.Is(err, syscall.EINVAL) {
 				return nil, err
 			}
 			return ivals, nil
@@ -687,17 +963,58 @@ var defaultFrameIntervals = []v4l_fract{
 }
 
 // ControlInfo returns information about a control.
-func (d *Device) ControlInfo(cid uint32) (ControlInfo, error) {
+func (d *Device) ControlInfo(cid uint32) (_ ControlInfo, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return ControlInfo{}, err
+	}
+	defer d.mu.Unlock()
+	return d.queryControl(cid)
+}
+
+// ListControls returns the ControlInfo for every control the device has.
+func (d *Device) ListControls() (_ []ControlInfo, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return nil, err
+	}
+	defer d.mu.Unlock()
+	return d.listControls()
+}
+
+// GetControl returns the current value of a control.
+func (d *Device) GetControl(cid uint32) (_ int32, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return 0, err
+	}
+	defer d.mu.Unlock()
+	return d.getControl(cid)
+}
+
+// SetControl sets the value of a control.
+func (d *Device) SetControl(cid uint32, value int32) (err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return err
+	}
+	defer d.mu.Unlock()
+	return d.setControl(cid, value)
+}
+
+// queryControl is the implementation of ControlInfo, shared by Device and
+// Subdevice.
+func (d *device) queryControl(cid uint32) (ControlInfo, error) {
 	info, err := d.controlInfo(cid)
 	if err == errBadControl {
 		// Pretend the control does not exist.
-		err = syscall.EINVAL
+		err = &OpError{Op: ioctlName(vidioc_queryctrl), Err: syscall.EINVAL}
 	}
 	return info, err
 }
 
-// ListControls returns the ControlInfo for every control the device has.
-func (d *Device) ListControls() ([]ControlInfo, error) {
+// listControls is the implementation of ListControls.
+func (d *device) listControls() ([]ControlInfo, error) {
 	var (
 		lastCID uint32
 		infos   []ControlInfo
@@ -705,14 +1022,14 @@ func (d *Device) ListControls() ([]ControlInfo, error) {
 
 	for {
 		info, err := d.controlInfo(lastCID | v4l_ctrlFlagNextCtrl)
-		switch err {
-		case nil:
+		switch {
+		case err == nil:
 			infos = append(infos, info)
 			lastCID = info.CID
-		case errBadControl:
+		case err == errBadControl:
 			// Pretend the control does not exist.
 			lastCID = info.CID
-		case syscall.EINVAL:
+		case errors.Is(err, syscall.EINVAL):
 			if lastCID == 0 {
 				// No support for v4l_ctrlFlagNextCtrl.
 				// Fall back to legacy method.
@@ -727,14 +1044,14 @@ func (d *Device) ListControls() ([]ControlInfo, error) {
 
 // listControlsLegacy enumerates all controls the device has by querying them
 // one-by-one rather than using the v4l_ctrlFlagNextCtrl flag.
-func (d *Device) listControlsLegacy() ([]ControlInfo, error) {
+func (d *device) listControlsLegacy() ([]ControlInfo, error) {
 	var infos []ControlInfo
 
 	// Standard controls.
 	for cid := uint32(v4l_cidBase); cid < v4l_cidLastp1; cid++ {
 		info, err := d.controlInfo(cid)
 		if err != nil {
-			if err == syscall.EINVAL || err == errBadControl {
+			if errors.Is(err, syscall.EINVAL) || err == errBadControl {
 				continue
 			}
 			return nil, err
@@ -746,7 +1063,7 @@ func (d *Device) listControlsLegacy() ([]ControlInfo, error) {
 	for cid := uint32(v4l_cidPrivateBase); ; cid++ {
 		info, err := d.controlInfo(cid)
 		if err != nil {
-			if err == syscall.EINVAL {
+			if errors.Is(err, syscall.EINVAL) {
 				break
 			}
 			if err == errBadControl {
@@ -764,7 +1081,7 @@ func (d *Device) listControlsLegacy() ([]ControlInfo, error) {
 // contorls of unsupported type it fails with errBadControl.
 func (d *device) controlInfo(cid uint32) (ControlInfo, error) {
 	qc := v4l_queryctrl{id: cid}
-	if err := ioctl_queryctrl(d.fd, &qc); err != nil {
+	if err := ioctl_queryctrl(d.dev, &qc); err != nil {
 		return ControlInfo{}, err
 	}
 
@@ -802,8 +1119,8 @@ func (d *device) controlInfo(cid uint32) (ControlInfo, error) {
 				id:    qc.id,
 				index: uint32(i),
 			}
-			if err := ioctl_querymenu(d.fd, &qm); err != nil {
-				if err == syscall.EINVAL {
+			if err := ioctl_querymenu(d.dev, &qm); err != nil {
+				if errors.Is(err, syscall.EINVAL) {
 					continue
 				}
 				return ControlInfo{}, err
@@ -827,22 +1144,22 @@ func (d *device) controlInfo(cid uint32) (ControlInfo, error) {
 	return info, nil
 }
 
-// GetControl returns the current value of a control.
-func (d *Device) GetControl(cid uint32) (int32, error) {
+// getControl is the implementation of GetControl.
+func (d *device) getControl(cid uint32) (int32, error) {
 	c := v4l_control{id: cid}
-	if err := ioctl_gCtrl(d.fd, &c); err != nil {
+	if err := ioctl_gCtrl(d.dev, &c); err != nil {
 		return 0, err
 	}
 	return c.value, nil
 }
 
-// SetControl sets the value of a control.
-func (d *Device) SetControl(cid uint32, value int32) error {
+// setControl is the implementation of SetControl.
+func (d *device) setControl(cid uint32, value int32) error {
 	c := v4l_control{
 		id:    cid,
 		value: value,
 	}
-	if err := ioctl_sCtrl(d.fd, &c); err != nil {
+	if err := ioctl_sCtrl(d.dev, &c); err != nil {
 		return err
 	}
 	return nil
@@ -0,0 +1,123 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// TestCloseWakesCapture checks that Close unblocks a goroutine waiting for a
+// frame, and that the device can be used while the wait is in progress. The
+// "device" is the read end of a pipe nobody writes to.
+func TestCloseWakesCapture(t *testing.T) {
+	var dev, wake [2]int
+	if err := syscall.Pipe2(dev[:], syscall.O_CLOEXEC); err != nil {
+		t.Fatal(err)
+	}
+	defer syscall.Close(dev[1])
+	if err := syscall.Pipe2(wake[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		t.Fatal(err)
+	}
+	d := &Device{&device{
+		path:     "/dev/video0",
+		dev:      fileBackend(dev[0]),
+		wakeR:    wake[0],
+		wakeW:    wake[1],
+		bufIndex: noBuffer,
+	}}
+	b := &Buffer{d: d.device}
+
+	errs := make(chan error)
+	go func() {
+		d.mu.Lock()
+		err := d.wait(-1)
+		d.mu.Unlock()
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	_, err := d.GetControl(CtrlBrightness)
+	if !errors.Is(err, ErrUnsupported) {
+		t.Errorf("GetControl: got: %v, expected: ENOTTY\n", err)
+	}
+
+	d.Close()
+	select {
+	case err := <-errs:
+		if err != ErrClosed {
+			t.Errorf("wait: got: %v, expected: %v\n", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("wait not woken up by Close\n")
+		return
+	}
+
+	if _, err := d.Capture(); err != ErrClosed {
+		t.Errorf("Capture: got: %v, expected: %v\n", err, ErrClosed)
+	}
+	if _, err := b.ReadByte(); err != ErrBufferGone {
+		t.Errorf("ReadByte: got: %v, expected: %v\n", err, ErrBufferGone)
+	}
+	d.Close()
+}
+
+func TestConcurrentCapture(t *testing.T) {
+	dev, err := OpenFake(FakeDevice{
+		Configs:  []DeviceConfig{{Format: fourcc("YUYV"), Width: 8, Height: 8, FPS: Frac{200, 1}}},
+		RealTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+
+	errs := make(chan error, 2)
+	for i := 0; i < cap(errs); i++ {
+		go func() {
+			for j := 0; j < 40; j++ {
+				if _, err := dev.Capture(); err != nil {
+					errs <- err
+					return
+				}
+			}
+			errs <- nil
+		}()
+	}
+	for i := 0; i < cap(errs); i++ {
+		if err := <-errs; err != nil {
+			t.Fatalf("Capture: %v\n", err)
+		}
+	}
+
+	// Every buffer but the last one captured must be queued again.
+	dev.mu.Lock()
+	f := dev.dev.(*fakeBackend)
+	queued, total := len(f.queued), len(f.bufs)
+	dev.mu.Unlock()
+	if queued != total-1 {
+		t.Errorf("%d of %d buffers queued, expected %d\n", queued, total, total-1)
+	}
+}
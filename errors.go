@@ -19,6 +19,8 @@
 
 package v4l
 
+import "syscall"
+
 // An Error is simply an error message.
 type Error string
 
@@ -32,11 +34,106 @@ const (
 	// not a V4L capture device.
 	ErrWrongDevice = Error("not a V4L capture device")
 
+	// ErrNotSubdevice is returned by OpenSubdevice when attempting to open a
+	// file that is not a V4L sub-device.
+	ErrNotSubdevice = Error("not a V4L sub-device")
+
 	// ErrUnsupported indicates that an operation failed due to a limitation of
-	// this library.
+	// this library or of the driver. An OpError caused by ENOTTY matches it
+	// according to errors.Is.
 	ErrUnsupported = Error("unsupported device or operation")
 
 	// ErrBufferGone is returned by methods of Buffer when the contents of the
 	// buffer is no longer available.
 	ErrBufferGone = Error("buffer contents not available")
+
+	// ErrNoDevice is returned by OpenBySerial and OpenByPort when there is no
+	// matching device in the system.
+	ErrNoDevice = Error("no matching device found")
+
+	// ErrDisconnected matches an OpError caused by the device going away,
+	// e.g. a USB camera being unplugged.
+	ErrDisconnected = Error("device disconnected")
+
+	// ErrBusy matches an OpError caused by the device being used by someone
+	// else. OpenedBy tells who else has the device open.
+	ErrBusy = Error("device busy")
+
+	// ErrPermission matches an OpError caused by insufficient permissions.
+	ErrPermission = Error("permission denied")
+
+	// ErrClosed is returned by the methods of Device, Subdevice, and
+	// ResilientDevice after they have been closed.
+	ErrClosed = Error("device closed")
+
+	// ErrInvalidFourCC is returned by ParseFourCC for strings that are not
+	// valid four-character codes.
+	ErrInvalidFourCC = Error("invalid FourCC")
+
+	// ErrInvalidPolicy is returned by Stream.Subscribe for unknown
+	// backpressure policies.
+	ErrInvalidPolicy = Error("invalid backpressure policy")
 )
+
+// An OpError records a failed operation on a device. The underlying error can
+// be tested with errors.Is, either against a syscall.Errno or against one of
+// ErrDisconnected, ErrBusy, ErrPermission, and ErrUnsupported.
+type OpError struct {
+	// Op is the operation that failed. It's usually the name of an IOCTL.
+	// (e.g. "VIDIOC_S_FMT")
+	Op string
+
+	// Path is the device path. (e.g. /dev/video0)
+	Path string
+
+	// Err is the underlying error, usually a syscall.Errno.
+	Err error
+}
+
+// Error returns e as a string. (e.g. "VIDIOC_DQBUF /dev/video0: no such
+// device")
+func (e *OpError) Error() string {
+	if e.Path == "" {
+		return e.Op + ": " + e.Err.Error()
+	}
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// Is tells if e belongs to the class of errors represented by target.
+func (e *OpError) Is(target error) bool {
+	errno, ok := e.Err.(syscall.Errno)
+	if !ok {
+		return false
+	}
+	switch target {
+	case ErrDisconnected:
+		return errno == syscall.ENODEV || errno == syscall.ENXIO
+	case ErrBusy:
+		return errno == syscall.EBUSY
+	case ErrPermission:
+		return errno == syscall.EACCES || errno == syscall.EPERM
+	case ErrUnsupported:
+		return errno == syscall.ENOTTY
+	}
+	return false
+}
+
+// annotate sets the Path of *err to the path of d, if *err is an OpError
+// without one. It's meant to be deferred by methods that return errors from
+// the ioctl helpers, which don't know the path.
+func (d *device) annotate(err *error) {
+	*err = withPath(*err, d.path)
+}
+
+// withPath sets the Path of err to path, if err is an OpError without one.
+func withPath(err error, path string) error {
+	if e, ok := err.(*OpError); ok && e.Path == "" {
+		e.Path = path
+	}
+	return err
+}
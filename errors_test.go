@@ -0,0 +1,64 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestOpError(t *testing.T) {
+	for _, x := range []struct {
+		errno   syscall.Errno
+		matches error
+	}{
+		{syscall.ENODEV, ErrDisconnected},
+		{syscall.ENXIO, ErrDisconnected},
+		{syscall.EBUSY, ErrBusy},
+		{syscall.EACCES, ErrPermission},
+		{syscall.EPERM, ErrPermission},
+		{syscall.ENOTTY, ErrUnsupported},
+		{syscall.EINVAL, nil},
+	} {
+		err := error(&OpError{Op: "VIDIOC_DQBUF", Err: x.errno})
+		if !errors.Is(err, x.errno) {
+			t.Errorf("%v does not match %v\n", err, x.errno)
+		}
+		for _, sentinel := range []error{ErrDisconnected, ErrBusy, ErrPermission, ErrUnsupported} {
+			if errors.Is(err, sentinel) != (sentinel == x.matches) {
+				t.Errorf("errors.Is(%v, %v) = %v\n", err, sentinel, !(sentinel == x.matches))
+			}
+		}
+	}
+
+	err := withPath(&OpError{Op: "open", Err: syscall.EACCES}, "/dev/video0")
+	if s := err.Error(); s != "open /dev/video0: permission denied" {
+		t.Errorf("got: %q\n", s)
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		t.Errorf("%v does not match os.ErrPermission\n", err)
+	}
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Path != "/dev/video0" {
+		t.Errorf("errors.As failed: %+v\n", opErr)
+	}
+}
@@ -0,0 +1,804 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// A FakeDevice describes a virtual capture device, which can be opened with
+// OpenFake. It lets applications (and this package) test their capture code on
+// machines without a camera.
+type FakeDevice struct {
+	// Info describes the device. Path, DeviceName, BusInfo, DriverName, and
+	// DriverVersion are reported through VIDIOC_QUERYCAP, while the fields
+	// that normally come from sysfs are reported as they are. Camera is
+	// ignored; a fake device is always a camera.
+	Info DeviceInfo
+
+	// Configs lists the supported configurations. The first one is the
+	// initial configuration. If it's empty, the device supports 640x480 YUYV
+	// at 30 fps.
+	Configs []DeviceConfig
+
+	// Controls lists the controls of the device. The initial value of a
+	// control is its Default.
+	Controls []ControlInfo
+
+	// Pattern, if not nil, fills in the image data of a frame, and returns the
+	// number of bytes used, which must be between 0 and len(data), or
+	// capturing the frame fails. If it's nil, the frames show moving color
+	// bars for YUYV, UYVY, YVYU, VYUY, RGB3, BGR3, GREY, and MJPG, and a byte
+	// ramp for other formats.
+	Pattern func(cfg DeviceConfig, seq uint32, data []byte) int
+
+	// RealTime makes the device deliver frames at the configured frame rate.
+	// Otherwise a new frame is always ready immediately.
+	RealTime bool
+
+	// OtherPriority, if not 0, is the access priority of another handle of
+	// the device, e.g. PriorityRecord simulates someone else recording.
+	OtherPriority int
+
+	// Err, if not nil, is called before every IOCTL with its name.
+	// (e.g. "VIDIOC_DQBUF") If it returns an error, the IOCTL fails with that
+	// error, e.g. syscall.ENODEV simulates a device that has been unplugged.
+	Err func(op string) error
+}
+
+// OpenFake opens a fake device. It behaves like a device opened with Open.
+func OpenFake(spec FakeDevice) (*Device, error) {
+	f, err := newFakeBackend(spec)
+	if err != nil {
+		return nil, err
+	}
+	return openBackend(f.spec.Info.Path, f)
+}
+
+// newFakeBackend creates the backend of a fake device.
+func newFakeBackend(spec FakeDevice) (*fakeBackend, error) {
+	if len(spec.Configs) == 0 {
+		spec.Configs = []DeviceConfig{{Format: fourcc("YUYV"), Width: 640, Height: 480, FPS: Frac{30, 1}}}
+	}
+	if spec.Info.Path == "" {
+		spec.Info.Path = "fake"
+	}
+	if spec.Pattern == nil {
+		spec.Pattern = fakePattern
+	}
+	f := &fakeBackend{
+		spec:  spec,
+		cfg:   spec.Configs[0],
+		ctrls: make(map[uint32]int32),
+		prio:  PriorityInteractive,
+		jpeg: v4l_jpegcompression{
+			quality:     75,
+			jpegMarkers: JPEGMarkerDHT | JPEGMarkerDQT,
+		},
+	}
+	for _, c := range spec.Controls {
+		f.ctrls[c.CID] = c.Default
+	}
+
+	// The ready pipe is readable while a frame can be dequeued.
+	var ready [2]int
+	if err := syscall.Pipe2(ready[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		return nil, &OpError{Op: "pipe2", Path: spec.Info.Path, Err: err}
+	}
+	f.readyR, f.readyW = ready[0], ready[1]
+	if !spec.RealTime {
+		syscall.Write(f.readyW, []byte{0})
+	}
+
+	return f, nil
+}
+
+// fakeBackend is the backend of a fake device. Its methods are called with the
+// mutex of the device held, so only the frame ticker needs synchronization.
+type fakeBackend struct {
+	spec      FakeDevice
+	cfg       DeviceConfig
+	ctrls     map[uint32]int32
+	prio      v4l_int
+	jpeg      v4l_jpegcompression
+	bufs      [][]byte
+	queued    []uint32
+	streaming bool
+	seq       uint32
+	readyR    int
+	readyW    int
+	stop      chan struct{}
+	ticker    sync.WaitGroup
+}
+
+// fakeBufferOffset is the distance between the buffer offsets reported by
+// VIDIOC_QUERYBUF. The offsets only identify the buffers.
+const fakeBufferOffset = 1 << 12
+
+func (f *fakeBackend) ioctl(request uint, argp ioctlArg) error {
+	if f.spec.Err != nil {
+		if err := f.spec.Err(ioctlName(request)); err != nil {
+			return err
+		}
+	}
+
+	switch request {
+	case vidioc_querycap:
+		return f.querycap(argp.(*v4l_capability))
+	case vidioc_enumstd:
+		return syscall.ENOTTY
+	case vidioc_gFmt:
+		return f.gFmt(argp.(*v4l_format_pix))
+	case vidioc_sFmt:
+		return f.sFmt(argp.(*v4l_format_pix))
+	case vidioc_gParm:
+		return f.gParm(argp.(*v4l_streamparm_capture))
+	case vidioc_sParm:
+		return f.sParm(argp.(*v4l_streamparm_capture))
+	case vidioc_reqbufs:
+		return f.reqbufs(argp.(*v4l_requestbuffers))
+	case vidioc_querybuf:
+		return f.querybuf(argp.(*v4l_buffer))
+	case vidioc_qbuf:
+		return f.qbuf(argp.(*v4l_buffer))
+	case vidioc_dqbuf:
+		return f.dqbuf(argp.(*v4l_buffer))
+	case vidioc_streamon:
+		return f.streamon()
+	case vidioc_streamoff:
+		f.streamoff()
+		return nil
+	case vidioc_enumFmt:
+		return f.enumFmt(argp.(*v4l_fmtdesc))
+	case vidioc_enumFramesizes:
+		return f.enumFramesizes(argp.(*v4l_frmsizeenum))
+	case vidioc_enumFrameintervals:
+		return f.enumFrameintervals(argp.(*v4l_frmivalenum))
+	case vidioc_queryctrl:
+		return f.queryctrl(argp.(*v4l_queryctrl))
+	case vidioc_querymenu:
+		return f.querymenu(argp.(*v4l_querymenu))
+	case vidioc_gCtrl:
+		return f.gCtrl(argp.(*v4l_control))
+	case vidioc_sCtrl:
+		return f.sCtrl(argp.(*v4l_control))
+	case vidioc_gPriority:
+		// The highest priority of all the handles, like the kernel.
+		p := f.prio
+		if other := v4l_int(f.spec.OtherPriority); other > p {
+			p = other
+		}
+		*argp.(*v4l_int) = p
+		return nil
+	case vidioc_sPriority:
+		p := *argp.(*v4l_int)
+		if p < PriorityBackground || p > PriorityRecord {
+			return syscall.EINVAL
+		}
+		if p == PriorityRecord && f.spec.OtherPriority == PriorityRecord {
+			return syscall.EBUSY
+		}
+		f.prio = p
+		return nil
+	case vidioc_gJpegcomp:
+		jc := f.jpeg
+		jc.appData = append([]byte(nil), jc.appData...)
+		jc.comData = append([]byte(nil), jc.comData...)
+		*argp.(*v4l_jpegcompression) = jc
+		return nil
+	case vidioc_sJpegcomp:
+		jc := *argp.(*v4l_jpegcompression)
+		if jc.quality < 0 || jc.quality > 100 || jc.appn < 0 || jc.appn > 15 {
+			return syscall.EINVAL
+		}
+		jc.appData = append([]byte(nil), jpegSegment(jc.appData, jc.appLen)...)
+		jc.comData = append([]byte(nil), jpegSegment(jc.comData, jc.comLen)...)
+		f.jpeg = jc
+		return nil
+	default:
+		// Including cropping, which cameras often don't support.
+		return syscall.ENOTTY
+	}
+}
+
+func (f *fakeBackend) mmap(offset int64, length int) ([]byte, error) {
+	i := offset / fakeBufferOffset
+	if offset%fakeBufferOffset != 0 || i < 0 || i >= int64(len(f.bufs)) ||
+		length != len(f.bufs[i]) {
+		return nil, syscall.EINVAL
+	}
+	return f.bufs[i], nil
+}
+
+func (f *fakeBackend) munmap(b []byte) {}
+
+func (f *fakeBackend) pollFd() int {
+	return f.readyR
+}
+
+func (f *fakeBackend) sysfsInfo(info *DeviceInfo) error {
+	s := &f.spec.Info
+	info.VendorID = s.VendorID
+	info.ProductID = s.ProductID
+	info.Serial = s.Serial
+	info.Manufacturer = s.Manufacturer
+	info.Port = s.Port
+	info.Interface = s.Interface
+	info.Index = s.Index
+	info.MediaNode = s.MediaNode
+	info.ByID = s.ByID
+	info.ByPath = s.ByPath
+	return nil
+}
+
+func (f *fakeBackend) close() {
+	f.streamoff()
+	syscall.Close(f.readyR)
+	syscall.Close(f.readyW)
+}
+
+func (f *fakeBackend) querycap(c *v4l_capability) error {
+	info := &f.spec.Info
+	*c = v4l_capability{
+		driver:  info.DriverName,
+		card:    info.DeviceName,
+		busInfo: info.BusInfo,
+		version: uint32(info.DriverVersion[0]&0xff)<<16 |
+			uint32(info.DriverVersion[1]&0xff)<<8 |
+			uint32(info.DriverVersion[2]&0xff),
+		capabilities: v4l_capVideoCapture | v4l_capStreaming | v4l_capDeviceCaps,
+		deviceCaps:   v4l_capVideoCapture | v4l_capStreaming,
+	}
+	return nil
+}
+
+func (f *fakeBackend) gFmt(p *v4l_format_pix) error {
+	if p.typ != v4l_bufTypeVideoCapture {
+		return syscall.EINVAL
+	}
+	stride, size := fakeLayout(f.cfg)
+	p.fmt = v4l_pixFormat{
+		width:        uint32(f.cfg.Width),
+		height:       uint32(f.cfg.Height),
+		pixelformat:  uint32(f.cfg.Format),
+		field:        v4l_fieldNone,
+		bytesperline: uint32(stride),
+		sizeimage:    uint32(size),
+		colorspace:   v4l_colorspaceDefault,
+	}
+	return nil
+}
+
+// sFmt picks the supported frame size closest to the requested one. If the
+// format isn't supported, it falls back to the initial one, as drivers do.
+func (f *fakeBackend) sFmt(p *v4l_format_pix) error {
+	if p.typ != v4l_bufTypeVideoCapture {
+		return syscall.EINVAL
+	}
+	if f.bufs != nil {
+		return syscall.EBUSY
+	}
+	format := FourCC(p.fmt.pixelformat)
+	if !f.supports(func(c DeviceConfig) bool { return c.Format == format }) {
+		format = f.spec.Configs[0].Format
+	}
+	w, h := int(p.fmt.width), int(p.fmt.height)
+	best, bestDist := DeviceConfig{}, -1
+	for _, c := range f.spec.Configs {
+		if c.Format != format {
+			continue
+		}
+		dist := abs(c.Width-w) + abs(c.Height-h)
+		if bestDist < 0 || dist < bestDist {
+			best, bestDist = c, dist
+		}
+	}
+	if !f.supports(func(c DeviceConfig) bool {
+		return c.Format == best.Format && c.Width == best.Width &&
+			c.Height == best.Height && c.FPS == f.cfg.FPS
+	}) {
+		f.cfg = best
+	} else {
+		f.cfg = DeviceConfig{Format: best.Format, Width: best.Width, Height: best.Height, FPS: f.cfg.FPS}
+	}
+	return f.gFmt(p)
+}
+
+func (f *fakeBackend) gParm(p *v4l_streamparm_capture) error {
+	if p.typ != v4l_bufTypeVideoCapture {
+		return syscall.EINVAL
+	}
+	p.parm = v4l_captureparm{
+		capability:   v4l_capTimeperframe,
+		timeperframe: v4l_fract{f.cfg.FPS.D, f.cfg.FPS.N},
+	}
+	return nil
+}
+
+// sParm picks the supported frame rate closest to the requested one.
+func (f *fakeBackend) sParm(p *v4l_streamparm_capture) error {
+	if p.typ != v4l_bufTypeVideoCapture {
+		return syscall.EINVAL
+	}
+	if f.streaming {
+		return syscall.EBUSY
+	}
+	tpf := p.parm.timeperframe
+	if tpf.numerator != 0 && tpf.denominator != 0 {
+		fps := float64(tpf.denominator) / float64(tpf.numerator)
+		bestDist := -1.0
+		for _, c := range f.spec.Configs {
+			if c.Format != f.cfg.Format || c.Width != f.cfg.Width ||
+				c.Height != f.cfg.Height || c.FPS.N == 0 || c.FPS.D == 0 {
+				continue
+			}
+			dist := float64(c.FPS.N)/float64(c.FPS.D) - fps
+			if dist < 0 {
+				dist = -dist
+			}
+			if bestDist < 0 || dist < bestDist {
+				f.cfg.FPS, bestDist = c.FPS, dist
+			}
+		}
+	}
+	return f.gParm(p)
+}
+
+func (f *fakeBackend) reqbufs(rb *v4l_requestbuffers) error {
+	if rb.typ != v4l_bufTypeVideoCapture || rb.memory != v4l_memoryMmap {
+		return syscall.EINVAL
+	}
+	if f.streaming {
+		return syscall.EBUSY
+	}
+	f.bufs, f.queued = nil, nil
+	if rb.count > 32 {
+		rb.count = 32
+	}
+	_, size := fakeLayout(f.cfg)
+	for i := uint32(0); i < rb.count; i++ {
+		f.bufs = append(f.bufs, make([]byte, size))
+	}
+	return nil
+}
+
+func (f *fakeBackend) querybuf(b *v4l_buffer) error {
+	if b.index >= uint32(len(f.bufs)) {
+		return syscall.EINVAL
+	}
+	b.offset = b.index * fakeBufferOffset
+	b.length = uint32(len(f.bufs[b.index]))
+	return nil
+}
+
+func (f *fakeBackend) qbuf(b *v4l_buffer) error {
+	if b.index >= uint32(len(f.bufs)) {
+		return syscall.EINVAL
+	}
+	for _, i := range f.queued {
+		if i == b.index {
+			return syscall.EINVAL
+		}
+	}
+	f.queued = append(f.queued, b.index)
+	return nil
+}
+
+func (f *fakeBackend) dqbuf(b *v4l_buffer) error {
+	if !f.streaming || len(f.queued) == 0 {
+		return syscall.EINVAL
+	}
+	if f.spec.RealTime {
+		var tick [1]byte
+		if n, _ := syscall.Read(f.readyR, tick[:]); n <= 0 {
+			return syscall.EAGAIN
+		}
+	}
+	i := f.queued[0]
+	n := f.spec.Pattern(f.cfg, f.seq, f.bufs[i])
+	if n < 0 || n > len(f.bufs[i]) {
+		return Error("fake pattern used " + strconv.Itoa(n) + " bytes of a " +
+			strconv.Itoa(len(f.bufs[i])) + "-byte buffer")
+	}
+	f.queued = f.queued[1:]
+	b.index = i
+	b.bytesused = uint32(n)
+	b.sequence = f.seq
+	b.offset = i * fakeBufferOffset
+	b.length = uint32(len(f.bufs[i]))
+	f.seq++
+	return nil
+}
+
+func (f *fakeBackend) streamon() error {
+	if len(f.bufs) == 0 {
+		return syscall.EINVAL
+	}
+	if f.streaming {
+		return nil
+	}
+	f.streaming = true
+	f.seq = 0
+	if f.spec.RealTime {
+		period := time.Second / 30
+		if fps := f.cfg.FPS; fps.N != 0 && fps.D != 0 {
+			period = time.Duration(int64(time.Second) * int64(fps.D) / int64(fps.N))
+		}
+		f.stop = make(chan struct{})
+		f.ticker.Add(1)
+		go f.tick(period, f.stop)
+	}
+	return nil
+}
+
+// tick makes a frame ready every period until stop is closed.
+func (f *fakeBackend) tick(period time.Duration, stop chan struct{}) {
+	defer f.ticker.Done()
+	t := time.NewTicker(period)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			syscall.Write(f.readyW, []byte{0})
+		case <-stop:
+			return
+		}
+	}
+}
+
+// streamoff stops streaming, and returns all buffers to the application.
+func (f *fakeBackend) streamoff() {
+	if !f.streaming {
+		return
+	}
+	f.streaming = false
+	f.queued = nil
+	if f.spec.RealTime {
+		close(f.stop)
+		f.ticker.Wait()
+		var drain [64]byte
+		for {
+			if n, _ := syscall.Read(f.readyR, drain[:]); n <= 0 {
+				break
+			}
+		}
+	}
+}
+
+func (f *fakeBackend) enumFmt(fd *v4l_fmtdesc) error {
+	if fd.typ != v4l_bufTypeVideoCapture {
+		return syscall.EINVAL
+	}
+	var formats []FourCC
+	for _, c := range f.spec.Configs {
+		formats = appendUnique(formats, c.Format)
+	}
+	if fd.index >= uint32(len(formats)) {
+		return syscall.EINVAL
+	}
+	fd.pixelformat = uint32(formats[fd.index])
+	fd.description = formats[fd.index].String()
+	if info, ok := formats[fd.index].Info(); ok {
+		fd.description = info.Name
+	}
+	return nil
+}
+
+func (f *fakeBackend) enumFramesizes(fs *v4l_frmsizeenum) error {
+	var sizes []v4l_frmsizeDiscrete
+	for _, c := range f.spec.Configs {
+		if c.Format != FourCC(fs.pixelFormat) {
+			continue
+		}
+		sz := v4l_frmsizeDiscrete{uint32(c.Width), uint32(c.Height)}
+		dupe := false
+		for _, s := range sizes {
+			dupe = dupe || s == sz
+		}
+		if !dupe {
+			sizes = append(sizes, sz)
+		}
+	}
+	if fs.index >= uint32(len(sizes)) {
+		return syscall.EINVAL
+	}
+	fs.typ = v4l_frmsizeTypeDiscrete
+	fs.discrete = sizes[fs.index]
+	return nil
+}
+
+func (f *fakeBackend) enumFrameintervals(fi *v4l_frmivalenum) error {
+	var ivals []v4l_fract
+	for _, c := range f.spec.Configs {
+		if c.Format != FourCC(fi.pixelFormat) || uint32(c.Width) != fi.width ||
+			uint32(c.Height) != fi.height {
+			continue
+		}
+		ival := v4l_fract{c.FPS.D, c.FPS.N}
+		dupe := false
+		for _, x := range ivals {
+			dupe = dupe || x == ival
+		}
+		if !dupe {
+			ivals = append(ivals, ival)
+		}
+	}
+	if fi.index >= uint32(len(ivals)) {
+		return syscall.EINVAL
+	}
+	fi.typ = v4l_frmivalTypeDiscrete
+	fi.discrete = ivals[fi.index]
+	return nil
+}
+
+// control returns the control with the given ID. If next is true, it returns
+// the one with the lowest ID greater than cid instead.
+func (f *fakeBackend) control(cid uint32, next bool) (*ControlInfo, bool) {
+	var found *ControlInfo
+	for i := range f.spec.Controls {
+		c := &f.spec.Controls[i]
+		switch {
+		case !next && c.CID == cid:
+			return c, true
+		case next && c.CID > cid && (found == nil || c.CID < found.CID):
+			found = c
+		}
+	}
+	return found, found != nil
+}
+
+func (f *fakeBackend) queryctrl(qc *v4l_queryctrl) error {
+	next := qc.id&v4l_ctrlFlagNextCtrl != 0
+	c, ok := f.control(qc.id&^v4l_ctrlFlagNextCtrl, next)
+	if !ok {
+		return syscall.EINVAL
+	}
+	*qc = v4l_queryctrl{
+		id:           c.CID,
+		typ:          fakeControlTypes[c.Type],
+		name:         c.Name,
+		minimum:      c.Min,
+		maximum:      c.Max,
+		step:         c.Step,
+		defaultValue: c.Default,
+	}
+	return nil
+}
+
+// fakeControlTypes maps ControlInfo.Type to V4L control types.
+var fakeControlTypes = map[string]uint32{
+	"int":      v4l_ctrlTypeInteger,
+	"bool":     v4l_ctrlTypeBoolean,
+	"enum":     v4l_ctrlTypeMenu,
+	"int-enum": v4l_ctrlTypeIntegerMenu,
+	"button":   v4l_ctrlTypeButton,
+}
+
+func (f *fakeBackend) querymenu(qm *v4l_querymenu) error {
+	c, ok := f.control(qm.id, false)
+	if !ok {
+		return syscall.EINVAL
+	}
+	for _, opt := range c.Options {
+		if uint32(opt.Value) == qm.index {
+			qm.name = opt.Name
+			qm.value = opt.Int64
+			return nil
+		}
+	}
+	return syscall.EINVAL
+}
+
+func (f *fakeBackend) gCtrl(ctrl *v4l_control) error {
+	c, ok := f.control(ctrl.id, false)
+	if !ok {
+		return syscall.EINVAL
+	}
+	if c.Type == "button" {
+		return syscall.EACCES
+	}
+	ctrl.value = f.ctrls[c.CID]
+	return nil
+}
+
+// sCtrl sets a control. Integers are rounded to the nearest valid value, as
+// drivers do.
+func (f *fakeBackend) sCtrl(ctrl *v4l_control) error {
+	c, ok := f.control(ctrl.id, false)
+	if !ok {
+		return syscall.EINVAL
+	}
+	v := ctrl.value
+	switch c.Type {
+	case "int":
+		if v < c.Min {
+			v = c.Min
+		}
+		if v > c.Max {
+			v = c.Max
+		}
+		if c.Step > 1 {
+			v = c.Min + (v-c.Min+c.Step/2)/c.Step*c.Step
+			if v > c.Max {
+				v -= c.Step
+			}
+		}
+	case "bool":
+		if v != 0 {
+			v = 1
+		}
+	case "enum", "int-enum":
+		valid := false
+		for _, opt := range c.Options {
+			valid = valid || opt.Value == v
+		}
+		if !valid {
+			return syscall.EINVAL
+		}
+	case "button":
+		return nil
+	}
+	f.ctrls[c.CID] = v
+	ctrl.value = v
+	return nil
+}
+
+// supports tells if any of the supported configurations satisfies match.
+func (f *fakeBackend) supports(match func(DeviceConfig) bool) bool {
+	for _, c := range f.spec.Configs {
+		if match(c) {
+			return true
+		}
+	}
+	return false
+}
+
+// fakeLayout returns the line stride and the buffer size for cfg.
+func fakeLayout(cfg DeviceConfig) (stride, size int) {
+	w, h := cfg.Width, cfg.Height
+	switch cfg.Format {
+	case fourcc("GREY"):
+		return w, w * h
+	case fourcc("RGB3"), fourcc("BGR3"):
+		return 3 * w, 3 * w * h
+	case fourcc("MJPG"):
+		size = 2 * w * h
+		if size < 1<<16 {
+			size = 1 << 16
+		}
+		return 0, size
+	default:
+		return 2 * w, 2 * w * h
+	}
+}
+
+// fakeBars are the colors of the test pattern.
+var fakeBars = []color.RGBA{
+	{255, 255, 255, 255}, // white
+	{255, 255, 0, 255},   // yellow
+	{0, 255, 255, 255},   // cyan
+	{0, 255, 0, 255},     // green
+	{255, 0, 255, 255},   // magenta
+	{255, 0, 0, 255},     // red
+	{0, 0, 255, 255},     // blue
+	{0, 0, 0, 255},       // black
+}
+
+// fakePattern draws color bars, which move one position to the left on every
+// frame.
+func fakePattern(cfg DeviceConfig, seq uint32, data []byte) int {
+	w, h := cfg.Width, cfg.Height
+	if w <= 0 || h <= 0 {
+		return 0
+	}
+	bar := func(x int) color.RGBA {
+		return fakeBars[(x*len(fakeBars)/w+int(seq%uint32(len(fakeBars))))%len(fakeBars)]
+	}
+	ycc := func(x int) (y, cb, cr byte) {
+		c := bar(x)
+		return color.RGBToYCbCr(c.R, c.G, c.B)
+	}
+
+	// Byte positions of Y0, U, Y1, and V within a pair of pixels.
+	var packed [4]int
+	switch cfg.Format {
+	case fourcc("YUYV"):
+		packed = [4]int{0, 1, 2, 3}
+	case fourcc("UYVY"):
+		packed = [4]int{1, 0, 3, 2}
+	case fourcc("YVYU"):
+		packed = [4]int{0, 3, 2, 1}
+	case fourcc("VYUY"):
+		packed = [4]int{1, 2, 3, 0}
+	}
+
+	switch cfg.Format {
+	case fourcc("YUYV"), fourcc("UYVY"), fourcc("YVYU"), fourcc("VYUY"):
+		for y := 0; y < h; y++ {
+			for x := 0; x+1 < w; x += 2 {
+				p := data[2*(y*w+x):]
+				y0, cb, cr := ycc(x)
+				y1, _, _ := ycc(x + 1)
+				p[packed[0]], p[packed[1]], p[packed[2]], p[packed[3]] = y0, cb, y1, cr
+			}
+		}
+	case fourcc("RGB3"), fourcc("BGR3"):
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				p, c := data[3*(y*w+x):], bar(x)
+				if cfg.Format == fourcc("RGB3") {
+					p[0], p[1], p[2] = c.R, c.G, c.B
+				} else {
+					p[0], p[1], p[2] = c.B, c.G, c.R
+				}
+			}
+		}
+	case fourcc("GREY"):
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				data[y*w+x], _, _ = ycc(x)
+			}
+		}
+	case fourcc("MJPG"):
+		img := image.NewRGBA(image.Rect(0, 0, w, h))
+		for y := 0; y < h; y++ {
+			for x := 0; x < w; x++ {
+				img.SetRGBA(x, y, bar(x))
+			}
+		}
+		var buf bytes.Buffer
+		jpeg.Encode(&buf, img, nil)
+		return copy(data, buf.Bytes())
+	default:
+		for i := range data {
+			data[i] = byte(i + int(seq))
+		}
+	}
+	return len(data)
+}
+
+// fourcc returns the FourCC code of a four-character string.
+func fourcc(s string) FourCC {
+	return MakeFourCC(s[0], s[1], s[2], s[3])
+}
+
+// appendUnique appends x to s, unless it's already there.
+func appendUnique(s []FourCC, x FourCC) []FourCC {
+	for _, y := range s {
+		if y == x {
+			return s
+		}
+	}
+	return append(s, x)
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
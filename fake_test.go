@@ -0,0 +1,268 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"errors"
+	"syscall"
+	"testing"
+	"time"
+)
+
+var fakeConfigs = []DeviceConfig{
+	{Format: fourcc("YUYV"), Width: 640, Height: 480, FPS: Frac{30, 1}},
+	{Format: fourcc("YUYV"), Width: 640, Height: 480, FPS: Frac{15, 1}},
+	{Format: fourcc("YUYV"), Width: 320, Height: 240, FPS: Frac{30, 1}},
+	{Format: fourcc("MJPG"), Width: 1280, Height: 720, FPS: Frac{30, 1}},
+}
+
+// mjpegConfig returns the MJPEG configuration of fakeConfigs with the given
+// quality.
+func mjpegConfig(quality int) DeviceConfig {
+	cfg := fakeConfigs[3]
+	cfg.JPEGQuality = quality
+	return cfg
+}
+
+func TestFakeConfig(t *testing.T) {
+	dev, err := OpenFake(FakeDevice{Configs: fakeConfigs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	cfgs, err := dev.ListConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfgs) != len(fakeConfigs) {
+		t.Fatalf("ListConfigs: got: %v, expected: %v\n", cfgs, fakeConfigs)
+	}
+	for i := range cfgs {
+		if cfgs[i] != fakeConfigs[i] {
+			t.Errorf("ListConfigs: got: %v, expected: %v\n", cfgs[i], fakeConfigs[i])
+		}
+	}
+
+	// The closest supported configuration is chosen.
+	tests := []struct{ set, get DeviceConfig }{
+		{fakeConfigs[1], fakeConfigs[1]},
+		{DeviceConfig{Format: fourcc("YUYV"), Width: 300, Height: 200, FPS: Frac{60, 1}}, fakeConfigs[2]},
+		{DeviceConfig{Format: fourcc("NV12"), Width: 640, Height: 480, FPS: Frac{10, 1}}, fakeConfigs[1]},
+		{DeviceConfig{Format: fourcc("MJPG"), Width: 1920, Height: 1080, FPS: Frac{30, 1}}, mjpegConfig(75)},
+		{DeviceConfig{Format: fourcc("MJPG"), Width: 1280, Height: 720, FPS: Frac{30, 1}, JPEGQuality: 90}, mjpegConfig(90)},
+		{DeviceConfig{Format: fourcc("YUYV"), Width: 640, Height: 480, FPS: Frac{30, 1}, JPEGQuality: 50}, fakeConfigs[0]},
+		{fakeConfigs[3], mjpegConfig(90)},
+	}
+	for _, test := range tests {
+		if err := dev.SetConfig(test.set); err != nil {
+			t.Errorf("SetConfig(%v): %v\n", test.set, err)
+			continue
+		}
+		cfg, err := dev.GetConfig()
+		if err != nil {
+			t.Errorf("GetConfig: %v\n", err)
+			continue
+		}
+		if cfg != test.get {
+			t.Errorf("SetConfig(%v): got: %v, expected: %v\n", test.set, cfg, test.get)
+		}
+	}
+
+	info, err := dev.BufferInfo()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.BufferSize < 1280*720 {
+		t.Errorf("BufferInfo: buffer too small: %d\n", info.BufferSize)
+	}
+}
+
+func TestFakeControls(t *testing.T) {
+	dev, err := OpenFake(FakeDevice{
+		Controls: []ControlInfo{
+			{CID: CtrlBrightness, Name: "Brightness", Type: "int", Min: 0, Max: 100, Step: 10, Default: 50},
+			{CID: CtrlHFlip, Name: "Horizontal Flip", Type: "bool", Max: 1, Step: 1},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	infos, err := dev.ListControls()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].Name != "Brightness" || infos[1].Type != "bool" {
+		t.Errorf("ListControls: got: %v\n", infos)
+	}
+
+	tests := []struct{ set, get int32 }{{50, 50}, {34, 30}, {-5, 0}, {200, 100}}
+	for _, test := range tests {
+		if err := dev.SetControl(CtrlBrightness, test.set); err != nil {
+			t.Errorf("SetControl(%d): %v\n", test.set, err)
+		}
+		v, err := dev.GetControl(CtrlBrightness)
+		if err != nil || v != test.get {
+			t.Errorf("SetControl(%d): got: %d (%v), expected: %d\n", test.set, v, err, test.get)
+		}
+	}
+
+	if _, err := dev.GetControl(CtrlContrast); !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("GetControl(CtrlContrast): got: %v, expected: EINVAL\n", err)
+	}
+}
+
+func TestFakeCapture(t *testing.T) {
+	dev, err := OpenFake(FakeDevice{
+		Configs: []DeviceConfig{{Format: fourcc("GREY"), Width: 16, Height: 2, FPS: Frac{30, 1}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+
+	for i := uint32(0); i < 10; i++ {
+		buf, err := dev.Capture()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if buf.SeqNum() != i {
+			t.Errorf("SeqNum: got: %d, expected: %d\n", buf.SeqNum(), i)
+		}
+		if buf.Size() != 32 {
+			t.Errorf("Size: got: %d, expected: 32\n", buf.Size())
+		}
+	}
+
+	// The bars move left by one position on every frame.
+	buf, _ := dev.Capture()
+	buf.Seek(2, 0)
+	first, _ := buf.ReadByte()
+	buf, _ = dev.Capture()
+	second, _ := buf.ReadByte()
+	if first != second {
+		t.Errorf("test pattern didn't move: %d, %d\n", first, second)
+	}
+}
+
+func TestFakeBadPattern(t *testing.T) {
+	for _, n := range []int{-1, 1 << 20} {
+		dev, err := OpenFake(FakeDevice{
+			Pattern: func(cfg DeviceConfig, seq uint32, data []byte) int { return n },
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := dev.TurnOn(); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := dev.Capture(); err == nil {
+			t.Errorf("Pattern returning %d: Capture succeeded\n", n)
+		}
+		dev.Close()
+	}
+}
+
+func TestFakeErr(t *testing.T) {
+	unplugged := false
+	dev, err := OpenFake(FakeDevice{
+		Err: func(op string) error {
+			if unplugged {
+				return syscall.ENODEV
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.Capture(); err != nil {
+		t.Fatal(err)
+	}
+	unplugged = true
+	_, err = dev.Capture()
+	if !errors.Is(err, ErrDisconnected) {
+		t.Errorf("got: %v, expected: %v\n", err, ErrDisconnected)
+	}
+	var opErr *OpError
+	if !errors.As(err, &opErr) || opErr.Op != "VIDIOC_QBUF" || opErr.Path != "fake" {
+		t.Errorf("got: %#v, expected an OpError for VIDIOC_QBUF on fake\n", err)
+	}
+}
+
+func TestFakeRealTime(t *testing.T) {
+	dev, err := OpenFake(FakeDevice{
+		Configs:  []DeviceConfig{{Format: fourcc("YUYV"), Width: 8, Height: 8, FPS: Frac{50, 1}}},
+		RealTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+
+	start := time.Now()
+	for i := 0; i < 5; i++ {
+		if _, err := dev.Capture(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if d := time.Since(start); d < 80*time.Millisecond {
+		t.Errorf("5 frames at 50 fps took only %v\n", d)
+	}
+	dev.Close()
+
+	// Close must wake up a waiting Capture.
+	dev, err = OpenFake(FakeDevice{
+		Configs:  []DeviceConfig{{Format: fourcc("YUYV"), Width: 8, Height: 8, FPS: Frac{1, 10}}},
+		RealTime: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+	errs := make(chan error)
+	go func() {
+		_, err := dev.Capture()
+		errs <- err
+	}()
+	time.Sleep(10 * time.Millisecond)
+	dev.Close()
+	select {
+	case err := <-errs:
+		if err != ErrClosed {
+			t.Errorf("Capture: got: %v, expected: %v\n", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Capture not woken up by Close\n")
+	}
+}
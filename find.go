@@ -40,7 +40,7 @@ func FindDevices() []DeviceInfo {
 		if err := syscall.Stat(path, &stat); err != nil {
 			return nil
 		}
-		major, minor := int(stat.Rdev>>8), int(stat.Rdev&255)
+		major, minor := devNumbers(uint64(stat.Rdev))
 		if major != 81 {
 			return nil
 		}
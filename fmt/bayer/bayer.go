@@ -0,0 +1,301 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package bayer provides support for raw Bayer formats, and demosaicing them.
+//
+// A Bayer image has a single sample per pixel, filtered through a color filter
+// array (CFA) which repeats every 2x2 pixels. Samples are 8, 10, 12 or 16 bits
+// deep, either in a byte (8 bits), in a little-endian 16-bit word, or MIPI
+// packed (10 and 12 bits).
+//
+// Importing the package registers decoders with package decode, which demosaic
+// frames into *image.RGBA with the default Options.
+package bayer
+
+import (
+	"image"
+	"image/color"
+)
+
+// FourCCs of the supported formats.
+const (
+	FourCCSBGGR8 = 'B' | 'A'<<8 | '8'<<16 | '1'<<24
+	FourCCSGBRG8 = 'G' | 'B'<<8 | 'R'<<16 | 'G'<<24
+	FourCCSGRBG8 = 'G' | 'R'<<8 | 'B'<<16 | 'G'<<24
+	FourCCSRGGB8 = 'R' | 'G'<<8 | 'G'<<16 | 'B'<<24
+
+	FourCCSBGGR10 = 'B' | 'G'<<8 | '1'<<16 | '0'<<24
+	FourCCSGBRG10 = 'G' | 'B'<<8 | '1'<<16 | '0'<<24
+	FourCCSGRBG10 = 'B' | 'A'<<8 | '1'<<16 | '0'<<24
+	FourCCSRGGB10 = 'R' | 'G'<<8 | '1'<<16 | '0'<<24
+
+	FourCCSBGGR12 = 'B' | 'G'<<8 | '1'<<16 | '2'<<24
+	FourCCSGBRG12 = 'G' | 'B'<<8 | '1'<<16 | '2'<<24
+	FourCCSGRBG12 = 'B' | 'A'<<8 | '1'<<16 | '2'<<24
+	FourCCSRGGB12 = 'R' | 'G'<<8 | '1'<<16 | '2'<<24
+
+	FourCCSBGGR16 = 'B' | 'Y'<<8 | 'R'<<16 | '2'<<24
+	FourCCSGBRG16 = 'G' | 'B'<<8 | '1'<<16 | '6'<<24
+	FourCCSGRBG16 = 'G' | 'R'<<8 | '1'<<16 | '6'<<24
+	FourCCSRGGB16 = 'R' | 'G'<<8 | '1'<<16 | '6'<<24
+
+	FourCCSBGGR10P = 'p' | 'B'<<8 | 'A'<<16 | 'A'<<24
+	FourCCSGBRG10P = 'p' | 'G'<<8 | 'A'<<16 | 'A'<<24
+	FourCCSGRBG10P = 'p' | 'g'<<8 | 'A'<<16 | 'A'<<24
+	FourCCSRGGB10P = 'p' | 'R'<<8 | 'A'<<16 | 'A'<<24
+
+	FourCCSBGGR12P = 'p' | 'B'<<8 | 'C'<<16 | 'C'<<24
+	FourCCSGBRG12P = 'p' | 'G'<<8 | 'C'<<16 | 'C'<<24
+	FourCCSGRBG12P = 'p' | 'g'<<8 | 'C'<<16 | 'C'<<24
+	FourCCSRGGB12P = 'p' | 'R'<<8 | 'C'<<16 | 'C'<<24
+)
+
+// A Pattern is the layout of the color filter array. It's named after the
+// colors of the top left 2x2 pixels, row by row.
+type Pattern int
+
+const (
+	BGGR Pattern = iota
+	GBRG
+	GRBG
+	RGGB
+)
+
+// Channels of the color filter array.
+const (
+	red = iota
+	green
+	blue
+)
+
+// channel returns the channel of the pixel at (x, y).
+func (p Pattern) channel(x, y int) int {
+	// The pixels of a 2x2 block are indexed as 0 1 / 2 3.
+	i := x&1 | y&1<<1
+	switch p {
+	case BGGR:
+		return [4]int{blue, green, green, red}[i]
+	case GBRG:
+		return [4]int{green, blue, red, green}[i]
+	case GRBG:
+		return [4]int{green, red, blue, green}[i]
+	}
+	return [4]int{red, green, green, blue}[i]
+}
+
+// A Format describes how samples are stored.
+type Format struct {
+	// Pattern is the layout of the color filter array.
+	Pattern Pattern
+
+	// Bits is the bit depth: 8, 10, 12, or 16.
+	Bits int
+
+	// Packed tells if the samples are MIPI packed. In 10-bit packed formats,
+	// every 5 bytes hold 4 pixels: first the high 8 bits of each, then their
+	// low 2 bits, starting from the least significant bits of the last byte.
+	// In 12-bit packed formats, every 3 bytes hold 2 pixels in the same
+	// fashion. Otherwise samples of more than 8 bits are stored in
+	// little-endian 16-bit words.
+	Packed bool
+}
+
+var formats = map[uint32]Format{
+	FourCCSBGGR8:   {BGGR, 8, false},
+	FourCCSGBRG8:   {GBRG, 8, false},
+	FourCCSGRBG8:   {GRBG, 8, false},
+	FourCCSRGGB8:   {RGGB, 8, false},
+	FourCCSBGGR10:  {BGGR, 10, false},
+	FourCCSGBRG10:  {GBRG, 10, false},
+	FourCCSGRBG10:  {GRBG, 10, false},
+	FourCCSRGGB10:  {RGGB, 10, false},
+	FourCCSBGGR12:  {BGGR, 12, false},
+	FourCCSGBRG12:  {GBRG, 12, false},
+	FourCCSGRBG12:  {GRBG, 12, false},
+	FourCCSRGGB12:  {RGGB, 12, false},
+	FourCCSBGGR16:  {BGGR, 16, false},
+	FourCCSGBRG16:  {GBRG, 16, false},
+	FourCCSGRBG16:  {GRBG, 16, false},
+	FourCCSRGGB16:  {RGGB, 16, false},
+	FourCCSBGGR10P: {BGGR, 10, true},
+	FourCCSGBRG10P: {GBRG, 10, true},
+	FourCCSGRBG10P: {GRBG, 10, true},
+	FourCCSRGGB10P: {RGGB, 10, true},
+	FourCCSBGGR12P: {BGGR, 12, true},
+	FourCCSGBRG12P: {GBRG, 12, true},
+	FourCCSGRBG12P: {GRBG, 12, true},
+	FourCCSRGGB12P: {RGGB, 12, true},
+}
+
+// FormatOf returns the format with the given FourCC, or false if there is no
+// such format.
+func FormatOf(fourcc uint32) (Format, bool) {
+	f, ok := formats[fourcc]
+	return f, ok
+}
+
+// valid tells if f is a supported combination of bit depth and packing.
+func (f Format) valid() bool {
+	switch f.Bits {
+	case 8, 16:
+		return !f.Packed
+	case 10, 12:
+		return true
+	}
+	return false
+}
+
+// group returns the base 2 logarithm of the number of pixels that are stored
+// together, and the number of bytes they take up.
+func (f Format) group() (shift uint, bytes int) {
+	switch {
+	case f.Packed && f.Bits == 10:
+		return 2, 5
+	case f.Packed && f.Bits == 12:
+		return 1, 3
+	case f.Bits == 8:
+		return 0, 1
+	}
+	return 0, 2
+}
+
+// lineLen returns the number of bytes that width pixels take up.
+func (f Format) lineLen(width int) int {
+	shift, b := f.group()
+	return (width + 1<<shift - 1) >> shift * b
+}
+
+// An Error is an error message returned by Wrap.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	// ErrDimensions means that the width, height, stride and format passed
+	// to Wrap don't describe a valid image.
+	ErrDimensions = Error("invalid image dimensions")
+
+	// ErrShortBuffer means that the buffer passed to Wrap is too small for
+	// the image.
+	ErrShortBuffer = Error("buffer too small for image")
+)
+
+// An Image is an in-memory raw Bayer image. It implements the image.Image
+// interface, as a greyscale image of the raw samples.
+type Image struct {
+	// Pix holds the pixel data.
+	Pix []uint8
+
+	// Stride is the distance in bytes between vertically adjacent pixels.
+	// Line y starts at index (y-Rect.Min.Y)*Stride.
+	Stride int
+
+	// Rect is the bounds of the image. The color filter array and the groups
+	// of packed pixels are aligned to even coordinates and multiples of the
+	// group size, respectively.
+	Rect image.Rectangle
+
+	// Format tells how samples are stored.
+	Format Format
+}
+
+// New returns a new image with the given bounds and format.
+func New(rect image.Rectangle, f Format) *Image {
+	// Lines cover whole groups of packed pixels.
+	l, r := rect.Min.X&^3, (rect.Max.X+3)&^3
+	stride := f.lineLen(r - l)
+	return &Image{make([]uint8, stride*rect.Dy()), stride, rect, f}
+}
+
+// Wrap returns an image with the given dimensions, stride (e.g.
+// BufferInfo.ImageStride) and format that uses data as its pixel data.
+func Wrap(data []byte, width, height, stride int, f Format) (*Image, error) {
+	if !f.valid() || width < 0 || height < 0 || stride < f.lineLen(width) {
+		return nil, ErrDimensions
+	}
+	if height > 0 && len(data) < stride*(height-1)+f.lineLen(width) {
+		return nil, ErrShortBuffer
+	}
+	return &Image{data, stride, image.Rect(0, 0, width, height), f}, nil
+}
+
+// ColorModel returns color.Gray16Model.
+func (img *Image) ColorModel() color.Model {
+	return color.Gray16Model
+}
+
+// Bounds returns the bounds of the image.
+func (img *Image) Bounds() image.Rectangle {
+	return img.Rect
+}
+
+// At returns the raw sample at (x, y) as a color.Gray16.
+func (img *Image) At(x, y int) color.Color {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.Gray16{}
+	}
+	v, bits := img.Value(x, y), uint(img.Format.Bits)
+	if bits < 16 {
+		v = v<<(16-bits) | v>>(2*bits-16)
+	}
+	return color.Gray16{v}
+}
+
+// Value returns the raw value of the sample at (x, y). It must be within the
+// bounds of the image.
+func (img *Image) Value(x, y int) uint16 {
+	f := img.Format
+	g := img.Pix[img.GroupOffset(x, y):]
+	switch shift, _ := f.group(); {
+	case f.Bits == 8:
+		return uint16(g[0])
+	case f.Packed:
+		k := uint(x) & (1<<shift - 1)
+		lowBits := uint(f.Bits - 8)
+		low := g[1<<shift] >> (k * lowBits) & (1<<lowBits - 1)
+		return uint16(g[k])<<lowBits | uint16(low)
+	default:
+		v := uint16(g[0]) | uint16(g[1])<<8
+		return v & (1<<uint(f.Bits) - 1)
+	}
+}
+
+// GroupOffset returns the index of the first element of Pix that corresponds
+// to the pixel at (x, y), or for packed formats, to the group of pixels to
+// which it belongs.
+func (img *Image) GroupOffset(x, y int) int {
+	shift, b := img.Format.group()
+	return (y-img.Rect.Min.Y)*img.Stride + (x>>shift-img.Rect.Min.X>>shift)*b
+}
+
+// SubImage returns an image representing the portion of img visible through
+// rect. The returned value shares pixels with the original image.
+func (img *Image) SubImage(rect image.Rectangle) image.Image {
+	rect = rect.Intersect(img.Rect)
+	if rect.Empty() {
+		return &Image{Format: img.Format}
+	}
+	i := img.GroupOffset(rect.Min.X, rect.Min.Y)
+	return &Image{img.Pix[i:], img.Stride, rect, img.Format}
+}
+
+// Opaque returns true.
+func (img *Image) Opaque() bool {
+	return true
+}
@@ -0,0 +1,261 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bayer
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+func TestFormatOf(t *testing.T) {
+	tests := []struct {
+		fourcc uint32
+		format Format
+	}{
+		{FourCCSBGGR8, Format{BGGR, 8, false}},
+		{FourCCSGRBG10, Format{GRBG, 10, false}},
+		{FourCCSGBRG12, Format{GBRG, 12, false}},
+		{FourCCSBGGR16, Format{BGGR, 16, false}},
+		{FourCCSRGGB10P, Format{RGGB, 10, true}},
+		{FourCCSGRBG12P, Format{GRBG, 12, true}},
+	}
+	for _, test := range tests {
+		if f, ok := FormatOf(test.fourcc); !ok || f != test.format {
+			t.Errorf("FormatOf(%#x): got: %v, %v, expected: %v\n", test.fourcc, f, ok, test.format)
+		}
+	}
+	if len(formats) != 24 {
+		t.Errorf("got %d formats, expected 24\n", len(formats))
+	}
+	if _, ok := FormatOf('Y' | 'U'<<8 | 'Y'<<16 | 'V'<<24); ok {
+		t.Errorf("FormatOf(YUYV) succeeded\n")
+	}
+}
+
+func TestValue(t *testing.T) {
+	tests := []struct {
+		format Format
+		data   []byte
+		values []uint16
+	}{
+		{Format{RGGB, 8, false}, []byte{1, 2, 3, 4}, []uint16{1, 2, 3, 4}},
+		{Format{RGGB, 10, false}, []byte{0xff, 0xff, 0x01, 0x02}, []uint16{0x3ff, 0x201}},
+		{Format{RGGB, 16, false}, []byte{0x34, 0x12}, []uint16{0x1234}},
+		{Format{RGGB, 10, true}, []byte{0x80, 0x40, 0xff, 0x00, 0xe4}, []uint16{0x200, 0x101, 0x3fe, 0x003}},
+		{Format{RGGB, 12, true}, []byte{0xab, 0xcd, 0x21}, []uint16{0xab1, 0xcd2}},
+	}
+	for _, test := range tests {
+		img, err := Wrap(test.data, len(test.values), 1, len(test.data), test.format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x, v := range test.values {
+			if got := img.Value(x, 0); got != v {
+				t.Errorf("%v: Value(%d, 0): got: %#x, expected: %#x\n", test.format, x, got, v)
+			}
+		}
+	}
+
+	if _, err := Wrap(make([]byte, 9), 5, 1, 9, Format{RGGB, 10, true}); err != ErrDimensions {
+		t.Errorf("got: %v, expected: %v\n", err, ErrDimensions)
+	}
+	if _, err := Wrap(make([]byte, 19), 5, 2, 10, Format{RGGB, 10, false}); err != ErrShortBuffer {
+		t.Errorf("got: %v, expected: %v\n", err, ErrShortBuffer)
+	}
+	if _, err := Wrap(make([]byte, 16), 4, 1, 16, Format{RGGB, 16, true}); err != ErrDimensions {
+		t.Errorf("got: %v, expected: %v\n", err, ErrDimensions)
+	}
+}
+
+func TestSubImage(t *testing.T) {
+	for _, f := range []Format{{BGGR, 8, false}, {BGGR, 12, false}, {BGGR, 10, true}, {BGGR, 12, true}} {
+		src := New(image.Rect(-3, -1, 8, 6), f)
+		for i := range src.Pix {
+			src.Pix[i] = byte(i*37 + 11)
+		}
+		for _, r := range []image.Rectangle{image.Rect(-1, 0, 5, 4), image.Rect(2, 3, 9, 9)} {
+			sub := src.SubImage(r)
+			for y := -2; y < 7; y++ {
+				for x := -4; x < 9; x++ {
+					c0 := sub.At(x, y)
+					c1 := color.Color(color.Gray16{})
+					if image.Pt(x, y).In(r) {
+						c1 = src.At(x, y)
+					}
+					if c0 != c1 {
+						t.Errorf("got: %v, expected: %v (x=%d, y=%d, r=%v, format=%v)\n",
+							c0, c1, x, y, r, f)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+// mosaicOf returns an image of the given format whose samples are taken from
+// the corresponding channel of rgb, scaled to the bit depth.
+func mosaicOf(rgb *image.RGBA, f Format) *Image {
+	img := New(rgb.Rect, f)
+	for y := rgb.Rect.Min.Y; y < rgb.Rect.Max.Y; y++ {
+		for x := rgb.Rect.Min.X; x < rgb.Rect.Max.X; x++ {
+			c := rgb.RGBAAt(x, y)
+			v := uint16([3]uint8{c.R, c.G, c.B}[f.Pattern.channel(x, y)])
+			setValue(img, x, y, v<<uint(f.Bits-8)|v>>uint(16-f.Bits))
+		}
+	}
+	return img
+}
+
+// setValue sets a sample of an unpacked image.
+func setValue(img *Image, x, y int, v uint16) {
+	i := img.GroupOffset(x, y)
+	if img.Format.Bits == 8 {
+		img.Pix[i] = uint8(v)
+		return
+	}
+	img.Pix[i], img.Pix[i+1] = uint8(v), uint8(v>>8)
+}
+
+func TestDemosaicUniform(t *testing.T) {
+	rgb := image.NewRGBA(image.Rect(1, 2, 9, 8))
+	want := color.RGBA{200, 100, 50, 255}
+	for i := 0; i < len(rgb.Pix); i += 4 {
+		rgb.Pix[i], rgb.Pix[i+1], rgb.Pix[i+2], rgb.Pix[i+3] = want.R, want.G, want.B, want.A
+	}
+	for _, pattern := range []Pattern{BGGR, GBRG, GRBG, RGGB} {
+		for _, bits := range []int{8, 12} {
+			src := mosaicOf(rgb, Format{pattern, bits, false})
+			for _, method := range []Method{Nearest, Bilinear, EdgeAware} {
+				dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+				Demosaic(dst, dst.Rect, src, src.Rect.Min, &Options{Method: method})
+				for y := 0; y < src.Rect.Dy(); y++ {
+					for x := 0; x < src.Rect.Dx(); x++ {
+						if c := dst.RGBAAt(x, y); c != want {
+							t.Errorf("got: %v, expected: %v (x=%d, y=%d, pattern=%d, bits=%d, method=%d)\n",
+								c, want, x, y, pattern, bits, method)
+							return
+						}
+					}
+				}
+				if c := dst.RGBAAt(9, 9); c != (color.RGBA{}) {
+					t.Errorf("pixel outside the source was drawn: %v\n", c)
+				}
+			}
+		}
+	}
+}
+
+func TestDemosaicRamp(t *testing.T) {
+	// A grey ramp is reproduced exactly away from the borders, where mirroring
+	// breaks the ramp.
+	rgb := image.NewRGBA(image.Rect(0, 0, 12, 12))
+	for y := 0; y < 12; y++ {
+		for x := 0; x < 12; x++ {
+			v := uint8(10*x + 5*y)
+			rgb.SetRGBA(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	for _, pattern := range []Pattern{BGGR, GRBG} {
+		src := mosaicOf(rgb, Format{pattern, 8, false})
+		for _, method := range []Method{Bilinear, EdgeAware} {
+			dst := image.NewRGBA(rgb.Rect)
+			Demosaic(dst, dst.Rect, src, image.Point{}, &Options{Method: method})
+			for y := 3; y < 9; y++ {
+				for x := 3; x < 9; x++ {
+					if c0, c1 := dst.RGBAAt(x, y), rgb.RGBAAt(x, y); c0 != c1 {
+						t.Errorf("got: %v, expected: %v (x=%d, y=%d, pattern=%d, method=%d)\n",
+							c0, c1, x, y, pattern, method)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestDemosaicEdge(t *testing.T) {
+	// A sharp vertical edge between black and white causes color fringes,
+	// which should be weaker with EdgeAware than with Bilinear.
+	rgb := image.NewRGBA(image.Rect(0, 0, 16, 16))
+	for y := 0; y < 16; y++ {
+		for x := 7; x < 16; x++ {
+			rgb.SetRGBA(x, y, color.RGBA{255, 255, 255, 255})
+		}
+	}
+	src := mosaicOf(rgb, Format{RGGB, 8, false})
+	fringe := func(method Method) int {
+		dst := image.NewRGBA(rgb.Rect)
+		Demosaic(dst, dst.Rect, src, image.Point{}, &Options{Method: method})
+		sum := 0
+		for y := 0; y < 16; y++ {
+			for x := 0; x < 16; x++ {
+				c := dst.RGBAAt(x, y)
+				sum += absInt(int(c.R)-int(c.G)) + absInt(int(c.B)-int(c.G))
+			}
+		}
+		return sum
+	}
+	if b, e := fringe(Bilinear), fringe(EdgeAware); e >= b {
+		t.Errorf("fringes: bilinear: %d, edge-aware: %d\n", b, e)
+	}
+	if e := fringe(EdgeAware); e != 0 {
+		t.Errorf("edge-aware fringes: got: %d, expected: 0\n", e)
+	}
+}
+
+func absInt(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func TestDemosaicOptions(t *testing.T) {
+	// A 10-bit grey card with a black level of 64 and a green cast.
+	src := New(image.Rect(0, 0, 4, 4), Format{BGGR, 10, false})
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			v := uint16(64 + 400)
+			if src.Format.Pattern.channel(x, y) == green {
+				v = 64 + 800
+			}
+			setValue(src, x, y, v)
+		}
+	}
+	dst := image.NewRGBA(src.Rect)
+	opts := &Options{BlackLevel: 64, Gains: [3]float64{1, 0.5, 1}}
+	Demosaic(dst, dst.Rect, src, image.Point{}, opts)
+	const want = 106 // 400 * 255 / (1023 - 64), rounded
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 4; x++ {
+			if c := dst.RGBAAt(x, y); c != (color.RGBA{want, want, want, 255}) {
+				t.Errorf("got: %v, expected: grey %d (x=%d, y=%d)\n", c, want, x, y)
+				return
+			}
+		}
+	}
+
+	// Nil options are the zero options.
+	dst2 := image.NewRGBA(src.Rect)
+	Demosaic(dst, dst.Rect, src, image.Point{}, &Options{})
+	Demosaic(dst2, dst2.Rect, src, image.Point{}, nil)
+	if string(dst.Pix) != string(dst2.Pix) {
+		t.Errorf("nil options differ from zero options\n")
+	}
+}
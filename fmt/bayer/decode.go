@@ -0,0 +1,44 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package bayer
+
+import (
+	"image"
+
+	"github.com/korandiz/v4l"
+	"github.com/korandiz/v4l/decode"
+)
+
+func init() {
+	fourccs := make([]v4l.FourCC, 0, len(formats))
+	for fourcc := range formats {
+		fourccs = append(fourccs, v4l.FourCC(fourcc))
+	}
+	decode.RegisterRGBA(wrapLayout, demosaic, fourccs...)
+}
+
+func wrapLayout(data []byte, l decode.Layout) (image.Image, error) {
+	return Wrap(data, l.Width, l.Height, l.Stride, formats[uint32(l.FourCC)])
+}
+
+// demosaic demosaics a frame with the default options.
+func demosaic(dst *image.RGBA, src image.Image) {
+	Demosaic(dst, dst.Rect, src.(*Image), image.Point{}, nil)
+}
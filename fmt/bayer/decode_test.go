@@ -0,0 +1,49 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package bayer
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/korandiz/v4l/decode"
+)
+
+func TestDecode(t *testing.T) {
+	data := make([]byte, 4*8)
+	for i := range data {
+		data[i] = uint8(23 * i)
+	}
+	src, err := Wrap(data, 8, 4, 8, Format{GRBG, 8, false})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := image.NewRGBA(src.Rect)
+	Demosaic(want, want.Rect, src, image.Point{}, nil)
+	l := decode.Layout{FourCC: FourCCSGRBG8, Width: 8, Height: 4, Stride: 8}
+	got, err := decode.DecodeLayout(nil, data, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g, ok := got.(*image.RGBA); !ok || !bytes.Equal(g.Pix, want.Pix) {
+		t.Errorf("got %v, want %v\n", got, want)
+	}
+}
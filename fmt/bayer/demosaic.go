@@ -0,0 +1,273 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package bayer
+
+import "image"
+
+// A Method is a demosaicing algorithm.
+type Method int
+
+const (
+	// Bilinear interpolates each missing color from the closest samples of
+	// that color. It's the default.
+	Bilinear Method = iota
+
+	// Nearest takes the missing colors from the same 2x2 block. It's the
+	// fastest, but colors only have half the resolution of the image.
+	Nearest
+
+	// EdgeAware interpolates green along edges rather than across them, and
+	// the other colors through their difference from green. It has much less
+	// color fringing at edges than Bilinear.
+	EdgeAware
+)
+
+// Options control how an image is demosaiced. The zero value selects Bilinear,
+// with no black level and no white balance correction.
+type Options struct {
+	// Method is the demosaicing algorithm.
+	Method Method
+
+	// BlackLevel is the raw value of black. It's subtracted from every
+	// sample. (e.g. 64 for many 10-bit sensors)
+	BlackLevel int
+
+	// Gains are the white balance gains for red, green and blue. A gain of
+	// zero is taken as 1.
+	Gains [3]float64
+}
+
+// margin is the number of samples around the demosaiced area that the
+// algorithms look at.
+const margin = 3
+
+// Demosaic aligns r.Min in dst with p in src, and replaces the part of dst
+// visible through r with the demosaiced part of src. Samples outside src are
+// mirrored from the inside. If opts is nil, the zero Options are used.
+func Demosaic(dst *image.RGBA, r image.Rectangle, src *Image, p image.Point, opts *Options) {
+	v := p.Sub(r.Min)
+	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
+	p = r.Min.Add(v)
+	if r.Empty() {
+		return
+	}
+	if opts == nil {
+		opts = &Options{}
+	}
+	m := newMosaic(src, image.Rectangle{p, p.Add(r.Size())}.Inset(-margin), opts)
+
+	var g []float32
+	if opts.Method == EdgeAware {
+		g = m.greenPlane()
+	}
+	for y := 0; y < r.Dy(); y++ {
+		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
+		for x := 0; x < r.Dx(); x++ {
+			var rgb [3]float32
+			switch opts.Method {
+			case Nearest:
+				rgb = m.nearest(x+margin, y+margin)
+			case EdgeAware:
+				rgb = m.edgeAware(x+margin, y+margin, g)
+			default:
+				rgb = m.bilinear(x+margin, y+margin)
+			}
+			d[4*x+0] = toUint8(rgb[red])
+			d[4*x+1] = toUint8(rgb[green])
+			d[4*x+2] = toUint8(rgb[blue])
+			d[4*x+3] = 0xff
+		}
+	}
+}
+
+// A mosaic holds the samples of an area of a Bayer image, with black level and
+// white balance applied, scaled to the range from 0 to 255.
+type mosaic struct {
+	v       []float32
+	w, h    int
+	origin  image.Point
+	pattern Pattern
+}
+
+// newMosaic reads the samples of src in r, mirroring the ones outside src.
+func newMosaic(src *Image, r image.Rectangle, opts *Options) *mosaic {
+	m := &mosaic{
+		v:       make([]float32, r.Dx()*r.Dy()),
+		w:       r.Dx(),
+		h:       r.Dy(),
+		origin:  r.Min,
+		pattern: src.Format.Pattern,
+	}
+
+	var scale [3]float32
+	white := float32(int(1)<<uint(src.Format.Bits) - 1 - opts.BlackLevel)
+	for c := range scale {
+		g := opts.Gains[c]
+		if g == 0 {
+			g = 1
+		}
+		scale[c] = float32(g) * 255 / white
+	}
+
+	for y := 0; y < m.h; y++ {
+		sy := mirror(r.Min.Y+y, src.Rect.Min.Y, src.Rect.Max.Y)
+		for x := 0; x < m.w; x++ {
+			sx := mirror(r.Min.X+x, src.Rect.Min.X, src.Rect.Max.X)
+			c := m.pattern.channel(sx, sy)
+			s := float32(int(src.Value(sx, sy))-opts.BlackLevel) * scale[c]
+			if s < 0 {
+				s = 0
+			}
+			m.v[y*m.w+x] = s
+		}
+	}
+	return m
+}
+
+// mirror maps x into [min, max) by moving it an even distance, so that it
+// stays on the same color of the color filter array. If the range is too
+// narrow for that, it clamps x.
+func mirror(x, min, max int) int {
+	if x < min {
+		x += (min - x + 1) &^ 1
+	}
+	if x >= max {
+		x -= (x - max + 2) &^ 1
+	}
+	if x < min {
+		x = min
+	}
+	return x
+}
+
+// at returns the sample at (x, y), relative to the origin of m.
+func (m *mosaic) at(x, y int) float32 {
+	return m.v[y*m.w+x]
+}
+
+// channel returns the channel of the sample at (x, y), relative to the origin
+// of m.
+func (m *mosaic) channel(x, y int) int {
+	return m.pattern.channel(m.origin.X+x, m.origin.Y+y)
+}
+
+func (m *mosaic) nearest(x, y int) [3]float32 {
+	// Every line of a 2x2 block has one green sample, and either a red or a
+	// blue one.
+	x0, y0 := x-(m.origin.X+x)&1, y-(m.origin.Y+y)&1
+	var rgb [3]float32
+	for j := 0; j < 2; j++ {
+		for i := 0; i < 2; i++ {
+			c := m.channel(x0+i, y0+j)
+			if c != green || y0+j == y {
+				rgb[c] = m.at(x0+i, y0+j)
+			}
+		}
+	}
+	return rgb
+}
+
+func (m *mosaic) bilinear(x, y int) [3]float32 {
+	var rgb [3]float32
+	c := m.channel(x, y)
+	rgb[c] = m.at(x, y)
+	if c == green {
+		h := (m.at(x-1, y) + m.at(x+1, y)) / 2
+		v := (m.at(x, y-1) + m.at(x, y+1)) / 2
+		if m.channel(x+1, y) == red {
+			rgb[red], rgb[blue] = h, v
+		} else {
+			rgb[red], rgb[blue] = v, h
+		}
+		return rgb
+	}
+	rgb[green] = (m.at(x-1, y) + m.at(x+1, y) + m.at(x, y-1) + m.at(x, y+1)) / 4
+	rgb[red+blue-c] = (m.at(x-1, y-1) + m.at(x+1, y-1) + m.at(x-1, y+1) + m.at(x+1, y+1)) / 4
+	return rgb
+}
+
+// greenPlane interpolates green everywhere but on the outermost two samples,
+// using the gradients of green and of the center color to pick a direction.
+func (m *mosaic) greenPlane() []float32 {
+	g := make([]float32, len(m.v))
+	for y := 2; y < m.h-2; y++ {
+		for x := 2; x < m.w-2; x++ {
+			c := m.at(x, y)
+			if m.channel(x, y) == green {
+				g[y*m.w+x] = c
+				continue
+			}
+			l, r, u, d := m.at(x-1, y), m.at(x+1, y), m.at(x, y-1), m.at(x, y+1)
+			ch := 2*c - m.at(x-2, y) - m.at(x+2, y)
+			cv := 2*c - m.at(x, y-2) - m.at(x, y+2)
+			gh := (l+r)/2 + ch/4
+			gv := (u+d)/2 + cv/4
+			dh := abs(l-r) + abs(ch)
+			dv := abs(u-d) + abs(cv)
+			switch {
+			case dh < dv:
+				g[y*m.w+x] = gh
+			case dv < dh:
+				g[y*m.w+x] = gv
+			default:
+				g[y*m.w+x] = (gh + gv) / 2
+			}
+		}
+	}
+	return g
+}
+
+func (m *mosaic) edgeAware(x, y int, g []float32) [3]float32 {
+	// diff returns the difference of the sample at (x, y) from green.
+	diff := func(x, y int) float32 {
+		return m.at(x, y) - g[y*m.w+x]
+	}
+	var rgb [3]float32
+	c := m.channel(x, y)
+	rgb[c] = m.at(x, y)
+	rgb[green] = g[y*m.w+x]
+	if c == green {
+		h := rgb[green] + (diff(x-1, y)+diff(x+1, y))/2
+		v := rgb[green] + (diff(x, y-1)+diff(x, y+1))/2
+		if m.channel(x+1, y) == red {
+			rgb[red], rgb[blue] = h, v
+		} else {
+			rgb[red], rgb[blue] = v, h
+		}
+		return rgb
+	}
+	rgb[red+blue-c] = rgb[green] +
+		(diff(x-1, y-1)+diff(x+1, y-1)+diff(x-1, y+1)+diff(x+1, y+1))/4
+	return rgb
+}
+
+func abs(x float32) float32 {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func toUint8(x float32) uint8 {
+	switch {
+	case x <= 0:
+		return 0
+	case x >= 255:
+		return 255
+	}
+	return uint8(x + 0.5)
+}
@@ -0,0 +1,165 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package depth
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// A Colormap maps depths to colors. The first color is for the nearest
+// distance, the last one for the farthest.
+type Colormap []color.RGBA
+
+// Predefined colormaps, of 256 colors each.
+var (
+	// Grey goes from white to black.
+	Grey = makeColormap(func(t float64) (r, g, b float64) {
+		return 1 - t, 1 - t, 1 - t
+	})
+
+	// Jet goes from dark blue through cyan, yellow and red to dark red.
+	Jet = makeColormap(func(t float64) (r, g, b float64) {
+		ramp := func(x float64) float64 {
+			return math.Max(0, math.Min(1, 1.5-math.Abs(4*t-x)))
+		}
+		return ramp(3), ramp(2), ramp(1)
+	})
+
+	// Turbo is like Jet, but perceptually smoother. It uses the polynomial
+	// approximation published with it.
+	Turbo = makeColormap(func(t float64) (r, g, b float64) {
+		r = 0.13572138 + t*(4.61539260+t*(-42.66032258+t*(132.13108234+t*(-152.94239396+t*59.28637943))))
+		g = 0.09140261 + t*(2.19418839+t*(4.84296658+t*(-14.18503333+t*(4.27729857+t*2.82956604))))
+		b = 0.10667330 + t*(12.64194608+t*(-60.58204836+t*(110.36276771+t*(-89.90310912+t*27.34824973))))
+		return r, g, b
+	})
+)
+
+// makeColormap builds a colormap from a function of a number between 0 and 1,
+// which returns color components between 0 and 1.
+func makeColormap(f func(t float64) (r, g, b float64)) Colormap {
+	cm := make(Colormap, 256)
+	for i := range cm {
+		r, g, b := f(float64(i) / 255)
+		cm[i] = color.RGBA{unit(r), unit(g), unit(b), 255}
+	}
+	return cm
+}
+
+// unit converts a color component between 0 and 1 to 8 bits.
+func unit(x float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Floor(x*255+0.5))))
+}
+
+// ColorizeOptions control how depths are mapped to colors. The zero value
+// maps the whole range of depths in the image through Turbo, with the default
+// depth scale.
+type ColorizeOptions struct {
+	// Near and Far are the distances in millimetres that are mapped to the
+	// first and the last color of the colormap. Nearer and farther pixels
+	// are clamped. If Far is zero, the range is taken from the image.
+	Near, Far float64
+
+	// Colormap maps the range from Near to Far to colors. Nil means Turbo.
+	Colormap Colormap
+
+	// Scale is the depth scale in metres per unit. Zero means DefaultScale.
+	Scale float64
+
+	// NoData is the color of pixels with no depth data.
+	NoData color.RGBA
+}
+
+// Colorize aligns r.Min in dst with p in src, and replaces the part of dst
+// visible through r with the colorized depths of src. If opts is nil, the zero
+// ColorizeOptions are used.
+func Colorize(dst *image.RGBA, r image.Rectangle, src *Image, p image.Point, opts *ColorizeOptions) {
+	v := p.Sub(r.Min)
+	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
+	p = r.Min.Add(v)
+	if r.Empty() {
+		return
+	}
+	if opts == nil {
+		opts = &ColorizeOptions{}
+	}
+	cm := opts.Colormap
+	if len(cm) == 0 {
+		cm = Turbo
+	}
+	scale := opts.Scale
+	if scale == 0 {
+		scale = DefaultScale
+	}
+
+	// Map raw values to colormap indices.
+	near, far := opts.Near/(scale*1000), opts.Far/(scale*1000)
+	if opts.Far == 0 {
+		min, max := depthRange(src, image.Rectangle{p, p.Add(r.Size())})
+		near, far = float64(min), float64(max)
+	}
+	k := 0.0
+	if far > near {
+		k = float64(len(cm)-1) / (far - near)
+	}
+
+	for y := 0; y < r.Dy(); y++ {
+		s := src.Pix[src.PixOffset(p.X, p.Y+y):]
+		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
+		for x := 0; x < r.Dx(); x++ {
+			c := opts.NoData
+			if v := uint16(s[2*x]) | uint16(s[2*x+1])<<8; v != 0 {
+				i := math.Floor((float64(v)-near)*k + 0.5)
+				switch {
+				case i <= 0:
+					c = cm[0]
+				case i >= float64(len(cm)-1):
+					c = cm[len(cm)-1]
+				default:
+					c = cm[int(i)]
+				}
+			}
+			d[4*x+0], d[4*x+1], d[4*x+2], d[4*x+3] = c.R, c.G, c.B, c.A
+		}
+	}
+}
+
+// depthRange returns the smallest and the largest raw value in the part of img
+// visible through r, ignoring pixels with no data.
+func depthRange(img *Image, r image.Rectangle) (min, max uint16) {
+	min = 0xffff
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			v := img.Value(x, y)
+			if v == 0 {
+				continue
+			}
+			if v < min {
+				min = v
+			}
+			if v > max {
+				max = v
+			}
+		}
+	}
+	if max == 0 {
+		return 0, 0
+	}
+	return min, max
+}
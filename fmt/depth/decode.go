@@ -0,0 +1,46 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package depth
+
+import (
+	"image"
+
+	"github.com/korandiz/v4l/decode"
+)
+
+func init() {
+	decode.RegisterGray16(wrapLayout, toGray16, FourCC)
+}
+
+func wrapLayout(data []byte, l decode.Layout) (image.Image, error) {
+	return Wrap(data, l.Width, l.Height, l.Stride)
+}
+
+// toGray16 copies the raw depth values of a frame.
+func toGray16(dst *image.Gray16, src image.Image) {
+	img := src.(*Image)
+	for y := 0; y < img.Rect.Dy(); y++ {
+		line := dst.Pix[y*dst.Stride:]
+		for x := 0; x < img.Rect.Dx(); x++ {
+			v := img.Value(x, y)
+			line[2*x], line[2*x+1] = uint8(v>>8), uint8(v)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package depth
+
+import (
+	"image"
+	"testing"
+
+	"github.com/korandiz/v4l/decode"
+)
+
+func TestDecode(t *testing.T) {
+	data := []byte{0x34, 0x12, 0xff, 0xff, 0, 0, 0, 0, 1, 0, 2, 0}
+	l := decode.Layout{FourCC: FourCC, Width: 2, Height: 2, Stride: 8}
+	got, err := decode.DecodeLayout(nil, data, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g, ok := got.(*image.Gray16)
+	if !ok {
+		t.Fatalf("got %T\n", got)
+	}
+	want := []uint16{0x1234, 0xffff, 1, 2}
+	for i, w := range want {
+		if v := g.Gray16At(i%2, i/2).Y; v != w {
+			t.Errorf("(%d, %d): got %#x, want %#x\n", i%2, i/2, v, w)
+		}
+	}
+}
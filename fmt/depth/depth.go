@@ -0,0 +1,172 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package depth provides support for the Z16 depth format of RGB-D cameras.
+//
+// Importing the package registers a decoder with package decode, which copies
+// the raw values of frames to *image.Gray16.
+package depth
+
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// FourCC of the Z16 format.
+const FourCC = 'Z' | '1'<<8 | '6'<<16 | ' '<<24
+
+// DefaultScale is the depth scale of most cameras, in metres per unit.
+const DefaultScale = 0.001
+
+// An Error is an error message returned by Wrap.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	// ErrDimensions means that the width, height and stride passed to Wrap
+	// don't describe a valid image.
+	ErrDimensions = Error("invalid image dimensions")
+
+	// ErrShortBuffer means that the buffer passed to Wrap is too small for
+	// the image.
+	ErrShortBuffer = Error("buffer too small for image")
+)
+
+// An Image is an in-memory Z16 image. It implements the image.Image interface,
+// as a greyscale image of the raw depth values.
+type Image struct {
+	// Pix holds the pixel data. Every pixel is a little-endian 16-bit word,
+	// the distance in units of the depth scale. Zero means no data.
+	Pix []uint8
+
+	// Stride is the distance in bytes between vertically adjacent pixels.
+	// Line y starts at index (y-Rect.Min.Y)*Stride.
+	Stride int
+
+	// Rect is the bounds of the image.
+	Rect image.Rectangle
+}
+
+// New returns a new Z16 image with the given bounds.
+func New(rect image.Rectangle) *Image {
+	w, h := rect.Dx(), rect.Dy()
+	return &Image{make([]uint8, 2*w*h), 2 * w, rect}
+}
+
+// Wrap returns a Z16 image with the given dimensions and stride (e.g.
+// BufferInfo.ImageStride) that uses data as its pixel data.
+func Wrap(data []byte, width, height, stride int) (*Image, error) {
+	if width < 0 || height < 0 || stride < 2*width {
+		return nil, ErrDimensions
+	}
+	if height > 0 && len(data) < stride*(height-1)+2*width {
+		return nil, ErrShortBuffer
+	}
+	return &Image{data, stride, image.Rect(0, 0, width, height)}, nil
+}
+
+// ColorModel returns color.Gray16Model.
+func (img *Image) ColorModel() color.Model {
+	return color.Gray16Model
+}
+
+// Bounds returns the bounds of the image.
+func (img *Image) Bounds() image.Rectangle {
+	return img.Rect
+}
+
+// At returns the color of the pixel at (x, y).
+func (img *Image) At(x, y int) color.Color {
+	return img.Gray16At(x, y)
+}
+
+// Gray16At returns the raw depth value at (x, y) as a color.Gray16.
+func (img *Image) Gray16At(x, y int) color.Gray16 {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.Gray16{}
+	}
+	return color.Gray16{img.Value(x, y)}
+}
+
+// Value returns the raw depth value at (x, y). It must be within the bounds of
+// the image.
+func (img *Image) Value(x, y int) uint16 {
+	i := img.PixOffset(x, y)
+	return uint16(img.Pix[i]) | uint16(img.Pix[i+1])<<8
+}
+
+// Millimetres returns the distance at (x, y) in millimetres, given the depth
+// scale in metres per unit. (e.g. DefaultScale) It returns 0 if there is no
+// data.
+func (img *Image) Millimetres(x, y int, scale float64) float64 {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return 0
+	}
+	return float64(img.Value(x, y)) * scale * 1000
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (img *Image) PixOffset(x, y int) int {
+	return (y-img.Rect.Min.Y)*img.Stride + (x-img.Rect.Min.X)*2
+}
+
+// SubImage returns an image representing the portion of img visible through
+// rect. The returned value shares pixels with the original image.
+func (img *Image) SubImage(rect image.Rectangle) image.Image {
+	rect = rect.Intersect(img.Rect)
+	if rect.Empty() {
+		return &Image{}
+	}
+	i := img.PixOffset(rect.Min.X, rect.Min.Y)
+	return &Image{img.Pix[i:], img.Stride, rect}
+}
+
+// Opaque returns true.
+func (img *Image) Opaque() bool {
+	return true
+}
+
+// ToMillimetres aligns r.Min in dst with p in src, and replaces the part of
+// dst visible through r with the distances in src, in millimetres. scale is the
+// depth scale of src in metres per unit. (e.g. DefaultScale) Distances are
+// rounded, and those over 65535 mm are clamped.
+func ToMillimetres(dst *image.Gray16, r image.Rectangle, src *Image, p image.Point, scale float64) {
+	v := p.Sub(r.Min)
+	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
+	p = r.Min.Add(v)
+	if r.Empty() {
+		return
+	}
+	k := scale * 1000
+	for y := 0; y < r.Dy(); y++ {
+		s := src.Pix[src.PixOffset(p.X, p.Y+y):]
+		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
+		for x := 0; x < r.Dx(); x++ {
+			mm := math.Floor(float64(uint16(s[2*x])|uint16(s[2*x+1])<<8)*k + 0.5)
+			if mm > 0xffff {
+				mm = 0xffff
+			}
+			m := uint16(mm)
+			d[2*x], d[2*x+1] = uint8(m>>8), uint8(m)
+		}
+	}
+}
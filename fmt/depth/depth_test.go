@@ -0,0 +1,140 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package depth
+
+import (
+	"image"
+	"image/color"
+	"testing"
+)
+
+// newSrc returns a 4x2 image with padding at the end of the lines.
+func newSrc(values ...uint16) *Image {
+	img := &Image{make([]byte, 2*10), 10, image.Rect(0, 0, 4, 2)}
+	for i, v := range values {
+		x, y := i%4, i/4
+		j := img.PixOffset(x, y)
+		img.Pix[j], img.Pix[j+1] = uint8(v), uint8(v>>8)
+	}
+	return img
+}
+
+func TestWrap(t *testing.T) {
+	data := []byte{0xd2, 0x04, 0x00, 0x00, 0xff, 0xff}
+	img, err := Wrap(data, 1, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := img.Value(0, 0); v != 1234 {
+		t.Errorf("Value: got: %d, expected: 1234\n", v)
+	}
+	if c := img.At(0, 1); c != (color.Gray16{0xffff}) {
+		t.Errorf("At: got: %v, expected: %v\n", c, color.Gray16{0xffff})
+	}
+	if mm := img.Millimetres(0, 0, DefaultScale); mm != 1234 {
+		t.Errorf("Millimetres: got: %v, expected: 1234\n", mm)
+	}
+	if mm := img.Millimetres(0, 0, 0.0001); mm < 123.39 || mm > 123.41 {
+		t.Errorf("Millimetres: got: %v, expected: 123.4\n", mm)
+	}
+	if _, err := Wrap(data[:5], 1, 2, 4); err != ErrShortBuffer {
+		t.Errorf("got: %v, expected: %v\n", err, ErrShortBuffer)
+	}
+	if _, err := Wrap(data, 3, 2, 4); err != ErrDimensions {
+		t.Errorf("got: %v, expected: %v\n", err, ErrDimensions)
+	}
+}
+
+func TestSubImage(t *testing.T) {
+	src := newSrc(1, 2, 3, 4, 5, 6, 7, 8)
+	sub := src.SubImage(image.Rect(1, 1, 3, 5)).(*Image)
+	if sub.Rect != image.Rect(1, 1, 3, 2) || sub.Value(2, 1) != 7 {
+		t.Errorf("got: %v, %d, expected: %v, 7\n", sub.Rect, sub.Value(2, 1), image.Rect(1, 1, 3, 2))
+	}
+	if c := sub.At(0, 0); c != (color.Gray16{}) {
+		t.Errorf("got: %v outside the bounds\n", c)
+	}
+}
+
+func TestToMillimetres(t *testing.T) {
+	src := newSrc(0, 100, 1000, 0xffff, 5, 15, 25, 35)
+	dst := image.NewGray16(image.Rect(0, 0, 4, 2))
+	ToMillimetres(dst, dst.Rect, src, image.Point{}, 0.01)
+	want := []uint16{0, 1000, 10000, 0xffff, 50, 150, 250, 350}
+	for i, w := range want {
+		if c := dst.Gray16At(i%4, i/4); c.Y != w {
+			t.Errorf("(%d, %d): got: %d, expected: %d\n", i%4, i/4, c.Y, w)
+		}
+	}
+}
+
+func TestColorize(t *testing.T) {
+	noData := color.RGBA{1, 2, 3, 255}
+	src := newSrc(0, 500, 1000, 1500, 2000, 2500, 1250, 1750)
+	dst := image.NewRGBA(src.Rect)
+	Colorize(dst, dst.Rect, src, image.Point{}, &ColorizeOptions{
+		Near:     1000,
+		Far:      2000,
+		Colormap: Grey,
+		NoData:   noData,
+	})
+	want := []color.RGBA{noData, Grey[0], Grey[0], Grey[128], Grey[255], Grey[255], Grey[64], Grey[191]}
+	for i, w := range want {
+		if c := dst.RGBAAt(i%4, i/4); c != w {
+			t.Errorf("(%d, %d): got: %v, expected: %v\n", i%4, i/4, c, w)
+		}
+	}
+
+	// The range is taken from the image, ignoring missing data.
+	Colorize(dst, dst.Rect, src, image.Point{}, &ColorizeOptions{Colormap: Jet})
+	if c := dst.RGBAAt(1, 0); c != Jet[0] {
+		t.Errorf("nearest: got: %v, expected: %v\n", c, Jet[0])
+	}
+	if c := dst.RGBAAt(1, 1); c != Jet[255] {
+		t.Errorf("farthest: got: %v, expected: %v\n", c, Jet[255])
+	}
+	if c := dst.RGBAAt(0, 0); c != (color.RGBA{}) {
+		t.Errorf("no data: got: %v, expected: %v\n", c, color.RGBA{})
+	}
+
+	// A constant image doesn't divide by zero.
+	Colorize(dst, dst.Rect, newSrc(7, 7, 7, 7, 7, 7, 7, 7), image.Point{}, nil)
+	if c := dst.RGBAAt(3, 1); c != Turbo[0] {
+		t.Errorf("constant: got: %v, expected: %v\n", c, Turbo[0])
+	}
+}
+
+func TestColormaps(t *testing.T) {
+	for name, cm := range map[string]Colormap{"Grey": Grey, "Jet": Jet, "Turbo": Turbo} {
+		if len(cm) != 256 {
+			t.Errorf("%s: got %d colors, expected 256\n", name, len(cm))
+		}
+	}
+	tests := []struct {
+		got, want color.RGBA
+	}{
+		{Grey[0], color.RGBA{255, 255, 255, 255}},
+		{Grey[255], color.RGBA{0, 0, 0, 255}},
+		{Jet[0], color.RGBA{0, 0, 128, 255}},
+		{Jet[255], color.RGBA{128, 0, 0, 255}},
+	}
+	for _, test := range tests {
+		if test.got != test.want {
+			t.Errorf("got: %v, expected: %v\n", test.got, test.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package grey
+
+import (
+	"image"
+
+	"github.com/korandiz/v4l/decode"
+)
+
+func init() {
+	decode.RegisterGray(wrapGreyLayout, copyGray, FourCC)
+	decode.RegisterGray16(wrapLayout, toGray16, FourCCY10, FourCCY12, FourCCY16)
+	decode.RegisterGray16(wrapPackedLayout, packedToGray16, FourCCY10P, FourCCY12P)
+}
+
+// wrapGreyLayout wraps an 8-bit frame.
+func wrapGreyLayout(data []byte, l decode.Layout) (image.Image, error) {
+	return WrapGrey(data, l.Width, l.Height, l.Stride)
+}
+
+// copyGray copies an 8-bit frame.
+func copyGray(dst *image.Gray, src image.Image) {
+	img := src.(*image.Gray)
+	w := img.Rect.Dx()
+	for y := 0; y < img.Rect.Dy(); y++ {
+		copy(dst.Pix[y*dst.Stride:][:w], img.Pix[y*img.Stride:])
+	}
+}
+
+// wrapLayout wraps a frame of 10, 12 or 16-bit values.
+func wrapLayout(data []byte, l decode.Layout) (image.Image, error) {
+	bits := 16
+	switch l.FourCC {
+	case FourCCY10:
+		bits = 10
+	case FourCCY12:
+		bits = 12
+	}
+	return Wrap(data, l.Width, l.Height, l.Stride, bits)
+}
+
+func toGray16(dst *image.Gray16, src image.Image) {
+	ToGray16(dst, dst.Rect, src.(*Image), image.Point{})
+}
+
+// wrapPackedLayout wraps a frame of packed 10 or 12-bit values.
+func wrapPackedLayout(data []byte, l decode.Layout) (image.Image, error) {
+	bits := 10
+	if l.FourCC == FourCCY12P {
+		bits = 12
+	}
+	return WrapPacked(data, l.Width, l.Height, l.Stride, bits)
+}
+
+func packedToGray16(dst *image.Gray16, src image.Image) {
+	PackedToGray16(dst, dst.Rect, src.(*PackedImage), image.Point{})
+}
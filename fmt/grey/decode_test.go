@@ -0,0 +1,78 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package grey
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/korandiz/v4l"
+	"github.com/korandiz/v4l/decode"
+)
+
+func TestDecode(t *testing.T) {
+	data := make([]byte, 3*12)
+	for i := range data {
+		data[i] = uint8(29 * i)
+	}
+	l := decode.Layout{FourCC: FourCC, Width: 5, Height: 3, Stride: 12}
+	got, err := decode.DecodeLayout(nil, data, l)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want, _ := WrapGrey(data, 5, 3, 12)
+	if g, ok := got.(*image.Gray); !ok || g.GrayAt(4, 2) != want.GrayAt(4, 2) {
+		t.Errorf("GREY: got %v, want %v\n", got, want)
+	}
+
+	for _, test := range []struct {
+		fourcc v4l.FourCC
+		bits   int
+		packed bool
+	}{
+		{FourCCY10, 10, false},
+		{FourCCY16, 16, false},
+		{FourCCY12P, 12, true},
+	} {
+		l.FourCC, l.Width = test.fourcc, 4
+		want := image.NewGray16(image.Rect(0, 0, 4, 3))
+		if test.packed {
+			src, err := WrapPacked(data, 4, 3, 12, test.bits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			PackedToGray16(want, want.Rect, src, image.Point{})
+		} else {
+			src, err := Wrap(data, 4, 3, 12, test.bits)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ToGray16(want, want.Rect, src, image.Point{})
+		}
+		got, err := decode.DecodeLayout(nil, data, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, ok := got.(*image.Gray16); !ok || !bytes.Equal(g.Pix, want.Pix) {
+			t.Errorf("%d bits: got %v, want %v\n", test.bits, got, want)
+		}
+	}
+}
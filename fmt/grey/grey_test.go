@@ -0,0 +1,184 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package grey
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+func TestWrapGrey(t *testing.T) {
+	data := []byte{1, 2, 3, 0, 4, 5, 6}
+	img, err := WrapGrey(data, 3, 2, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := img.GrayAt(2, 1); c.Y != 6 {
+		t.Errorf("got: %v, expected: 6\n", c.Y)
+	}
+	img.Pix[0] = 9
+	if data[0] != 9 {
+		t.Errorf("image doesn't share memory with the buffer\n")
+	}
+	if _, err := WrapGrey(data[:6], 3, 2, 4); err != ErrShortBuffer {
+		t.Errorf("got: %v, expected: %v\n", err, ErrShortBuffer)
+	}
+	if _, err := WrapGrey(data, 3, 2, 2); err != ErrDimensions {
+		t.Errorf("got: %v, expected: %v\n", err, ErrDimensions)
+	}
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		bits  int
+		pixel []byte
+		value uint16
+		gray  uint16
+	}{
+		{10, []byte{0xff, 0x03}, 0x3ff, 0xffff},
+		{10, []byte{0x00, 0x02}, 0x200, 0x8020},
+		{10, []byte{0x00, 0xfe}, 0x200, 0x8020},
+		{12, []byte{0x01, 0x08}, 0x801, 0x8018},
+		{16, []byte{0x34, 0x12}, 0x1234, 0x1234},
+	}
+	for _, test := range tests {
+		img, err := Wrap(test.pixel, 1, 1, 2, test.bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if v := img.Value(0, 0); v != test.value {
+			t.Errorf("Y%d %v: Value: got: %#x, expected: %#x\n", test.bits, test.pixel, v, test.value)
+		}
+		if c := img.Gray16At(0, 0); c.Y != test.gray {
+			t.Errorf("Y%d %v: Gray16At: got: %#x, expected: %#x\n", test.bits, test.pixel, c.Y, test.gray)
+		}
+	}
+	if _, err := Wrap(make([]byte, 7), 2, 2, 4, 10); err != ErrShortBuffer {
+		t.Errorf("got: %v, expected: %v\n", err, ErrShortBuffer)
+	}
+	if _, err := Wrap(make([]byte, 8), 2, 2, 4, 8); err != ErrDimensions {
+		t.Errorf("got: %v, expected: %v\n", err, ErrDimensions)
+	}
+}
+
+func TestWrapPacked(t *testing.T) {
+	tests := []struct {
+		bits   int
+		data   []byte
+		values []uint16
+	}{
+		{10, []byte{0x80, 0x40, 0xff, 0x00, 0xe4}, []uint16{0x200, 0x101, 0x3fe, 0x003}},
+		{12, []byte{0xab, 0xcd, 0x21}, []uint16{0xab1, 0xcd2}},
+	}
+	for _, test := range tests {
+		img, err := WrapPacked(test.data, len(test.values), 1, len(test.data), test.bits)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for x, v := range test.values {
+			if got := img.Value(x, 0); got != v {
+				t.Errorf("Y%dP: Value(%d, 0): got: %#x, expected: %#x\n", test.bits, x, got, v)
+			}
+		}
+	}
+	if _, err := WrapPacked(make([]byte, 9), 5, 1, 9, 10); err != ErrDimensions {
+		t.Errorf("got: %v, expected: %v\n", err, ErrDimensions)
+	}
+	if _, err := WrapPacked(make([]byte, 9), 5, 1, 10, 10); err != ErrShortBuffer {
+		t.Errorf("got: %v, expected: %v\n", err, ErrShortBuffer)
+	}
+}
+
+// newSrc returns test images of 9x5 pixels with padding at the end of the
+// lines.
+func newSrc(bits int) (*Image, *PackedImage) {
+	img := &Image{make([]byte, 22*5), 22, image.Rect(0, 0, 9, 5), bits}
+	packed := &PackedImage{make([]byte, 16*5), 16, image.Rect(0, 0, 9, 5), 10}
+	if bits == 12 {
+		packed.Bits = 12
+	}
+	for i := range img.Pix {
+		img.Pix[i] = byte(i*37 + 11)
+	}
+	for i := range packed.Pix {
+		packed.Pix[i] = byte(i*53 + 7)
+	}
+	return img, packed
+}
+
+func TestSubImage(t *testing.T) {
+	for _, bits := range []int{10, 12} {
+		img, packed := newSrc(bits)
+		for _, src := range []image.Image{img, packed} {
+			for _, r := range []image.Rectangle{
+				image.Rect(1, 1, 8, 4), image.Rect(3, 0, 9, 5), image.Rect(-2, 2, 5, 9),
+			} {
+				sub := src.(interface {
+					SubImage(image.Rectangle) image.Image
+				}).SubImage(r)
+				for y := -1; y < 6; y++ {
+					for x := -1; x < 10; x++ {
+						c0 := sub.At(x, y)
+						c1 := color.Color(color.Gray16{})
+						if image.Pt(x, y).In(r) {
+							c1 = src.At(x, y)
+						}
+						if c0 != c1 {
+							t.Errorf("got: %v, expected: %v (x=%d, y=%d, r=%v, %T)\n",
+								c0, c1, x, y, r, src)
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestConvert(t *testing.T) {
+	r0 := image.Rect(-2, -3, 10, 9)
+	for _, bits := range []int{10, 12, 16} {
+		img, packed := newSrc(bits)
+		for _, r := range []image.Rectangle{r0, image.Rect(-5, 1, 4, 20), image.Rect(1, 2, 5, 6)} {
+			for _, p := range []image.Point{{0, 0}, {-2, 1}, {3, 2}} {
+				gray0, gray1 := image.NewGray(r0), image.NewGray(r0)
+				ToGray(gray0, r, img, p)
+				draw.Draw(gray1, r, img, p, draw.Over)
+				gray16a, gray16b := image.NewGray16(r0), image.NewGray16(r0)
+				ToGray16(gray16a, r, img, p)
+				draw.Draw(gray16b, r, img, p, draw.Over)
+				if string(gray0.Pix) != string(gray1.Pix) || string(gray16a.Pix) != string(gray16b.Pix) {
+					t.Errorf("Y%d: conversion differs from image/draw (r=%v, p=%v)\n", bits, r, p)
+				}
+				if bits == 16 {
+					continue
+				}
+				gray0, gray1 = image.NewGray(r0), image.NewGray(r0)
+				PackedToGray(gray0, r, packed, p)
+				draw.Draw(gray1, r, packed, p, draw.Over)
+				gray16a, gray16b = image.NewGray16(r0), image.NewGray16(r0)
+				PackedToGray16(gray16a, r, packed, p)
+				draw.Draw(gray16b, r, packed, p, draw.Over)
+				if string(gray0.Pix) != string(gray1.Pix) || string(gray16a.Pix) != string(gray16b.Pix) {
+					t.Errorf("Y%dP: conversion differs from image/draw (r=%v, p=%v)\n", bits, r, p)
+				}
+			}
+		}
+	}
+}
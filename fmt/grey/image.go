@@ -0,0 +1,277 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package grey provides support for the greyscale formats: GREY, Y10, Y12, Y16,
+// and the MIPI-packed Y10P and Y12P.
+//
+// GREY images are wrapped as *image.Gray. The other formats have their own
+// image types, which use the pixel data in place too. The decoders registered
+// with package decode copy GREY frames to *image.Gray, and convert the others to
+// *image.Gray16.
+package grey
+
+import (
+	"image"
+	"image/color"
+)
+
+// FourCCs of the supported formats.
+const (
+	FourCC     = 'G' | 'R'<<8 | 'E'<<16 | 'Y'<<24
+	FourCCY10  = 'Y' | '1'<<8 | '0'<<16 | ' '<<24
+	FourCCY12  = 'Y' | '1'<<8 | '2'<<16 | ' '<<24
+	FourCCY16  = 'Y' | '1'<<8 | '6'<<16 | ' '<<24
+	FourCCY10P = 'Y' | '1'<<8 | '0'<<16 | 'P'<<24
+	FourCCY12P = 'Y' | '1'<<8 | '2'<<16 | 'P'<<24
+)
+
+// An Error is an error message returned by the Wrap functions.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	// ErrDimensions means that the width, height, stride and bit depth don't
+	// describe a valid image.
+	ErrDimensions = Error("invalid image dimensions")
+
+	// ErrShortBuffer means that the buffer is too small for the image.
+	ErrShortBuffer = Error("buffer too small for image")
+)
+
+// checkSize validates the dimensions of an image whose lines take up at least
+// lineLen bytes.
+func checkSize(data []byte, width, height, stride, lineLen int) error {
+	if width < 0 || height < 0 || stride < lineLen {
+		return ErrDimensions
+	}
+	if height > 0 && len(data) < stride*(height-1)+lineLen {
+		return ErrShortBuffer
+	}
+	return nil
+}
+
+// WrapGrey returns a GREY image with the given dimensions and stride (e.g.
+// BufferInfo.ImageStride) that uses data as its pixel data.
+func WrapGrey(data []byte, width, height, stride int) (*image.Gray, error) {
+	if err := checkSize(data, width, height, stride, width); err != nil {
+		return nil, err
+	}
+	return &image.Gray{Pix: data, Stride: stride, Rect: image.Rect(0, 0, width, height)}, nil
+}
+
+// An Image is an in-memory Y10, Y12 or Y16 image. It implements the
+// image.Image interface.
+type Image struct {
+	// Pix holds the pixel data. Every pixel is a little-endian 16-bit word,
+	// of which only the low Bits bits are significant.
+	Pix []uint8
+
+	// Stride is the distance in bytes between vertically adjacent pixels.
+	// Line y starts at index (y-Rect.Min.Y)*Stride.
+	Stride int
+
+	// Rect is the bounds of the image.
+	Rect image.Rectangle
+
+	// Bits is the bit depth, from 9 to 16.
+	Bits int
+}
+
+// New returns a new image with the given bounds and bit depth.
+func New(rect image.Rectangle, bits int) *Image {
+	w, h := rect.Dx(), rect.Dy()
+	return &Image{make([]uint8, 2*w*h), 2 * w, rect, bits}
+}
+
+// Wrap returns an image with the given dimensions, stride and bit depth (e.g.
+// 10 for Y10) that uses data as its pixel data.
+func Wrap(data []byte, width, height, stride, bits int) (*Image, error) {
+	if bits < 9 || bits > 16 {
+		return nil, ErrDimensions
+	}
+	if err := checkSize(data, width, height, stride, 2*width); err != nil {
+		return nil, err
+	}
+	return &Image{data, stride, image.Rect(0, 0, width, height), bits}, nil
+}
+
+// ColorModel returns color.Gray16Model.
+func (img *Image) ColorModel() color.Model {
+	return color.Gray16Model
+}
+
+// Bounds returns the bounds of the image.
+func (img *Image) Bounds() image.Rectangle {
+	return img.Rect
+}
+
+// At returns the color of the pixel at (x, y).
+func (img *Image) At(x, y int) color.Color {
+	return img.Gray16At(x, y)
+}
+
+// Gray16At returns the color of the pixel at (x, y), scaled to 16 bits.
+func (img *Image) Gray16At(x, y int) color.Gray16 {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.Gray16{}
+	}
+	return color.Gray16{scale(img.Value(x, y), img.Bits)}
+}
+
+// Value returns the raw value of the pixel at (x, y). It must be within the
+// bounds of the image.
+func (img *Image) Value(x, y int) uint16 {
+	i := img.PixOffset(x, y)
+	v := uint16(img.Pix[i]) | uint16(img.Pix[i+1])<<8
+	return v & (1<<uint(img.Bits) - 1)
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (img *Image) PixOffset(x, y int) int {
+	return (y-img.Rect.Min.Y)*img.Stride + (x-img.Rect.Min.X)*2
+}
+
+// SubImage returns an image representing the portion of img visible through
+// rect. The returned value shares pixels with the original image.
+func (img *Image) SubImage(rect image.Rectangle) image.Image {
+	rect = rect.Intersect(img.Rect)
+	if rect.Empty() {
+		return &Image{Bits: img.Bits}
+	}
+	i := img.PixOffset(rect.Min.X, rect.Min.Y)
+	return &Image{img.Pix[i:], img.Stride, rect, img.Bits}
+}
+
+// Opaque returns true.
+func (img *Image) Opaque() bool {
+	return true
+}
+
+// A PackedImage is an in-memory Y10P or Y12P image. It implements the
+// image.Image interface.
+type PackedImage struct {
+	// Pix holds the pixel data. In Y10P, every 5 bytes hold 4 pixels: first
+	// the high 8 bits of each, then their low 2 bits, starting from the least
+	// significant bits of the last byte. In Y12P, every 3 bytes hold 2 pixels
+	// in the same fashion.
+	Pix []uint8
+
+	// Stride is the distance in bytes between vertically adjacent pixels.
+	// Line y starts at index (y-Rect.Min.Y)*Stride.
+	Stride int
+
+	// Rect is the bounds of the image. Groups are aligned to x coordinates
+	// that are multiples of their number of pixels, i.e. 4 for Y10P and 2
+	// for Y12P.
+	Rect image.Rectangle
+
+	// Bits is the bit depth, either 10 or 12.
+	Bits int
+}
+
+// WrapPacked returns a packed image with the given dimensions, stride and bit
+// depth (10 for Y10P, 12 for Y12P) that uses data as its pixel data.
+func WrapPacked(data []byte, width, height, stride, bits int) (*PackedImage, error) {
+	if bits != 10 && bits != 12 {
+		return nil, ErrDimensions
+	}
+	img := &PackedImage{data, stride, image.Rect(0, 0, width, height), bits}
+	shift, b := img.group()
+	n := 1 << shift
+	if err := checkSize(data, width, height, stride, (width+n-1)/n*b); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+// group returns the base 2 logarithm of the number of pixels in a group, and
+// the number of bytes they take up.
+func (img *PackedImage) group() (shift uint, bytes int) {
+	if img.Bits == 12 {
+		return 1, 3
+	}
+	return 2, 5
+}
+
+// ColorModel returns color.Gray16Model.
+func (img *PackedImage) ColorModel() color.Model {
+	return color.Gray16Model
+}
+
+// Bounds returns the bounds of the image.
+func (img *PackedImage) Bounds() image.Rectangle {
+	return img.Rect
+}
+
+// At returns the color of the pixel at (x, y).
+func (img *PackedImage) At(x, y int) color.Color {
+	return img.Gray16At(x, y)
+}
+
+// Gray16At returns the color of the pixel at (x, y), scaled to 16 bits.
+func (img *PackedImage) Gray16At(x, y int) color.Gray16 {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.Gray16{}
+	}
+	return color.Gray16{scale(img.Value(x, y), img.Bits)}
+}
+
+// Value returns the raw value of the pixel at (x, y). It must be within the
+// bounds of the image.
+func (img *PackedImage) Value(x, y int) uint16 {
+	shift, _ := img.group()
+	g := img.Pix[img.GroupOffset(x, y):]
+	k := uint(x) & (1<<shift - 1)
+	lowBits := uint(img.Bits - 8)
+	low := g[1<<shift] >> (k * lowBits) & (1<<lowBits - 1)
+	return uint16(g[k])<<lowBits | uint16(low)
+}
+
+// GroupOffset returns the index of the first element of Pix that corresponds
+// to the group of pixels to which (x, y) belongs.
+func (img *PackedImage) GroupOffset(x, y int) int {
+	shift, b := img.group()
+	return (y-img.Rect.Min.Y)*img.Stride + (x>>shift-img.Rect.Min.X>>shift)*b
+}
+
+// SubImage returns an image representing the portion of img visible through
+// rect. The returned value shares pixels with the original image.
+func (img *PackedImage) SubImage(rect image.Rectangle) image.Image {
+	rect = rect.Intersect(img.Rect)
+	if rect.Empty() {
+		return &PackedImage{Bits: img.Bits}
+	}
+	i := img.GroupOffset(rect.Min.X, rect.Min.Y)
+	return &PackedImage{img.Pix[i:], img.Stride, rect, img.Bits}
+}
+
+// Opaque returns true.
+func (img *PackedImage) Opaque() bool {
+	return true
+}
+
+// scale scales a value with the given bit depth to 16 bits.
+func scale(v uint16, bits int) uint16 {
+	if bits >= 16 {
+		return v
+	}
+	return v<<uint(16-bits) | v>>uint(2*bits-16)
+}
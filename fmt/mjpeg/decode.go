@@ -0,0 +1,37 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package mjpeg
+
+import (
+	"bytes"
+	"image"
+	"image/jpeg"
+
+	"github.com/korandiz/v4l/decode"
+)
+
+func init() {
+	decode.Register(FourCC, decodeJPEG)
+}
+
+// decodeJPEG decodes a frame with image/jpeg. It never reuses dst.
+func decodeJPEG(dst image.Image, data []byte, l decode.Layout) (image.Image, error) {
+	return jpeg.Decode(bytes.NewReader(data))
+}
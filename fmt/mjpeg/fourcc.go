@@ -14,10 +14,16 @@
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-// Package mjpeg just defines the FourCC of the MJPEG format.
+// Package mjpeg defines the FourCC of the MJPEG format, and registers a
+// decoder for it with package decode.
 //
-// An MJPEG stream is simply a sequence of JPEG images. Use the image/jpeg
-// package for decoding.
+// An MJPEG stream is simply a sequence of JPEG images, so frames are decoded
+// with image/jpeg. It always allocates a new image, so unlike for the other
+// formats, decode.DecodeInto doesn't reuse dst for MJPEG.
+//
+// The compression quality and the markers included in the images can be
+// changed with the JPEGParams and SetJPEGParams methods of v4l.Device, after
+// configuring it for this format.
 package mjpeg
 
 const FourCC = 'M' | 'J'<<8 | 'P'<<16 | 'G'<<24
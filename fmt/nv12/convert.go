@@ -0,0 +1,131 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nv12
+
+import "image"
+
+// ToRGBA aligns r.Min in dst with p in src, and draws the part of src visible
+// through r over src.
+//
+// It's several times faster than the image/draw package.
+func ToRGBA(dst *image.RGBA, r image.Rectangle, src *Image, p image.Point) {
+	v := p.Sub(r.Min)
+	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
+	p = r.Min.Add(v)
+	if r.Empty() {
+		return
+	}
+	cbi, cri := src.chromaOrder()
+	for y := 0; y < r.Dy(); y++ {
+		sy := src.Y[src.YOffset(p.X, p.Y+y):]
+		suv := src.UV[src.UVOffset(p.X, p.Y+y):]
+		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
+		for x := 0; x < r.Dx(); x++ {
+			c := ((p.X+x)>>1 - p.X>>1) * 2
+			yy := int32(sy[x]) * 0x010101
+			cb := int32(suv[c+cbi]) - 128
+			cr := int32(suv[c+cri]) - 128
+			d[4*x+0] = clamp(yy + 91881*cr)
+			d[4*x+1] = clamp(yy - 22554*cb - 46802*cr)
+			d[4*x+2] = clamp(yy + 116130*cb)
+			d[4*x+3] = 255
+		}
+	}
+}
+
+func clamp(x int32) uint8 {
+	if uint32(x)&0xff000000 == 0 {
+		return uint8(x >> 16)
+	} else {
+		return uint8(^(x >> 31))
+	}
+}
+
+// ToGray aligns r.Min in dst with p in src, and draws the part of src visible
+// through r over src.
+//
+// It's several times faster than the image/draw package.
+func ToGray(dst *image.Gray, r image.Rectangle, src *Image, p image.Point) {
+	v := p.Sub(r.Min)
+	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
+	p = r.Min.Add(v)
+	if r.Empty() {
+		return
+	}
+	cbi, cri := src.chromaOrder()
+	for y := 0; y < r.Dy(); y++ {
+		sy := src.Y[src.YOffset(p.X, p.Y+y):]
+		suv := src.UV[src.UVOffset(p.X, p.Y+y):]
+		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
+		for x := 0; x < r.Dx(); x++ {
+			c := ((p.X+x)>>1 - p.X>>1) * 2
+			yy := int32(sy[x]) * 0x010101
+			cb := int32(suv[c+cbi]) - 128
+			cr := int32(suv[c+cri]) - 128
+			r := clamp2(yy + 91881*cr)
+			g := clamp2(yy - 22554*cb - 46802*cr)
+			b := clamp2(yy + 116130*cb)
+			d[x] = uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
+		}
+	}
+}
+
+func clamp2(x int32) int32 {
+	if uint32(x)&0xff000000 == 0 {
+		return (x >> 8) & 0xffff
+	} else {
+		return ^(x >> 31) & 0xffff
+	}
+}
+
+// ToYCbCr aligns r.Min in dst with p in src, and draws the part of src visible
+// through r over src.
+//
+// Panics if the subsample ratio of dst is not 4:4:4.
+func ToYCbCr(dst *image.YCbCr, r image.Rectangle, src *Image, p image.Point) {
+	if dst.SubsampleRatio != image.YCbCrSubsampleRatio444 {
+		panic("subsample ratio must be 4:4:4")
+	}
+	v := p.Sub(r.Min)
+	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
+	p = r.Min.Add(v)
+	if r.Empty() {
+		return
+	}
+	cbi, cri := src.chromaOrder()
+	for y := 0; y < r.Dy(); y++ {
+		sy := src.Y[src.YOffset(p.X, p.Y+y):]
+		suv := src.UV[src.UVOffset(p.X, p.Y+y):]
+		dy := dst.Y[dst.YOffset(r.Min.X, r.Min.Y+y):]
+		dcb := dst.Cb[dst.COffset(r.Min.X, r.Min.Y+y):]
+		dcr := dst.Cr[dst.COffset(r.Min.X, r.Min.Y+y):]
+		copy(dy[:r.Dx()], sy)
+		for x := 0; x < r.Dx(); x++ {
+			c := ((p.X+x)>>1 - p.X>>1) * 2
+			dcb[x], dcr[x] = suv[c+cbi], suv[c+cri]
+		}
+	}
+}
+
+// chromaOrder returns the positions of Cb and Cr within a pair of chroma
+// samples.
+func (img *Image) chromaOrder() (cb, cr int) {
+	if img.NV21 {
+		return 1, 0
+	}
+	return 0, 1
+}
@@ -0,0 +1,52 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package nv12
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/korandiz/v4l"
+	"github.com/korandiz/v4l/decode"
+)
+
+func TestDecode(t *testing.T) {
+	data := make([]byte, 6*4+6*2)
+	for i := range data {
+		data[i] = uint8(41 * i)
+	}
+	for _, fourcc := range []v4l.FourCC{FourCC, FourCCNV21} {
+		src, err := Wrap(data, 5, 4, 6)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src.NV21 = fourcc == FourCCNV21
+		want := image.NewRGBA(src.Rect)
+		ToRGBA(want, want.Rect, src, image.Point{})
+		got, err := decode.DecodeLayout(nil, data, decode.Layout{FourCC: fourcc, Width: 5, Height: 4, Stride: 6})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, ok := got.(*image.RGBA); !ok || !bytes.Equal(g.Pix, want.Pix) {
+			t.Errorf("%v: got %v, want %v\n", fourcc, got, want)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package nv12 provides support for the NV12 and NV21 formats.
+//
+// Importing the package registers decoders with package decode, which convert
+// frames to *image.RGBA.
+package nv12
+
+import (
+	"image"
+	"image/color"
+)
+
+// FourCC of the NV12 format.
+const FourCC = 'N' | 'V'<<8 | '1'<<16 | '2'<<24
+
+// FourCCNV21 is the FourCC of the NV21 format, which only differs from NV12 in
+// the order of the chroma samples.
+const FourCCNV21 = 'N' | 'V'<<8 | '2'<<16 | '1'<<24
+
+// An Image is an in-memory NV12 or NV21 image. It implements the image.Image
+// interface.
+//
+// The image has two planes. The luma plane has one sample for each pixel. The
+// chroma plane has a pair of interleaved samples for each 2x2 block of pixels,
+// aligned to even coordinates. The sample order is Cb, Cr for NV12, and Cr, Cb
+// for NV21.
+type Image struct {
+	// Y and UV hold the luma and the chroma plane.
+	Y  []uint8
+	UV []uint8
+
+	// YStride and UVStride are the distances in bytes between vertically
+	// adjacent samples in Y and UV.
+	YStride  int
+	UVStride int
+
+	// Rect is the bounds of the image.
+	Rect image.Rectangle
+
+	// NV21 tells if the chroma samples are in NV21 order.
+	NV21 bool
+}
+
+// New returns a new NV12 image with the given bounds. It can be turned into an
+// NV21 image by setting its NV21 field.
+func New(rect image.Rectangle) *Image {
+	l, r := rect.Min.X&^1, (rect.Max.X+1)&^1
+	t, b := rect.Min.Y&^1, (rect.Max.Y+1)&^1
+	w, h := rect.Dx(), rect.Dy()
+	cw, ch := r-l, (b-t)/2
+	return &Image{
+		Y:        make([]uint8, w*h),
+		UV:       make([]uint8, cw*ch),
+		YStride:  w,
+		UVStride: cw,
+		Rect:     rect,
+	}
+}
+
+// Wrap returns an image with the given dimensions that uses data as its pixel
+// data, as laid out by V4L2 in a single buffer: the luma plane with the given
+// stride (e.g. BufferInfo.ImageStride), immediately followed by the chroma plane
+// with the same stride. It fails with ErrShortBuffer if data is too small.
+//
+// Images with other layouts can be set up by filling in the fields of Image.
+func Wrap(data []byte, width, height, stride int) (*Image, error) {
+	if width < 0 || height < 0 || stride < (width+1)&^1 {
+		return nil, ErrDimensions
+	}
+	uvOffset := stride * height
+	if len(data) < uvOffset+stride*((height+1)/2) {
+		return nil, ErrShortBuffer
+	}
+	return &Image{
+		Y:        data[:uvOffset],
+		UV:       data[uvOffset:],
+		YStride:  stride,
+		UVStride: stride,
+		Rect:     image.Rect(0, 0, width, height),
+	}, nil
+}
+
+// An Error is an error message returned by Wrap.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	// ErrDimensions means that the width, height and stride passed to Wrap
+	// don't describe a valid image.
+	ErrDimensions = Error("invalid image dimensions")
+
+	// ErrShortBuffer means that the buffer passed to Wrap is too small for
+	// the image.
+	ErrShortBuffer = Error("buffer too small for image")
+)
+
+// ColorModel returns color.YCbCrModel.
+func (img *Image) ColorModel() color.Model {
+	return color.YCbCrModel
+}
+
+// Bounds returns the bounds of the image.
+func (img *Image) Bounds() image.Rectangle {
+	return img.Rect
+}
+
+// At returns the color of the pixel at (x, y).
+func (img *Image) At(x, y int) color.Color {
+	return img.YCbCrAt(x, y)
+}
+
+// YCbCrAt returns the color of the pixel at (x, y).
+func (img *Image) YCbCrAt(x, y int) color.YCbCr {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.YCbCr{}
+	}
+	return color.YCbCr{
+		img.Y[img.YOffset(x, y)],
+		img.UV[img.CbOffset(x, y)],
+		img.UV[img.CrOffset(x, y)],
+	}
+}
+
+// YOffset returns the index at which the Y component of the pixel at (x, y) is
+// located in Y.
+func (img *Image) YOffset(x, y int) int {
+	return (y-img.Rect.Min.Y)*img.YStride + (x - img.Rect.Min.X)
+}
+
+// CbOffset returns the index at which the Cb component of the pixel at (x, y)
+// is located in UV.
+func (img *Image) CbOffset(x, y int) int {
+	if img.NV21 {
+		return img.UVOffset(x, y) + 1
+	}
+	return img.UVOffset(x, y)
+}
+
+// CrOffset returns the index at which the Cr component of the pixel at (x, y)
+// is located in UV.
+func (img *Image) CrOffset(x, y int) int {
+	if img.NV21 {
+		return img.UVOffset(x, y)
+	}
+	return img.UVOffset(x, y) + 1
+}
+
+// UVOffset returns the index of the first element in UV that corresponds to the
+// 2x2 block of pixels to which (x, y) belongs.
+func (img *Image) UVOffset(x, y int) int {
+	return (y>>1-img.Rect.Min.Y>>1)*img.UVStride + (x>>1-img.Rect.Min.X>>1)*2
+}
+
+// SubImage returns an image representing the portion of img visible through
+// rect. The returned value shares pixels with the original image.
+func (img *Image) SubImage(rect image.Rectangle) image.Image {
+	rect = rect.Intersect(img.Rect)
+	if rect.Empty() {
+		return &Image{}
+	}
+	return &Image{
+		Y:        img.Y[img.YOffset(rect.Min.X, rect.Min.Y):],
+		UV:       img.UV[img.UVOffset(rect.Min.X, rect.Min.Y):],
+		YStride:  img.YStride,
+		UVStride: img.UVStride,
+		Rect:     rect,
+		NV21:     img.NV21,
+	}
+}
+
+// Opaque returns true.
+func (img *Image) Opaque() bool {
+	return true
+}
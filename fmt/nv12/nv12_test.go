@@ -0,0 +1,215 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package nv12
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+// newSrc returns a test image whose pixels are given by expectedAt.
+func newSrc(nv21 bool) *Image {
+	img := New(image.Rect(11, 21, 18, 28))
+	img.NV21 = nv21
+	r := img.Rect
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c := expectedAt(x, y)
+			img.Y[img.YOffset(x, y)] = c.Y
+			img.UV[img.CbOffset(x, y)] = c.Cb
+			img.UV[img.CrOffset(x, y)] = c.Cr
+		}
+	}
+	return img
+}
+
+func expectedAt(x, y int) color.YCbCr {
+	return color.YCbCr{
+		Y:  byte(x<<4 + y),
+		Cb: byte(x>>1<<5 + y>>1<<2 + 1),
+		Cr: byte(x>>1<<5 + y>>1<<2 + 3),
+	}
+}
+
+// rects returns rectangles around r, overlapping it in various ways.
+func rects(r image.Rectangle) []image.Rectangle {
+	var rs []image.Rectangle
+	for _, min := range []image.Point{r.Min.Sub(image.Pt(2, 1)), r.Min, r.Min.Add(image.Pt(1, 1)), r.Min.Add(image.Pt(2, 3))} {
+		for _, max := range []image.Point{r.Max.Add(image.Pt(1, 2)), r.Max, r.Max.Sub(image.Pt(1, 1)), r.Max.Sub(image.Pt(2, 3))} {
+			rs = append(rs, image.Rectangle{min, max})
+		}
+	}
+	return rs
+}
+
+func TestNew(t *testing.T) {
+	// This test never fails explicitly. If it doesn't panic, it's okay.
+	for L := -3; L < 4; L++ {
+		for T := -3; T < 4; T++ {
+			for R := 8; R < 12; R++ {
+				for B := 8; B < 12; B++ {
+					img := New(image.Rect(L, T, R, B))
+					for y := T; y < B; y++ {
+						for x := L; x < R; x++ {
+							img.At(x, y)
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestImage(t *testing.T) {
+	for _, nv21 := range []bool{false, true} {
+		src := newSrc(nv21)
+		for _, r := range rects(src.Rect) {
+			s := src.SubImage(r)
+			for y := src.Rect.Min.Y - 2; y < src.Rect.Max.Y+2; y++ {
+				for x := src.Rect.Min.X - 2; x < src.Rect.Max.X+2; x++ {
+					p := image.Pt(x, y)
+					c0 := s.At(x, y)
+					c1 := color.YCbCr{}
+					if p.In(r) && p.In(src.Rect) {
+						c1 = expectedAt(x, y)
+					}
+					if c0 != c1 {
+						t.Errorf("got: %v, expected: %v (x=%d, y=%d, r=%v, nv21=%v)\n",
+							c0, c1, x, y, r, nv21)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestWrap(t *testing.T) {
+	data := []byte{
+		// Y
+		10, 11, 12, 0,
+		20, 21, 22, 0,
+		30, 31, 32, 0,
+		// UV
+		100, 200, 101, 201,
+		102, 202, 103, 203,
+	}
+	img, err := Wrap(data, 3, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		x, y int
+		c    color.YCbCr
+	}{
+		{0, 0, color.YCbCr{10, 100, 200}},
+		{1, 1, color.YCbCr{21, 100, 200}},
+		{2, 0, color.YCbCr{12, 101, 201}},
+		{0, 2, color.YCbCr{30, 102, 202}},
+		{2, 2, color.YCbCr{32, 103, 203}},
+	}
+	for _, test := range tests {
+		if c := img.YCbCrAt(test.x, test.y); c != test.c {
+			t.Errorf("At(%d, %d): got: %v, expected: %v\n", test.x, test.y, c, test.c)
+		}
+	}
+	img.NV21 = true
+	if c := img.YCbCrAt(2, 2); c != (color.YCbCr{32, 203, 103}) {
+		t.Errorf("NV21 At(2, 2): got: %v, expected: %v\n", c, color.YCbCr{32, 203, 103})
+	}
+
+	if _, err := Wrap(data[:len(data)-1], 3, 3, 4); err != ErrShortBuffer {
+		t.Errorf("short buffer: got: %v, expected: %v\n", err, ErrShortBuffer)
+	}
+	if _, err := Wrap(data, 5, 3, 4); err != ErrDimensions {
+		t.Errorf("short stride: got: %v, expected: %v\n", err, ErrDimensions)
+	}
+}
+
+func TestToRGBA(t *testing.T) {
+	for _, nv21 := range []bool{false, true} {
+		src := newSrc(nv21)
+		r0 := image.Rect(-2, -3, 10, 9)
+		for _, r := range rects(image.Rect(0, 0, 7, 7)) {
+			for _, p := range rects(src.Rect) {
+				dst0 := image.NewRGBA(r0)
+				dst1 := image.NewRGBA(r0)
+				ToRGBA(dst0, r, src, p.Min)
+				draw.Draw(dst1, r, src, p.Min, draw.Over)
+				for i := range dst0.Pix {
+					if dst0.Pix[i] != dst1.Pix[i] {
+						t.Errorf("got: %v, expected: %v (r=%v, p=%v, nv21=%v)\n",
+							dst0.Pix, dst1.Pix, r, p.Min, nv21)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestToGray(t *testing.T) {
+	for _, nv21 := range []bool{false, true} {
+		src := newSrc(nv21)
+		r0 := image.Rect(-2, -3, 10, 9)
+		for _, r := range rects(image.Rect(0, 0, 7, 7)) {
+			for _, p := range rects(src.Rect) {
+				dst0 := image.NewGray(r0)
+				dst1 := image.NewGray(r0)
+				ToGray(dst0, r, src, p.Min)
+				draw.Draw(dst1, r, src, p.Min, draw.Over)
+				for i := range dst0.Pix {
+					if dst0.Pix[i] != dst1.Pix[i] {
+						t.Errorf("got: %v, expected: %v (r=%v, p=%v, nv21=%v)\n",
+							dst0.Pix, dst1.Pix, r, p.Min, nv21)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestToYCbCr(t *testing.T) {
+	for _, nv21 := range []bool{false, true} {
+		src := newSrc(nv21)
+		r0 := image.Rect(-2, -3, 10, 9)
+		for _, r := range rects(image.Rect(0, 0, 7, 7)) {
+			for _, p := range rects(src.Rect) {
+				d := p.Min.Sub(r.Min)
+				dst := image.NewYCbCr(r0, image.YCbCrSubsampleRatio444)
+				ToYCbCr(dst, r, src, p.Min)
+				for y := r0.Min.Y; y < r0.Max.Y; y++ {
+					for x := r0.Min.X; x < r0.Max.X; x++ {
+						c0 := dst.At(x, y)
+						c1 := color.YCbCr{}
+						if image.Pt(x, y).In(r) && image.Pt(x, y).Add(d).In(src.Rect) {
+							c1 = expectedAt(x+d.X, y+d.Y)
+						}
+						if c0 != c1 {
+							t.Errorf("got: %v, expected: %v (x=%d, y=%d, r=%v, p=%v, nv21=%v)\n",
+								c0, c1, x, y, r, p.Min, nv21)
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
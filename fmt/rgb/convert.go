@@ -0,0 +1,119 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rgb
+
+import "image"
+
+// ToRGBA aligns r.Min in dst with p in src, and replaces the part of dst
+// visible through r with the corresponding part of src, premultiplying the
+// colors by alpha.
+//
+// It's several times faster than the image/draw package.
+func ToRGBA(dst *image.RGBA, r image.Rectangle, src *Image, p image.Point) {
+	v := p.Sub(r.Min)
+	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
+	p = r.Min.Add(v)
+	if r.Empty() {
+		return
+	}
+	for y := 0; y < r.Dy(); y++ {
+		s := src.Pix[src.PixOffset(p.X, p.Y+y):]
+		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
+		unpack(d, s, r.Dx(), src.Format)
+		if src.Format.HasAlpha() {
+			for x := 0; x < r.Dx(); x++ {
+				a := d[4*x+3]
+				d[4*x+0] = premultiply(d[4*x+0], a)
+				d[4*x+1] = premultiply(d[4*x+1], a)
+				d[4*x+2] = premultiply(d[4*x+2], a)
+			}
+		}
+	}
+}
+
+// premultiply multiplies c by alpha, rounding the way color.NRGBA does.
+func premultiply(c, a uint8) uint8 {
+	return uint8(uint32(c) * 0x101 * uint32(a) / 0xff >> 8)
+}
+
+// ToNRGBA aligns r.Min in dst with p in src, and replaces the part of dst
+// visible through r with the corresponding part of src.
+//
+// It's several times faster than the image/draw package.
+func ToNRGBA(dst *image.NRGBA, r image.Rectangle, src *Image, p image.Point) {
+	v := p.Sub(r.Min)
+	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
+	p = r.Min.Add(v)
+	if r.Empty() {
+		return
+	}
+	for y := 0; y < r.Dy(); y++ {
+		s := src.Pix[src.PixOffset(p.X, p.Y+y):]
+		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
+		unpack(d, s, r.Dx(), src.Format)
+	}
+}
+
+// unpack converts n pixels from s in format f to non-premultiplied RGBA in d.
+func unpack(d, s []uint8, n int, f Format) {
+	switch f {
+	case RGB24:
+		for i := 0; i < n; i++ {
+			d[4*i+0], d[4*i+1], d[4*i+2], d[4*i+3] = s[3*i+0], s[3*i+1], s[3*i+2], 0xff
+		}
+	case BGR24:
+		for i := 0; i < n; i++ {
+			d[4*i+0], d[4*i+1], d[4*i+2], d[4*i+3] = s[3*i+2], s[3*i+1], s[3*i+0], 0xff
+		}
+	case RGB565, RGB565X:
+		lo, hi := 0, 1
+		if f == RGB565X {
+			lo, hi = 1, 0
+		}
+		for i := 0; i < n; i++ {
+			w := uint16(s[2*i+lo]) | uint16(s[2*i+hi])<<8
+			r, g, b := uint8(w>>11), uint8(w>>5)&0x3f, uint8(w)&0x1f
+			d[4*i+0], d[4*i+1], d[4*i+2], d[4*i+3] = r<<3|r>>2, g<<2|g>>4, b<<3|b>>2, 0xff
+		}
+	case RGB555, RGB555X:
+		lo, hi := 0, 1
+		if f == RGB555X {
+			lo, hi = 1, 0
+		}
+		for i := 0; i < n; i++ {
+			w := uint16(s[2*i+lo]) | uint16(s[2*i+hi])<<8
+			r, g, b := uint8(w>>10)&0x1f, uint8(w>>5)&0x1f, uint8(w)&0x1f
+			d[4*i+0], d[4*i+1], d[4*i+2], d[4*i+3] = r<<3|r>>2, g<<3|g>>2, b<<3|b>>2, 0xff
+		}
+	case XRGB32:
+		for i := 0; i < n; i++ {
+			d[4*i+0], d[4*i+1], d[4*i+2], d[4*i+3] = s[4*i+1], s[4*i+2], s[4*i+3], 0xff
+		}
+	case ARGB32:
+		for i := 0; i < n; i++ {
+			d[4*i+0], d[4*i+1], d[4*i+2], d[4*i+3] = s[4*i+1], s[4*i+2], s[4*i+3], s[4*i+0]
+		}
+	case XBGR32:
+		for i := 0; i < n; i++ {
+			d[4*i+0], d[4*i+1], d[4*i+2], d[4*i+3] = s[4*i+2], s[4*i+1], s[4*i+0], 0xff
+		}
+	case ABGR32:
+		for i := 0; i < n; i++ {
+			d[4*i+0], d[4*i+1], d[4*i+2], d[4*i+3] = s[4*i+2], s[4*i+1], s[4*i+0], s[4*i+3]
+		}
+	}
+}
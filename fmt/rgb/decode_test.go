@@ -0,0 +1,59 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package rgb
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/korandiz/v4l"
+	"github.com/korandiz/v4l/decode"
+)
+
+func TestDecode(t *testing.T) {
+	data := make([]byte, 2*16)
+	for i := range data {
+		data[i] = uint8(31 * i)
+	}
+	for _, f := range formats {
+		format, _ := FormatOf(f.fourcc)
+		src, err := Wrap(data, 3, 2, 16, format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := image.NewRGBA(src.Rect)
+		ToRGBA(want, want.Rect, src, image.Point{})
+		l := decode.Layout{FourCC: v4l.FourCC(f.fourcc), Width: 3, Height: 2, Stride: 16}
+		got, err := decode.DecodeLayout(nil, data, l)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, ok := got.(*image.RGBA); !ok || !bytes.Equal(g.Pix, want.Pix) {
+			t.Errorf("%#x: got %v, want %v\n", f.fourcc, got, want)
+		}
+	}
+	if _, err := Wrap(data, 5, 2, 16, XRGB32); err != ErrDimensions {
+		t.Errorf("got %v, want %v\n", err, ErrDimensions)
+	}
+	if _, err := Wrap(data[:30], 4, 2, 16, XRGB32); err != ErrShortBuffer {
+		t.Errorf("got %v, want %v\n", err, ErrShortBuffer)
+	}
+}
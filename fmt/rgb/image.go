@@ -0,0 +1,222 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package rgb provides support for the packed RGB formats.
+//
+// Importing the package registers decoders with package decode, which convert
+// frames to *image.RGBA.
+package rgb
+
+import (
+	"image"
+	"image/color"
+)
+
+// FourCCs of the supported formats.
+const (
+	FourCCRGB24   = 'R' | 'G'<<8 | 'B'<<16 | '3'<<24
+	FourCCBGR24   = 'B' | 'G'<<8 | 'R'<<16 | '3'<<24
+	FourCCRGB565  = 'R' | 'G'<<8 | 'B'<<16 | 'P'<<24
+	FourCCRGB565X = 'R' | 'G'<<8 | 'B'<<16 | 'R'<<24
+	FourCCRGB555  = 'R' | 'G'<<8 | 'B'<<16 | 'O'<<24
+	FourCCRGB555X = 'R' | 'G'<<8 | 'B'<<16 | 'Q'<<24
+	FourCCXRGB32  = 'B' | 'X'<<8 | '2'<<16 | '4'<<24
+	FourCCARGB32  = 'B' | 'A'<<8 | '2'<<16 | '4'<<24
+	FourCCXBGR32  = 'X' | 'R'<<8 | '2'<<16 | '4'<<24
+	FourCCABGR32  = 'A' | 'R'<<8 | '2'<<16 | '4'<<24
+)
+
+// A Format is a pixel layout.
+type Format int
+
+// The 16-bit formats are stored as little-endian words, except for the ones
+// whose name ends in X, which are big-endian. The byte orders of the 32-bit
+// formats are as in memory.
+const (
+	RGB24   Format = iota // R, G, B
+	BGR24                 // B, G, R
+	RGB565                // rrrrrggg gggbbbbb
+	RGB565X               // rrrrrggg gggbbbbb
+	RGB555                // xrrrrrgg gggbbbbb
+	RGB555X               // xrrrrrgg gggbbbbb
+	XRGB32                // X, R, G, B
+	ARGB32                // A, R, G, B
+	XBGR32                // B, G, R, X
+	ABGR32                // B, G, R, A
+)
+
+// FormatOf returns the format with the given FourCC, or false if there is no
+// such format.
+func FormatOf(fourcc uint32) (Format, bool) {
+	switch fourcc {
+	case FourCCRGB24:
+		return RGB24, true
+	case FourCCBGR24:
+		return BGR24, true
+	case FourCCRGB565:
+		return RGB565, true
+	case FourCCRGB565X:
+		return RGB565X, true
+	case FourCCRGB555:
+		return RGB555, true
+	case FourCCRGB555X:
+		return RGB555X, true
+	case FourCCXRGB32:
+		return XRGB32, true
+	case FourCCARGB32:
+		return ARGB32, true
+	case FourCCXBGR32:
+		return XBGR32, true
+	case FourCCABGR32:
+		return ABGR32, true
+	}
+	return 0, false
+}
+
+// BytesPerPixel returns the size of a pixel in bytes.
+func (f Format) BytesPerPixel() int {
+	switch f {
+	case RGB24, BGR24:
+		return 3
+	case RGB565, RGB565X, RGB555, RGB555X:
+		return 2
+	}
+	return 4
+}
+
+// HasAlpha tells if the format has an alpha channel.
+func (f Format) HasAlpha() bool {
+	return f == ARGB32 || f == ABGR32
+}
+
+// An Image is an in-memory image in one of the packed RGB formats. It
+// implements the image.Image interface. Colors with alpha are not
+// premultiplied.
+type Image struct {
+	// Pix holds the pixel data.
+	Pix []uint8
+
+	// Stride is the distance in bytes between vertically adjacent pixels.
+	// Line y starts at index (y-Rect.Min.Y)*Stride.
+	Stride int
+
+	// Rect is the bounds of the image.
+	Rect image.Rectangle
+
+	// Format is the pixel layout.
+	Format Format
+}
+
+// New returns a new image with the given bounds and format.
+func New(rect image.Rectangle, f Format) *Image {
+	w, h := rect.Dx(), rect.Dy()
+	n := f.BytesPerPixel()
+	return &Image{make([]uint8, n*w*h), n * w, rect, f}
+}
+
+// Wrap returns an image with the given dimensions and format that uses data as
+// its pixel data, with lines stride bytes apart (e.g. BufferInfo.ImageStride).
+// It fails with ErrShortBuffer if data is too small.
+func Wrap(data []byte, width, height, stride int, f Format) (*Image, error) {
+	lineLen := f.BytesPerPixel() * width
+	if width < 0 || height < 0 || stride < lineLen || f < RGB24 || f > ABGR32 {
+		return nil, ErrDimensions
+	}
+	if height > 0 && len(data) < stride*(height-1)+lineLen {
+		return nil, ErrShortBuffer
+	}
+	return &Image{data, stride, image.Rect(0, 0, width, height), f}, nil
+}
+
+// An Error is an error message returned by Wrap.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	// ErrDimensions means that the width, height, stride and format passed to
+	// Wrap don't describe a valid image.
+	ErrDimensions = Error("invalid image dimensions")
+
+	// ErrShortBuffer means that the buffer passed to Wrap is too small for
+	// the image.
+	ErrShortBuffer = Error("buffer too small for image")
+)
+
+// ColorModel returns color.NRGBAModel.
+func (img *Image) ColorModel() color.Model {
+	return color.NRGBAModel
+}
+
+// Bounds returns the bounds of the image.
+func (img *Image) Bounds() image.Rectangle {
+	return img.Rect
+}
+
+// At returns the color of the pixel at (x, y).
+func (img *Image) At(x, y int) color.Color {
+	return img.NRGBAAt(x, y)
+}
+
+// NRGBAAt returns the color of the pixel at (x, y).
+func (img *Image) NRGBAAt(x, y int) color.NRGBA {
+	if !(image.Point{x, y}.In(img.Rect)) {
+		return color.NRGBA{}
+	}
+	var c [4]uint8
+	unpack(c[:], img.Pix[img.PixOffset(x, y):], 1, img.Format)
+	return color.NRGBA{c[0], c[1], c[2], c[3]}
+}
+
+// PixOffset returns the index of the first element of Pix that corresponds to
+// the pixel at (x, y).
+func (img *Image) PixOffset(x, y int) int {
+	return (y-img.Rect.Min.Y)*img.Stride + (x-img.Rect.Min.X)*img.Format.BytesPerPixel()
+}
+
+// SubImage returns an image representing the portion of img visible through
+// rect. The returned value shares pixels with the original image.
+func (img *Image) SubImage(rect image.Rectangle) image.Image {
+	rect = rect.Intersect(img.Rect)
+	if rect.Empty() {
+		return &Image{Format: img.Format}
+	}
+	i := img.PixOffset(rect.Min.X, rect.Min.Y)
+	return &Image{img.Pix[i:], img.Stride, rect, img.Format}
+}
+
+// Opaque scans the entire image and reports whether it is fully opaque.
+func (img *Image) Opaque() bool {
+	if !img.Format.HasAlpha() || img.Rect.Empty() {
+		return true
+	}
+	a := 0
+	if img.Format == ABGR32 {
+		a = 3
+	}
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		p := img.Pix[img.PixOffset(img.Rect.Min.X, y):]
+		for x := 0; x < img.Rect.Dx(); x++ {
+			if p[4*x+a] != 0xff {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,154 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package rgb
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var formats = []struct {
+	fourcc uint32
+	format Format
+	pixel  []byte
+	color  color.NRGBA
+}{
+	{FourCCRGB24, RGB24, []byte{1, 2, 3}, color.NRGBA{1, 2, 3, 255}},
+	{FourCCBGR24, BGR24, []byte{1, 2, 3}, color.NRGBA{3, 2, 1, 255}},
+	{FourCCRGB565, RGB565, []byte{0x10, 0x84}, color.NRGBA{0x84, 0x82, 0x84, 255}},
+	{FourCCRGB565X, RGB565X, []byte{0xf8, 0x01}, color.NRGBA{255, 0, 8, 255}},
+	{FourCCRGB555, RGB555, []byte{0x01, 0xfc}, color.NRGBA{255, 0, 8, 255}},
+	{FourCCRGB555X, RGB555X, []byte{0x03, 0xe0}, color.NRGBA{0, 255, 0, 255}},
+	{FourCCXRGB32, XRGB32, []byte{9, 1, 2, 3}, color.NRGBA{1, 2, 3, 255}},
+	{FourCCARGB32, ARGB32, []byte{128, 1, 2, 3}, color.NRGBA{1, 2, 3, 128}},
+	{FourCCXBGR32, XBGR32, []byte{3, 2, 1, 9}, color.NRGBA{1, 2, 3, 255}},
+	{FourCCABGR32, ABGR32, []byte{3, 2, 1, 128}, color.NRGBA{1, 2, 3, 128}},
+}
+
+func TestFormats(t *testing.T) {
+	for _, test := range formats {
+		f, ok := FormatOf(test.fourcc)
+		if !ok || f != test.format {
+			t.Errorf("FormatOf(%#x): got: %v, %v, expected: %v\n", test.fourcc, f, ok, test.format)
+		}
+		if n := f.BytesPerPixel(); n != len(test.pixel) {
+			t.Errorf("format %d: BytesPerPixel: got: %d, expected: %d\n", f, n, len(test.pixel))
+		}
+		img := New(image.Rect(5, 5, 6, 6), test.format)
+		copy(img.Pix, test.pixel)
+		if c := img.NRGBAAt(5, 5); c != test.color {
+			t.Errorf("format %d: got: %v, expected: %v\n", f, c, test.color)
+		}
+		if img.Opaque() != (test.color.A == 255) {
+			t.Errorf("format %d: Opaque: got: %v\n", f, img.Opaque())
+		}
+	}
+	if _, ok := FormatOf('Y' | 'U'<<8 | 'Y'<<16 | 'V'<<24); ok {
+		t.Errorf("FormatOf(YUYV) succeeded\n")
+	}
+}
+
+// newSrc returns a test image with padding at the end of the lines.
+func newSrc(f Format) *Image {
+	rect := image.Rect(3, 5, 10, 11)
+	stride := rect.Dx()*f.BytesPerPixel() + 3
+	img := &Image{make([]byte, stride*rect.Dy()), stride, rect, f}
+	for i := range img.Pix {
+		img.Pix[i] = byte(i*37 + 11)
+	}
+	return img
+}
+
+func TestSubImage(t *testing.T) {
+	for _, test := range formats {
+		src := newSrc(test.format)
+		for _, r := range []image.Rectangle{
+			image.Rect(0, 0, 20, 20),
+			image.Rect(4, 6, 8, 9),
+			image.Rect(9, 10, 12, 12),
+			image.Rect(0, 0, 3, 3),
+		} {
+			s := src.SubImage(r)
+			for y := src.Rect.Min.Y - 1; y < src.Rect.Max.Y+1; y++ {
+				for x := src.Rect.Min.X - 1; x < src.Rect.Max.X+1; x++ {
+					c0 := s.At(x, y)
+					c1 := color.NRGBA{}
+					if p := image.Pt(x, y); p.In(r) {
+						c1 = src.NRGBAAt(x, y)
+					}
+					if c0 != c1 {
+						t.Errorf("got: %v, expected: %v (x=%d, y=%d, r=%v, format=%d)\n",
+							c0, c1, x, y, r, test.format)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestToRGBA(t *testing.T) {
+	r0 := image.Rect(-2, -3, 10, 9)
+	for _, test := range formats {
+		src := newSrc(test.format)
+		for _, r := range []image.Rectangle{r0, image.Rect(-5, 1, 4, 20), image.Rect(1, 2, 5, 6)} {
+			for _, p := range []image.Point{src.Rect.Min, src.Rect.Min.Sub(image.Pt(2, 1)), image.Pt(6, 8)} {
+				dst0 := image.NewRGBA(r0)
+				dst1 := image.NewRGBA(r0)
+				ToRGBA(dst0, r, src, p)
+				draw.Draw(dst1, r, src, p, draw.Src)
+				for i := range dst0.Pix {
+					if dst0.Pix[i] != dst1.Pix[i] {
+						t.Errorf("got: %v, expected: %v (r=%v, p=%v, format=%d)\n",
+							dst0.Pix, dst1.Pix, r, p, test.format)
+						return
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestToNRGBA(t *testing.T) {
+	r0 := image.Rect(-2, -3, 10, 9)
+	for _, test := range formats {
+		src := newSrc(test.format)
+		for _, r := range []image.Rectangle{r0, image.Rect(-5, 1, 4, 20), image.Rect(1, 2, 5, 6)} {
+			for _, p := range []image.Point{src.Rect.Min, src.Rect.Min.Sub(image.Pt(2, 1)), image.Pt(6, 8)} {
+				d := p.Sub(r.Min)
+				dst := image.NewNRGBA(r0)
+				ToNRGBA(dst, r, src, p)
+				for y := r0.Min.Y; y < r0.Max.Y; y++ {
+					for x := r0.Min.X; x < r0.Max.X; x++ {
+						c0 := dst.NRGBAAt(x, y)
+						c1 := color.NRGBA{}
+						if image.Pt(x, y).In(r) {
+							c1 = src.NRGBAAt(x+d.X, y+d.Y)
+						}
+						if c0 != c1 {
+							t.Errorf("got: %v, expected: %v (x=%d, y=%d, r=%v, p=%v, format=%d)\n",
+								c0, c1, x, y, r, p, test.format)
+							return
+						}
+					}
+				}
+			}
+		}
+	}
+}
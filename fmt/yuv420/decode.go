@@ -0,0 +1,51 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package yuv420
+
+import (
+	"image"
+
+	"github.com/korandiz/v4l/decode"
+)
+
+func init() {
+	decode.RegisterYCbCr(wrapLayout, copyYCbCr, image.YCbCrSubsampleRatio420, FourCC, FourCCYV12)
+}
+
+func wrapLayout(data []byte, l decode.Layout) (image.Image, error) {
+	if l.FourCC == FourCCYV12 {
+		return WrapYV12(data, l.Width, l.Height, l.Stride)
+	}
+	return Wrap(data, l.Width, l.Height, l.Stride)
+}
+
+// copyYCbCr copies a frame.
+func copyYCbCr(dst *image.YCbCr, src image.Image) {
+	img := src.(*image.YCbCr)
+	w, h := img.Rect.Dx(), img.Rect.Dy()
+	for y := 0; y < h; y++ {
+		copy(dst.Y[y*dst.YStride:][:w], img.Y[y*img.YStride:])
+	}
+	cw, ch := (w+1)/2, (h+1)/2
+	for y := 0; y < ch; y++ {
+		copy(dst.Cb[y*dst.CStride:][:cw], img.Cb[y*img.CStride:])
+		copy(dst.Cr[y*dst.CStride:][:cw], img.Cr[y*img.CStride:])
+	}
+}
@@ -0,0 +1,60 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package yuv420
+
+import (
+	"image"
+	"testing"
+
+	"github.com/korandiz/v4l"
+	"github.com/korandiz/v4l/decode"
+)
+
+func TestDecode(t *testing.T) {
+	for _, fourcc := range []v4l.FourCC{FourCC, FourCCYV12} {
+		wrap := Wrap
+		if fourcc == FourCCYV12 {
+			wrap = WrapYV12
+		}
+		want, err := wrap(data, 3, 3, 4)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := decode.DecodeLayout(nil, data, decode.Layout{FourCC: fourcc, Width: 3, Height: 3, Stride: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		g, ok := got.(*image.YCbCr)
+		if !ok {
+			t.Fatalf("got %T\n", got)
+		}
+		for y := 0; y < 3; y++ {
+			for x := 0; x < 3; x++ {
+				if g.YCbCrAt(x, y) != want.YCbCrAt(x, y) {
+					t.Errorf("%v: (%d, %d): got %v, want %v\n", fourcc, x, y,
+						g.YCbCrAt(x, y), want.YCbCrAt(x, y))
+				}
+			}
+		}
+		if &g.Y[0] == &data[0] {
+			t.Errorf("%v: data not copied\n", fourcc)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Package yuv420 provides support for the planar YUV 4:2:0 formats YU12 (also
+// known as I420) and YV12.
+//
+// Images in these formats are wrapped as *image.YCbCr without copying, so they
+// can be used directly with the standard library, e.g. the image/draw and
+// image/jpeg packages. The decoders registered with package decode copy frames
+// to *image.YCbCr instead.
+package yuv420
+
+import "image"
+
+// FourCC of the YU12 format. The image has three planes: Y, followed by Cb and
+// Cr at half the horizontal and vertical resolution.
+const FourCC = 'Y' | 'U'<<8 | '1'<<16 | '2'<<24
+
+// FourCCYV12 is the FourCC of the YV12 format, which only differs from YU12 in
+// the order of the chroma planes: Cr comes before Cb.
+const FourCCYV12 = 'Y' | 'V'<<8 | '1'<<16 | '2'<<24
+
+// An Error is an error message returned by Wrap.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	// ErrDimensions means that the width, height and stride passed to Wrap
+	// don't describe a valid image.
+	ErrDimensions = Error("invalid image dimensions")
+
+	// ErrShortBuffer means that the buffer passed to Wrap is too small for
+	// the image.
+	ErrShortBuffer = Error("buffer too small for image")
+)
+
+// Wrap returns an image with the given dimensions that uses data as its pixel
+// data, as laid out by V4L2 in a single YU12 buffer: the luma plane with the
+// given stride (e.g. BufferInfo.ImageStride), followed by the Cb and the Cr
+// plane with half that stride. It fails with ErrShortBuffer if data is too
+// small.
+func Wrap(data []byte, width, height, stride int) (*image.YCbCr, error) {
+	return wrap(data, width, height, stride, false)
+}
+
+// WrapYV12 is like Wrap, but for YV12 buffers.
+func WrapYV12(data []byte, width, height, stride int) (*image.YCbCr, error) {
+	return wrap(data, width, height, stride, true)
+}
+
+func wrap(data []byte, width, height, stride int, yv12 bool) (*image.YCbCr, error) {
+	cstride := stride / 2
+	if width < 0 || height < 0 || stride < width || cstride < (width+1)/2 {
+		return nil, ErrDimensions
+	}
+	ySize := stride * height
+	cSize := cstride * ((height + 1) / 2)
+	if len(data) < ySize+2*cSize {
+		return nil, ErrShortBuffer
+	}
+	c1 := data[ySize : ySize+cSize : ySize+cSize]
+	c2 := data[ySize+cSize : ySize+2*cSize : ySize+2*cSize]
+	if yv12 {
+		c1, c2 = c2, c1
+	}
+	return &image.YCbCr{
+		Y:              data[:ySize:ySize],
+		Cb:             c1,
+		Cr:             c2,
+		YStride:        stride,
+		CStride:        cstride,
+		SubsampleRatio: image.YCbCrSubsampleRatio420,
+		Rect:           image.Rect(0, 0, width, height),
+	}, nil
+}
@@ -0,0 +1,124 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package yuv420
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"testing"
+)
+
+// data is a 3x3 image with a stride of 4.
+var data = []byte{
+	// Y
+	10, 11, 12, 0,
+	20, 21, 22, 0,
+	30, 31, 32, 0,
+	// First chroma plane
+	100, 101,
+	102, 103,
+	// Second chroma plane
+	200, 201,
+	202, 203,
+}
+
+func TestWrap(t *testing.T) {
+	tests := []struct {
+		x, y int
+		c    color.YCbCr
+	}{
+		{0, 0, color.YCbCr{10, 100, 200}},
+		{1, 1, color.YCbCr{21, 100, 200}},
+		{2, 0, color.YCbCr{12, 101, 201}},
+		{0, 2, color.YCbCr{30, 102, 202}},
+		{2, 2, color.YCbCr{32, 103, 203}},
+	}
+
+	img, err := Wrap(data, 3, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		if c := img.YCbCrAt(test.x, test.y); c != test.c {
+			t.Errorf("At(%d, %d): got: %v, expected: %v\n", test.x, test.y, c, test.c)
+		}
+	}
+
+	img, err = WrapYV12(data, 3, 3, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, test := range tests {
+		c := test.c
+		c.Cb, c.Cr = c.Cr, c.Cb
+		if got := img.YCbCrAt(test.x, test.y); got != c {
+			t.Errorf("YV12 At(%d, %d): got: %v, expected: %v\n", test.x, test.y, got, c)
+		}
+	}
+
+	// The image shares memory with the buffer.
+	img.Y[0] = 99
+	if data[0] != 99 {
+		t.Errorf("image doesn't share memory with the buffer\n")
+	}
+	data[0] = 10
+}
+
+func TestWrapErrors(t *testing.T) {
+	tests := []struct {
+		n, w, h, stride int
+		err             error
+	}{
+		{len(data), 3, 3, 4, nil},
+		{len(data) - 1, 3, 3, 4, ErrShortBuffer},
+		{len(data), 5, 3, 4, ErrDimensions},
+		{len(data), 3, 3, 3, ErrDimensions},
+		{len(data), 3, -1, 4, ErrDimensions},
+		{0, 0, 0, 0, nil},
+	}
+	for _, test := range tests {
+		_, err := Wrap(data[:test.n], test.w, test.h, test.stride)
+		if err != test.err {
+			t.Errorf("Wrap(%d bytes, %d, %d, %d): got: %v, expected: %v\n",
+				test.n, test.w, test.h, test.stride, err, test.err)
+		}
+	}
+}
+
+func TestEncode(t *testing.T) {
+	buf := make([]byte, 64*48*3/2)
+	for i := range buf {
+		buf[i] = byte(i)
+	}
+	img, err := Wrap(buf, 64, 48, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := jpeg.Encode(&out, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	dec, err := jpeg.Decode(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dec.Bounds() != image.Rect(0, 0, 64, 48) {
+		t.Errorf("got: %v, expected: %v\n", dec.Bounds(), image.Rect(0, 0, 64, 48))
+	}
+}
@@ -16,48 +16,119 @@
 
 package yuyv
 
-import "image"
+import (
+	"image"
+	"image/color"
+	"math"
+)
+
+// A Matrix selects the coefficients of the conversion between YCbCr and RGB.
+type Matrix int
+
+const (
+	BT601  Matrix = iota // SD video, and JPEG
+	BT709                // HD video
+	BT2020               // UHD video
+)
+
+// Options select how the converters interpret the samples of the source image.
+// The zero value selects BT.601 with full range, which is what the image/color
+// package uses. Options don't affect the At method of Image, which returns the
+// samples as they are.
+type Options struct {
+	// Matrix is the conversion matrix.
+	Matrix Matrix
+
+	// Limited tells that the samples use the limited (or "video") range,
+	// i.e. Y is in [16, 235], and Cb and Cr are in [16, 240], as opposed to
+	// the full [0, 255].
+	Limited bool
+}
+
+// coeffs are the factors of a conversion to RGB in 16.16 fixed point. A
+// channel is computed as (Y-yoff)*y + bias plus the chroma terms.
+type coeffs struct {
+	y, yoff, bias  int32
+	rv, gu, gv, bu int32
+}
+
+// coeffs returns the conversion factors for o. The ones of the zero Options are
+// the same as in the image/color package.
+func (o Options) coeffs() coeffs {
+	if o == (Options{}) {
+		return coeffs{0x010101, 0, 0, 91881, 22554, 46802, 116130}
+	}
+	kr, kb := 0.299, 0.114
+	switch o.Matrix {
+	case BT709:
+		kr, kb = 0.2126, 0.0722
+	case BT2020:
+		kr, kb = 0.2627, 0.0593
+	}
+	kg := 1 - kr - kb
+	c := coeffs{y: 0x010101}
+	scale := 1.0
+	if o.Limited {
+		c.y, c.yoff, c.bias = 76309, 16, 1<<15 // 255/219
+		scale = 255.0 / 224
+	}
+	fix := func(x float64) int32 {
+		return int32(math.Round(x * scale * 65536))
+	}
+	c.rv = fix(2 * (1 - kr))
+	c.gu = fix(2 * (1 - kb) * kb / kg)
+	c.gv = fix(2 * (1 - kr) * kr / kg)
+	c.bu = fix(2 * (1 - kb))
+	return c
+}
 
 // ToRGBA aligns r.Min in dst with p in src, and draws the part of src visible
 // through r over src.
 //
 // It's several times faster than the image/draw package.
 func ToRGBA(dst *image.RGBA, r image.Rectangle, src *Image, p image.Point) {
+	Options{}.ToRGBA(dst, r, src, p)
+}
+
+// ToRGBA is like the ToRGBA function, but converts according to o.
+func (o Options) ToRGBA(dst *image.RGBA, r image.Rectangle, src *Image, p image.Point) {
 	v := p.Sub(r.Min)
 	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
 	p = r.Min.Add(v)
 	if r.Empty() {
 		return
 	}
+	c := o.coeffs()
+	i0, icb, i1, icr := src.Order.offsets()
 	for y := 0; y < r.Dy(); y++ {
 		s := src.Pix[src.PixPairOffset(p.X, p.Y+y):]
 		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
 		n := r.Dx()
 		if p.X&1 != 0 {
-			cb := int32(s[1]) - 128
-			y2 := int32(s[2]) * 0x010101
-			cr := int32(s[3]) - 128
-			d[0] = clamp(y2 + 91881*cr)
-			d[1] = clamp(y2 - 22554*cb - 46802*cr)
-			d[2] = clamp(y2 + 116130*cb)
+			cb := int32(s[icb]) - 128
+			y2 := (int32(s[i1])-c.yoff)*c.y + c.bias
+			cr := int32(s[icr]) - 128
+			d[0] = clamp(y2 + c.rv*cr)
+			d[1] = clamp(y2 - c.gu*cb - c.gv*cr)
+			d[2] = clamp(y2 + c.bu*cb)
 			d[3] = 255
 			s = s[4:]
 			d = d[4:]
 			n--
 		}
 		for x := 0; x < n; x += 2 {
-			y1 := int32(s[2*x+0]) * 0x010101
-			cb := int32(s[2*x+1]) - 128
-			y2 := int32(s[2*x+2]) * 0x010101
-			cr := int32(s[2*x+3]) - 128
-			d[4*x+0] = clamp(y1 + 91881*cr)
-			d[4*x+1] = clamp(y1 - 22554*cb - 46802*cr)
-			d[4*x+2] = clamp(y1 + 116130*cb)
+			y1 := (int32(s[2*x+i0])-c.yoff)*c.y + c.bias
+			cb := int32(s[2*x+icb]) - 128
+			y2 := (int32(s[2*x+i1])-c.yoff)*c.y + c.bias
+			cr := int32(s[2*x+icr]) - 128
+			d[4*x+0] = clamp(y1 + c.rv*cr)
+			d[4*x+1] = clamp(y1 - c.gu*cb - c.gv*cr)
+			d[4*x+2] = clamp(y1 + c.bu*cb)
 			d[4*x+3] = 255
 			if x < n-1 {
-				d[4*x+4] = clamp(y2 + 91881*cr)
-				d[4*x+5] = clamp(y2 - 22554*cb - 46802*cr)
-				d[4*x+6] = clamp(y2 + 116130*cb)
+				d[4*x+4] = clamp(y2 + c.rv*cr)
+				d[4*x+5] = clamp(y2 - c.gu*cb - c.gv*cr)
+				d[4*x+6] = clamp(y2 + c.bu*cb)
 				d[4*x+7] = 255
 			}
 		}
@@ -77,41 +148,50 @@ func clamp(x int32) uint8 {
 //
 // It's several times faster than the image/draw package.
 func ToGray(dst *image.Gray, r image.Rectangle, src *Image, p image.Point) {
+	Options{}.ToGray(dst, r, src, p)
+}
+
+// ToGray is like the ToGray function, but converts according to o. The grey
+// level is computed from RGB the same way for all matrices, as in the
+// image/color package.
+func (o Options) ToGray(dst *image.Gray, r image.Rectangle, src *Image, p image.Point) {
 	v := p.Sub(r.Min)
 	r = r.Intersect(dst.Rect).Intersect(src.Rect.Sub(v))
 	p = r.Min.Add(v)
 	if r.Empty() {
 		return
 	}
+	c := o.coeffs()
+	i0, icb, i1, icr := src.Order.offsets()
 	for y := 0; y < r.Dy(); y++ {
 		s := src.Pix[src.PixPairOffset(p.X, p.Y+y):]
 		d := dst.Pix[dst.PixOffset(r.Min.X, r.Min.Y+y):]
 		n := r.Dx()
 		if p.X&1 != 0 {
-			cb := int32(s[1]) - 128
-			y2 := int32(s[2]) * 0x010101
-			cr := int32(s[3]) - 128
-			r := clamp2(y2 + 91881*cr)
-			g := clamp2(y2 - 22554*cb - 46802*cr)
-			b := clamp2(y2 + 116130*cb)
+			cb := int32(s[icb]) - 128
+			y2 := (int32(s[i1])-c.yoff)*c.y + c.bias
+			cr := int32(s[icr]) - 128
+			r := clamp2(y2 + c.rv*cr)
+			g := clamp2(y2 - c.gu*cb - c.gv*cr)
+			b := clamp2(y2 + c.bu*cb)
 			d[0] = uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
 			s = s[4:]
 			d = d[1:]
 			n--
 		}
 		for x := 0; x < n; x += 2 {
-			y1 := int32(s[2*x+0]) * 0x010101
-			cb := int32(s[2*x+1]) - 128
-			y2 := int32(s[2*x+2]) * 0x010101
-			cr := int32(s[2*x+3]) - 128
-			r := clamp2(y1 + 91881*cr)
-			g := clamp2(y1 - 22554*cb - 46802*cr)
-			b := clamp2(y1 + 116130*cb)
+			y1 := (int32(s[2*x+i0])-c.yoff)*c.y + c.bias
+			cb := int32(s[2*x+icb]) - 128
+			y2 := (int32(s[2*x+i1])-c.yoff)*c.y + c.bias
+			cr := int32(s[2*x+icr]) - 128
+			r := clamp2(y1 + c.rv*cr)
+			g := clamp2(y1 - c.gu*cb - c.gv*cr)
+			b := clamp2(y1 + c.bu*cb)
 			d[x] = uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
 			if x < n-1 {
-				r := clamp2(y2 + 91881*cr)
-				g := clamp2(y2 - 22554*cb - 46802*cr)
-				b := clamp2(y2 + 116130*cb)
+				r := clamp2(y2 + c.rv*cr)
+				g := clamp2(y2 - c.gu*cb - c.gv*cr)
+				b := clamp2(y2 + c.bu*cb)
 				d[x+1] = uint8((19595*r + 38470*g + 7471*b + 1<<15) >> 24)
 			}
 		}
@@ -131,6 +211,13 @@ func clamp2(x int32) int32 {
 //
 // Panics if the subsample ratio of dst is not 4:4:4.
 func ToYCbCr(dst *image.YCbCr, r image.Rectangle, src *Image, p image.Point) {
+	Options{}.ToYCbCr(dst, r, src, p)
+}
+
+// ToYCbCr is like the ToYCbCr function, but converts according to o. As dst
+// is interpreted as BT.601 with full range, the samples are copied unchanged
+// only for the zero Options, and converted through RGB otherwise.
+func (o Options) ToYCbCr(dst *image.YCbCr, r image.Rectangle, src *Image, p image.Point) {
 	if dst.SubsampleRatio != image.YCbCrSubsampleRatio444 {
 		panic("subsample ratio must be 4:4:4")
 	}
@@ -140,6 +227,11 @@ func ToYCbCr(dst *image.YCbCr, r image.Rectangle, src *Image, p image.Point) {
 	if r.Empty() {
 		return
 	}
+	if o != (Options{}) {
+		o.toYCbCr(dst, r, src, p)
+		return
+	}
+	i0, icb, i1, icr := src.Order.offsets()
 	for y := 0; y < r.Dy(); y++ {
 		s := src.Pix[src.PixPairOffset(p.X, p.Y+y):]
 		dy := dst.Y[dst.YOffset(r.Min.X, r.Min.Y+y):]
@@ -147,7 +239,7 @@ func ToYCbCr(dst *image.YCbCr, r image.Rectangle, src *Image, p image.Point) {
 		dcr := dst.Cr[dst.COffset(r.Min.X, r.Min.Y+y):]
 		n := r.Dx()
 		if p.X&1 != 0 {
-			dy[0], dcb[0], dcr[0] = s[2], s[1], s[3]
+			dy[0], dcb[0], dcr[0] = s[i1], s[icb], s[icr]
 			s = s[4:]
 			dy = dy[1:]
 			dcb = dcb[1:]
@@ -155,10 +247,36 @@ func ToYCbCr(dst *image.YCbCr, r image.Rectangle, src *Image, p image.Point) {
 			n--
 		}
 		for x := 0; x < n; x += 2 {
-			dy[x], dcb[x], dcr[x] = s[2*x], s[2*x+1], s[2*x+3]
+			dy[x], dcb[x], dcr[x] = s[2*x+i0], s[2*x+icb], s[2*x+icr]
 			if x < n-1 {
-				dy[x+1], dcb[x+1], dcr[x+1] = s[2*x+2], s[2*x+1], s[2*x+3]
+				dy[x+1], dcb[x+1], dcr[x+1] = s[2*x+i1], s[2*x+icb], s[2*x+icr]
+			}
+		}
+	}
+}
+
+// toYCbCr converts the already clipped rectangle r through RGB.
+func (o Options) toYCbCr(dst *image.YCbCr, r image.Rectangle, src *Image, p image.Point) {
+	c := o.coeffs()
+	i0, icb, i1, icr := src.Order.offsets()
+	for y := 0; y < r.Dy(); y++ {
+		for x := 0; x < r.Dx(); x++ {
+			s := src.Pix[src.PixPairOffset(p.X+x, p.Y+y):]
+			yy := int32(s[i0])
+			if (p.X+x)&1 != 0 {
+				yy = int32(s[i1])
 			}
+			yy = (yy-c.yoff)*c.y + c.bias
+			cb := int32(s[icb]) - 128
+			cr := int32(s[icr]) - 128
+			Y, Cb, Cr := color.RGBToYCbCr(
+				clamp(yy+c.rv*cr),
+				clamp(yy-c.gu*cb-c.gv*cr),
+				clamp(yy+c.bu*cb),
+			)
+			i := dst.YOffset(r.Min.X+x, r.Min.Y+y)
+			j := dst.COffset(r.Min.X+x, r.Min.Y+y)
+			dst.Y[i], dst.Cb[j], dst.Cr[j] = Y, Cb, Cr
 		}
 	}
 }
@@ -0,0 +1,39 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package yuyv
+
+import (
+	"image"
+
+	"github.com/korandiz/v4l/decode"
+)
+
+func init() {
+	decode.RegisterRGBA(wrapLayout, toRGBA, FourCC, FourCCUYVY, FourCCYVYU, FourCCVYUY)
+}
+
+func wrapLayout(data []byte, l decode.Layout) (image.Image, error) {
+	order, _ := OrderOf(uint32(l.FourCC))
+	return Wrap(data, l.Width, l.Height, l.Stride, order)
+}
+
+func toRGBA(dst *image.RGBA, src image.Image) {
+	ToRGBA(dst, dst.Rect, src.(*Image), image.Point{})
+}
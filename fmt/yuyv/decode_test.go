@@ -0,0 +1,58 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package yuyv
+
+import (
+	"bytes"
+	"image"
+	"testing"
+
+	"github.com/korandiz/v4l"
+	"github.com/korandiz/v4l/decode"
+)
+
+func TestDecode(t *testing.T) {
+	data := make([]byte, 2*10+8)
+	for i := range data {
+		data[i] = uint8(37 * i)
+	}
+	for _, fourcc := range []v4l.FourCC{FourCC, FourCCUYVY, FourCCYVYU, FourCCVYUY} {
+		order, _ := OrderOf(uint32(fourcc))
+		src, err := Wrap(data, 4, 3, 10, order)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want := image.NewRGBA(src.Rect)
+		ToRGBA(want, want.Rect, src, image.Point{})
+		got, err := decode.DecodeLayout(nil, data, decode.Layout{FourCC: fourcc, Width: 4, Height: 3, Stride: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if g, ok := got.(*image.RGBA); !ok || !bytes.Equal(g.Pix, want.Pix) {
+			t.Errorf("%d: got %v, want %v\n", order, got, want)
+		}
+	}
+	if _, err := decode.DecodeLayout(nil, data, decode.Layout{FourCC: FourCC, Width: 4, Height: 4, Stride: 10}); err != ErrShortBuffer {
+		t.Errorf("got %v, want %v\n", err, ErrShortBuffer)
+	}
+	if _, err := Wrap(data, 6, 1, 10, YUYV); err != ErrDimensions {
+		t.Errorf("got %v, want %v\n", err, ErrDimensions)
+	}
+}
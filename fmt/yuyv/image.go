@@ -14,7 +14,15 @@
 // You should have received a copy of the GNU General Public License
 // along with this program.  If not, see <http://www.gnu.org/licenses/>.
 
-// Package yuyv provides support for the YUYV format.
+// Package yuyv provides support for the packed 4:2:2 formats: YUYV, UYVY, YVYU,
+// and VYUY, which differ only in the order of the samples of a pixel pair.
+//
+// Images can be converted to RGB with the BT.601, BT.709, or BT.2020 matrix,
+// from either full or limited range samples, as selected by Options. The
+// zero Options convert like the image/color package (BT.601, full range).
+//
+// Importing the package registers decoders with package decode, which convert
+// frames to *image.RGBA with the zero Options.
 package yuyv
 
 import (
@@ -25,11 +33,58 @@ import (
 // FourCC of the YUYV format.
 const FourCC = 'Y' | 'U'<<8 | 'Y'<<16 | 'V'<<24
 
-// An Image is an in-memory YUYV image. It implements the image.Image interface.
+// FourCCs of the other packed 4:2:2 formats.
+const (
+	FourCCUYVY = 'U' | 'Y'<<8 | 'V'<<16 | 'Y'<<24
+	FourCCYVYU = 'Y' | 'V'<<8 | 'Y'<<16 | 'U'<<24
+	FourCCVYUY = 'V' | 'Y'<<8 | 'U'<<16 | 'Y'<<24
+)
+
+// An Order is the order of the samples of a pixel pair.
+type Order int
+
+const (
+	YUYV Order = iota // Y0, Cb, Y1, Cr
+	UYVY              // Cb, Y0, Cr, Y1
+	YVYU              // Y0, Cr, Y1, Cb
+	VYUY              // Cr, Y0, Cb, Y1
+)
+
+// OrderOf returns the sample order of a packed 4:2:2 format, or false if
+// fourcc is not one of them.
+func OrderOf(fourcc uint32) (Order, bool) {
+	switch fourcc {
+	case FourCC:
+		return YUYV, true
+	case FourCCUYVY:
+		return UYVY, true
+	case FourCCYVYU:
+		return YVYU, true
+	case FourCCVYUY:
+		return VYUY, true
+	}
+	return 0, false
+}
+
+// offsets returns the positions of Y0, Cb, Y1, and Cr within a pixel pair.
+func (o Order) offsets() (y0, cb, y1, cr int) {
+	switch o {
+	case UYVY:
+		return 1, 0, 3, 2
+	case YVYU:
+		return 0, 3, 2, 1
+	case VYUY:
+		return 1, 2, 3, 0
+	}
+	return 0, 1, 2, 3
+}
+
+// An Image is an in-memory YUYV image, or one in another packed 4:2:2 format.
+// It implements the image.Image interface.
 type Image struct {
 	// Pix holds the pixel data. Every four bytes corresponds to a pair of
 	// horizontally adjacent pixels. The two pixels have separate luma values
-	// and shared chroma. The sample order is Y0, Cb, Y1, Cr.
+	// and shared chroma. The sample order is given by Order.
 	Pix []uint8
 
 	// Stride is the distance in bytes between vertically adjacent pixels.
@@ -38,15 +93,51 @@ type Image struct {
 
 	// Rect is the bounds of the image.
 	Rect image.Rectangle
+
+	// Order is the sample order. The zero value is YUYV.
+	Order Order
 }
 
-// New returns a new YUYV image with the given bounds.
+// New returns a new YUYV image with the given bounds. It can be turned into an
+// image of another packed 4:2:2 format by setting its Order field.
 func New(rect image.Rectangle) *Image {
 	l, r := rect.Min.X&^1, (rect.Max.X+1)&^1
 	w, h := r-l, rect.Dy()
-	return &Image{make([]uint8, 2*w*h), 2 * w, rect}
+	return &Image{make([]uint8, 2*w*h), 2 * w, rect, YUYV}
+}
+
+// Wrap returns an image with the given dimensions and sample order that uses
+// data as its pixel data, with lines stride bytes apart (e.g.
+// BufferInfo.ImageStride). It fails with ErrShortBuffer if data is too small.
+func Wrap(data []byte, width, height, stride int, order Order) (*Image, error) {
+	lineLen := 2 * ((width + 1) &^ 1)
+	if width < 0 || height < 0 || stride < lineLen || order < YUYV || order > VYUY {
+		return nil, ErrDimensions
+	}
+	if height > 0 && len(data) < stride*(height-1)+lineLen {
+		return nil, ErrShortBuffer
+	}
+	return &Image{data, stride, image.Rect(0, 0, width, height), order}, nil
+}
+
+// An Error is an error message returned by Wrap.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
 }
 
+const (
+	// ErrDimensions means that the width, height, stride and order passed to
+	// Wrap don't describe a valid image.
+	ErrDimensions = Error("invalid image dimensions")
+
+	// ErrShortBuffer means that the buffer passed to Wrap is too small for
+	// the image.
+	ErrShortBuffer = Error("buffer too small for image")
+)
+
 // ColorModel returns color.YCbCrModel.
 func (img *Image) ColorModel() color.Model {
 	return color.YCbCrModel
@@ -77,19 +168,25 @@ func (img *Image) YCbCrAt(x, y int) color.YCbCr {
 // YOffset returns the index at which the Y component of the pixel at (x, y) is
 // located in Pix.
 func (img *Image) YOffset(x, y int) int {
-	return img.PixPairOffset(x, y) + 2*(x&1)
+	y0, _, y1, _ := img.Order.offsets()
+	if x&1 != 0 {
+		return img.PixPairOffset(x, y) + y1
+	}
+	return img.PixPairOffset(x, y) + y0
 }
 
 // CbOffset returns the index at which the Cb component of the pixel at (x, y)
 // is located in Pix.
 func (img *Image) CbOffset(x, y int) int {
-	return img.PixPairOffset(x, y) + 1
+	_, cb, _, _ := img.Order.offsets()
+	return img.PixPairOffset(x, y) + cb
 }
 
 // CrOffset returns the index at which the Cr component of the pixel at (x, y)
 // is located in Pix.
 func (img *Image) CrOffset(x, y int) int {
-	return img.PixPairOffset(x, y) + 3
+	_, _, _, cr := img.Order.offsets()
+	return img.PixPairOffset(x, y) + cr
 }
 
 // PixPairOffset returns the index of the first element in Pix that corresponds
@@ -106,7 +203,7 @@ func (img *Image) SubImage(rect image.Rectangle) image.Image {
 		return &Image{}
 	}
 	i := img.PixPairOffset(rect.Min.X, rect.Min.Y)
-	return &Image{img.Pix[i:], img.Stride, rect}
+	return &Image{img.Pix[i:], img.Stride, rect, img.Order}
 }
 
 // Opaque returns true.
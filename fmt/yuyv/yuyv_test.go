@@ -17,9 +17,11 @@
 package yuyv
 
 import (
+	"bytes"
 	"image"
 	"image/color"
 	"image/draw"
+	"math"
 	"testing"
 )
 
@@ -275,3 +277,205 @@ func TestGrayConversion(t *testing.T) {
 		}
 	}
 }
+
+// reorder returns a copy of src with the samples in the given order.
+func reorder(o Order) *Image {
+	img := &Image{make([]byte, len(src.Pix)), src.Stride, src.Rect, o}
+	copy(img.Pix, src.Pix)
+	for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+		for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+			img.Pix[img.YOffset(x, y)] = src.Pix[src.YOffset(x, y)]
+			img.Pix[img.CbOffset(x, y)] = src.Pix[src.CbOffset(x, y)]
+			img.Pix[img.CrOffset(x, y)] = src.Pix[src.CrOffset(x, y)]
+		}
+	}
+	return img
+}
+
+func TestOrderOf(t *testing.T) {
+	tests := []struct {
+		fourcc uint32
+		order  Order
+		ok     bool
+	}{
+		{FourCC, YUYV, true},
+		{FourCCUYVY, UYVY, true},
+		{FourCCYVYU, YVYU, true},
+		{FourCCVYUY, VYUY, true},
+		{'M' | 'J'<<8 | 'P'<<16 | 'G'<<24, 0, false},
+	}
+	for _, test := range tests {
+		if o, ok := OrderOf(test.fourcc); o != test.order || ok != test.ok {
+			t.Errorf("OrderOf(%#x): got: %v, %v, expected: %v, %v\n",
+				test.fourcc, o, ok, test.order, test.ok)
+		}
+	}
+
+	// The byte order of UYVY, as found in a raw buffer.
+	img := New(image.Rect(0, 0, 2, 1))
+	img.Order = UYVY
+	copy(img.Pix, []byte{10, 20, 30, 40})
+	if c := img.YCbCrAt(1, 0); c != (color.YCbCr{40, 10, 30}) {
+		t.Errorf("UYVY: got: %v, expected: %v\n", c, color.YCbCr{40, 10, 30})
+	}
+}
+
+// TestOrders checks that the other sample orders behave just like YUYV, which
+// is tested against the image/draw package by the tests above.
+func TestOrders(t *testing.T) {
+	r0 := image.Rect(-2, -6, 14, 10)
+	for _, o := range []Order{UYVY, YVYU, VYUY} {
+		img := reorder(o)
+		for L := r0.Min.X; L < r0.Max.X; L += 4 {
+			for T := r0.Min.Y; T < r0.Max.Y; T += 4 {
+				for R := r0.Min.X + 1; R < r0.Max.X; R += 4 {
+					for B := r0.Min.Y + 1; B < r0.Max.Y; B += 4 {
+						for X := src.Rect.Min.X - 2; X < src.Rect.Max.X+2; X++ {
+							for Y := src.Rect.Min.Y - 2; Y < src.Rect.Max.Y+2; Y++ {
+								r := image.Rect(L, T, R, B)
+								p := image.Pt(X, Y)
+								if !checkOrder(img, r0, r, p) {
+									t.Errorf("order %d differs from YUYV (r=%v, p=%v)\n", o, r, p)
+									return
+								}
+							}
+						}
+					}
+				}
+			}
+		}
+		for y := src.Rect.Min.Y; y < src.Rect.Max.Y; y++ {
+			for x := src.Rect.Min.X; x < src.Rect.Max.X; x++ {
+				r := image.Rect(x, y, src.Rect.Max.X, src.Rect.Max.Y)
+				if c0, c1 := img.SubImage(r).At(x, y), expectedAt(x, y); c0 != c1 {
+					t.Errorf("got: %v, expected: %v (x=%d, y=%d, order=%d)\n", c0, c1, x, y, o)
+				}
+			}
+		}
+	}
+}
+
+// checkOrder tells if img converts the same way as src.
+func checkOrder(img *Image, r0, r image.Rectangle, p image.Point) bool {
+	rgba0, rgba1 := image.NewRGBA(r0), image.NewRGBA(r0)
+	ToRGBA(rgba0, r, src, p)
+	ToRGBA(rgba1, r, img, p)
+	gray0, gray1 := image.NewGray(r0), image.NewGray(r0)
+	ToGray(gray0, r, src, p)
+	ToGray(gray1, r, img, p)
+	ycc0 := image.NewYCbCr(r0, image.YCbCrSubsampleRatio444)
+	ycc1 := image.NewYCbCr(r0, image.YCbCrSubsampleRatio444)
+	ToYCbCr(ycc0, r, src, p)
+	ToYCbCr(ycc1, r, img, p)
+	return bytes.Equal(rgba0.Pix, rgba1.Pix) && bytes.Equal(gray0.Pix, gray1.Pix) &&
+		bytes.Equal(ycc0.Y, ycc1.Y) && bytes.Equal(ycc0.Cb, ycc1.Cb) &&
+		bytes.Equal(ycc0.Cr, ycc1.Cr)
+}
+
+// reference converts YCbCr to RGB in floating point, according to o.
+func reference(o Options, y, cb, cr uint8) (r, g, b float64) {
+	kr, kb := 0.299, 0.114
+	switch o.Matrix {
+	case BT709:
+		kr, kb = 0.2126, 0.0722
+	case BT2020:
+		kr, kb = 0.2627, 0.0593
+	}
+	kg := 1 - kr - kb
+	Y, U, V := float64(y), float64(cb)-128, float64(cr)-128
+	if o.Limited {
+		Y = (Y - 16) * 255 / 219
+		U *= 255.0 / 224
+		V *= 255.0 / 224
+	}
+	r = Y + 2*(1-kr)*V
+	g = Y - 2*(1-kb)*kb/kg*U - 2*(1-kr)*kr/kg*V
+	b = Y + 2*(1-kb)*U
+	return
+}
+
+func round8(x float64) uint8 {
+	return uint8(math.Max(0, math.Min(255, math.Round(x))))
+}
+
+func near(a, b uint8, tolerance int) bool {
+	d := int(a) - int(b)
+	return -tolerance <= d && d <= tolerance
+}
+
+func TestOptions(t *testing.T) {
+	var (
+		ycc  = New(image.Rect(0, 0, 2, 1))
+		rgb  = image.NewRGBA(ycc.Rect)
+		gray = image.NewGray(ycc.Rect)
+		out  = image.NewYCbCr(ycc.Rect, image.YCbCrSubsampleRatio444)
+	)
+	for _, m := range []Matrix{BT601, BT709, BT2020} {
+		for _, limited := range []bool{false, true} {
+			o := Options{m, limited}
+			for y := 0; y < 256; y += 5 {
+				for cb := 0; cb < 256; cb += 5 {
+					for cr := 0; cr < 256; cr += 5 {
+						ycc.Pix[0] = uint8(y)
+						ycc.Pix[1] = uint8(cb)
+						ycc.Pix[2] = uint8(y)
+						ycc.Pix[3] = uint8(cr)
+						o.ToRGBA(rgb, rgb.Rect, ycc, ycc.Rect.Min)
+						o.ToGray(gray, gray.Rect, ycc, ycc.Rect.Min)
+						o.ToYCbCr(out, out.Rect, ycc, ycc.Rect.Min)
+
+						fr, fg, fb := reference(o, uint8(y), uint8(cb), uint8(cr))
+						r, g, b := round8(fr), round8(fg), round8(fb)
+						wantGray := color.GrayModel.Convert(color.RGBA{r, g, b, 255}).(color.Gray).Y
+						wantY, wantCb, wantCr := color.RGBToYCbCr(r, g, b)
+						if o == (Options{}) {
+							wantY, wantCb, wantCr = uint8(y), uint8(cb), uint8(cr)
+						}
+						for x := 0; x < 2; x++ {
+							got := rgb.RGBAAt(x, 0)
+							if !near(got.R, r, 1) || !near(got.G, g, 1) || !near(got.B, b, 1) {
+								t.Errorf("%+v: ToRGBA: got %v, want %v (y=%d, cb=%d, cr=%d)\n",
+									o, got, color.RGBA{r, g, b, 255}, y, cb, cr)
+								return
+							}
+							if got := gray.GrayAt(x, 0).Y; !near(got, wantGray, 1) {
+								t.Errorf("%+v: ToGray: got %d, want %d (y=%d, cb=%d, cr=%d)\n",
+									o, got, wantGray, y, cb, cr)
+								return
+							}
+							c := out.YCbCrAt(x, 0)
+							if !near(c.Y, wantY, 1) || !near(c.Cb, wantCb, 2) || !near(c.Cr, wantCr, 2) {
+								t.Errorf("%+v: ToYCbCr: got %v, want %v (y=%d, cb=%d, cr=%d)\n",
+									o, c, color.YCbCr{wantY, wantCb, wantCr}, y, cb, cr)
+								return
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestOptionsRange(t *testing.T) {
+	want := image.NewRGBA(src.Rect)
+	ToRGBA(want, want.Rect, src, src.Rect.Min)
+	got := image.NewRGBA(src.Rect)
+	Options{BT601, false}.ToRGBA(got, got.Rect, src, src.Rect.Min)
+	if !bytes.Equal(got.Pix, want.Pix) {
+		t.Errorf("got %v, want %v\n", got.Pix, want.Pix)
+	}
+	// Full range white and black stay as they are with any matrix, and so do
+	// limited range 235 and 16.
+	img := New(image.Rect(0, 0, 4, 1))
+	copy(img.Pix, []byte{255, 128, 0, 128, 235, 128, 16, 128})
+	for _, m := range []Matrix{BT601, BT709, BT2020} {
+		rgba := image.NewRGBA(image.Rect(0, 0, 4, 1))
+		Options{m, false}.ToRGBA(rgba, image.Rect(0, 0, 2, 1), img, image.Point{})
+		Options{m, true}.ToRGBA(rgba, image.Rect(2, 0, 4, 1), img, image.Point{2, 0})
+		want := []byte{255, 255, 255, 255, 0, 0, 0, 255, 255, 255, 255, 255, 0, 0, 0, 255}
+		if !bytes.Equal(rgba.Pix, want) {
+			t.Errorf("%d: got %v, want %v\n", m, rgba.Pix, want)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// A FourCC is a four-character code identifying a pixel format, with the first
+// character at the lowest byte.
+type FourCC uint32
+
+// FourCCBigEndian is the flag bit that marks the big-endian variant of a
+// format whose samples are larger than a byte.
+const FourCCBigEndian FourCC = 1 << 31
+
+// MakeFourCC returns the FourCC code of the characters a, b, c, and d.
+func MakeFourCC(a, b, c, d byte) FourCC {
+	return FourCC(a) | FourCC(b)<<8 | FourCC(c)<<16 | FourCC(d)<<24
+}
+
+// BigEndian tells whether the big-endian flag is set in f.
+func (f FourCC) BigEndian() bool {
+	return f&FourCCBigEndian != 0
+}
+
+// String returns the characters of f with trailing spaces removed (e.g.
+// "YUYV", "Y10"), followed by "-BE" if the big-endian flag is set. Codes with
+// non-printable characters are formatted as hexadecimal numbers.
+func (f FourCC) String() string {
+	c := f &^ FourCCBigEndian
+	b := []byte{byte(c), byte(c >> 8), byte(c >> 16), byte(c >> 24)}
+	for _, x := range b {
+		if x < ' ' || x > '~' {
+			return "0x" + strconv.FormatUint(uint64(f), 16)
+		}
+	}
+	s := strings.TrimRight(string(b), " ")
+	if f.BigEndian() {
+		s += "-BE"
+	}
+	return s
+}
+
+// ParseFourCC is the inverse of FourCC.String. Codes shorter than four
+// characters are padded with spaces (e.g. "Y10" becomes "Y10 ").
+func ParseFourCC(s string) (FourCC, error) {
+	var f FourCC
+	if strings.HasSuffix(s, "-BE") {
+		s = strings.TrimSuffix(s, "-BE")
+		f = FourCCBigEndian
+	}
+	if strings.HasPrefix(s, "0x") {
+		n, err := strconv.ParseUint(s[2:], 16, 32)
+		if err != nil {
+			return 0, ErrInvalidFourCC
+		}
+		return FourCC(n) | f, nil
+	}
+	if len(s) == 0 || len(s) > 4 {
+		return 0, ErrInvalidFourCC
+	}
+	b := []byte("    ")
+	for i := 0; i < len(s); i++ {
+		if s[i] < ' ' || s[i] > '~' || s[0] == ' ' {
+			return 0, ErrInvalidFourCC
+		}
+		b[i] = s[i]
+	}
+	return f | MakeFourCC(b[0], b[1], b[2], b[3]), nil
+}
+
+// Info returns the description of f in the format registry.
+func (f FourCC) Info() (FormatInfo, bool) {
+	formatsMu.RLock()
+	defer formatsMu.RUnlock()
+	info, ok := formats[f]
+	return info, ok
+}
+
+// A FormatInfo describes a pixel format.
+type FormatInfo struct {
+	// FourCC is the code of the format.
+	FourCC FourCC
+
+	// Name is a human-readable description (e.g. "YUYV 4:2:2").
+	Name string
+
+	// BitsPerPixel is the average number of bits per pixel, including
+	// padding. It is 0 for compressed formats.
+	BitsPerPixel int
+
+	// Planes is the number of planes the samples are stored in (e.g. 1 for
+	// YUYV, 2 for NV12, and 3 for YU12).
+	Planes int
+
+	// HSub and VSub are the horizontal and vertical chroma subsampling
+	// factors (e.g. 2 and 2 for 4:2:0). They are 1 for formats without
+	// chroma and for compressed formats.
+	HSub, VSub int
+
+	// Compressed tells whether the format is compressed (e.g. MJPEG).
+	Compressed bool
+}
+
+var (
+	formatsMu sync.RWMutex
+	formats   = make(map[FourCC]FormatInfo)
+)
+
+// RegisterFormat adds a format to the registry, replacing any previous entry
+// with the same code.
+func RegisterFormat(info FormatInfo) {
+	formatsMu.Lock()
+	formats[info.FourCC] = info
+	formatsMu.Unlock()
+}
+
+// Formats returns all the formats in the registry, sorted by code.
+func Formats() []FormatInfo {
+	formatsMu.RLock()
+	list := make([]FormatInfo, 0, len(formats))
+	for _, info := range formats {
+		list = append(list, info)
+	}
+	formatsMu.RUnlock()
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].FourCC < list[j].FourCC
+	})
+	return list
+}
+
+func init() {
+	type entry struct {
+		code       string
+		name       string
+		bpp        int
+		planes     int
+		hsub, vsub int
+	}
+
+	raw := []entry{
+		// Packed YUV.
+		{"YUYV", "YUYV 4:2:2", 16, 1, 2, 1},
+		{"UYVY", "UYVY 4:2:2", 16, 1, 2, 1},
+		{"YVYU", "YVYU 4:2:2", 16, 1, 2, 1},
+		{"VYUY", "VYUY 4:2:2", 16, 1, 2, 1},
+
+		// Semi-planar and planar YUV.
+		{"NV12", "Y/UV 4:2:0", 12, 2, 2, 2},
+		{"NV21", "Y/VU 4:2:0", 12, 2, 2, 2},
+		{"NV16", "Y/UV 4:2:2", 16, 2, 2, 1},
+		{"NV61", "Y/VU 4:2:2", 16, 2, 2, 1},
+		{"NV24", "Y/UV 4:4:4", 24, 2, 1, 1},
+		{"NV42", "Y/VU 4:4:4", 24, 2, 1, 1},
+		{"YU12", "Planar YUV 4:2:0", 12, 3, 2, 2},
+		{"YV12", "Planar YVU 4:2:0", 12, 3, 2, 2},
+		{"422P", "Planar YUV 4:2:2", 16, 3, 2, 1},
+
+		// RGB.
+		{"RGB3", "24-bit RGB 8-8-8", 24, 1, 1, 1},
+		{"BGR3", "24-bit BGR 8-8-8", 24, 1, 1, 1},
+		{"RGBP", "16-bit RGB 5-6-5", 16, 1, 1, 1},
+		{"RGBR", "16-bit RGB 5-6-5 BE", 16, 1, 1, 1},
+		{"RGBO", "16-bit A/XRGB 1-5-5-5", 16, 1, 1, 1},
+		{"RGBQ", "16-bit A/XRGB 1-5-5-5 BE", 16, 1, 1, 1},
+		{"BX24", "32-bit XRGB 8-8-8-8", 32, 1, 1, 1},
+		{"BA24", "32-bit ARGB 8-8-8-8", 32, 1, 1, 1},
+		{"XR24", "32-bit BGRX 8-8-8-8", 32, 1, 1, 1},
+		{"AR24", "32-bit BGRA 8-8-8-8", 32, 1, 1, 1},
+
+		// Greyscale and depth.
+		{"GREY", "8-bit Greyscale", 8, 1, 1, 1},
+		{"Y10 ", "10-bit Greyscale", 16, 1, 1, 1},
+		{"Y12 ", "12-bit Greyscale", 16, 1, 1, 1},
+		{"Y16 ", "16-bit Greyscale", 16, 1, 1, 1},
+		{"Y16 -BE", "16-bit Greyscale BE", 16, 1, 1, 1},
+		{"Y10P", "10-bit Greyscale (MIPI Packed)", 10, 1, 1, 1},
+		{"Y12P", "12-bit Greyscale (MIPI Packed)", 12, 1, 1, 1},
+		{"Z16 ", "16-bit Depth", 16, 1, 1, 1},
+	}
+
+	// Bayer.
+	for _, p := range []struct{ pattern, c8, c10, c12, c16, c10p, c12p string }{
+		{"BGGR", "BA81", "BG10", "BG12", "BYR2", "pBAA", "pBCC"},
+		{"GBRG", "GBRG", "GB10", "GB12", "GB16", "pGAA", "pGCC"},
+		{"GRBG", "GRBG", "BA10", "BA12", "GR16", "pgAA", "pgCC"},
+		{"RGGB", "RGGB", "RG10", "RG12", "RG16", "pRAA", "pRCC"},
+	} {
+		raw = append(raw,
+			entry{p.c8, "8-bit Bayer " + p.pattern, 8, 1, 1, 1},
+			entry{p.c10, "10-bit Bayer " + p.pattern, 16, 1, 1, 1},
+			entry{p.c12, "12-bit Bayer " + p.pattern, 16, 1, 1, 1},
+			entry{p.c16, "16-bit Bayer " + p.pattern, 16, 1, 1, 1},
+			entry{p.c10p, "10-bit Bayer " + p.pattern + " (MIPI Packed)", 10, 1, 1, 1},
+			entry{p.c12p, "12-bit Bayer " + p.pattern + " (MIPI Packed)", 12, 1, 1, 1},
+		)
+	}
+
+	for _, e := range raw {
+		f, err := ParseFourCC(e.code)
+		if err != nil {
+			panic(err)
+		}
+		RegisterFormat(FormatInfo{f, e.name, e.bpp, e.planes, e.hsub, e.vsub, false})
+	}
+
+	// Compressed.
+	for _, e := range []struct{ code, name string }{
+		{"MJPG", "Motion-JPEG"},
+		{"JPEG", "JFIF JPEG"},
+		{"H264", "H.264"},
+		{"HEVC", "HEVC"},
+		{"VP80", "VP8"},
+		{"VP90", "VP9"},
+		{"MPEG", "MPEG-1/2/4 Multiplexed"},
+	} {
+		f, err := ParseFourCC(e.code)
+		if err != nil {
+			panic(err)
+		}
+		RegisterFormat(FormatInfo{f, e.name, 0, 1, 1, 1, true})
+	}
+}
@@ -0,0 +1,124 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import "testing"
+
+func TestFourCCString(t *testing.T) {
+	tests := []struct {
+		f    FourCC
+		want string
+	}{
+		{MakeFourCC('Y', 'U', 'Y', 'V'), "YUYV"},
+		{MakeFourCC('Y', '1', '0', ' '), "Y10"},
+		{MakeFourCC('Y', '1', '6', ' ') | FourCCBigEndian, "Y16-BE"},
+		{MakeFourCC('p', 'B', 'A', 'A'), "pBAA"},
+		{0x01020304, "0x1020304"},
+	}
+	for _, test := range tests {
+		if got := test.f.String(); got != test.want {
+			t.Errorf("%#x: got %q, want %q\n", uint32(test.f), got, test.want)
+		}
+		f, err := ParseFourCC(test.want)
+		if err != nil || f != test.f {
+			t.Errorf("ParseFourCC(%q) = %#x, %v\n", test.want, uint32(f), err)
+		}
+	}
+}
+
+func TestParseFourCC(t *testing.T) {
+	if f, err := ParseFourCC("MJPG"); err != nil || f != 0x47504a4d {
+		t.Errorf("MJPG: got %#x, %v\n", uint32(f), err)
+	}
+	for _, s := range []string{"", "TOOLONG", " ABC", "-BE", "Y\x0010", "0xzz"} {
+		if _, err := ParseFourCC(s); err != ErrInvalidFourCC {
+			t.Errorf("%q: got %v, want %v\n", s, err, ErrInvalidFourCC)
+		}
+	}
+}
+
+func TestFormatInfo(t *testing.T) {
+	tests := []struct {
+		code       string
+		bpp        int
+		planes     int
+		hsub, vsub int
+		compressed bool
+	}{
+		{"YUYV", 16, 1, 2, 1, false},
+		{"NV12", 12, 2, 2, 2, false},
+		{"YU12", 12, 3, 2, 2, false},
+		{"RGB3", 24, 1, 1, 1, false},
+		{"Y10P", 10, 1, 1, 1, false},
+		{"Y16-BE", 16, 1, 1, 1, false},
+		{"pRAA", 10, 1, 1, 1, false},
+		{"MJPG", 0, 1, 1, 1, true},
+	}
+	for _, test := range tests {
+		f, err := ParseFourCC(test.code)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, ok := f.Info()
+		if !ok {
+			t.Errorf("%s: not registered\n", test.code)
+			continue
+		}
+		if info.FourCC != f || info.BitsPerPixel != test.bpp ||
+			info.Planes != test.planes || info.HSub != test.hsub ||
+			info.VSub != test.vsub || info.Compressed != test.compressed {
+			t.Errorf("%s: got %+v\n", test.code, info)
+		}
+	}
+	if _, ok := fourcc("ABCD").Info(); ok {
+		t.Errorf("ABCD: registered\n")
+	}
+
+	RegisterFormat(FormatInfo{FourCC: fourcc("ABCD"), Name: "Test"})
+	t.Cleanup(func() {
+		formatsMu.Lock()
+		delete(formats, fourcc("ABCD"))
+		formatsMu.Unlock()
+	})
+	if info, ok := fourcc("ABCD").Info(); !ok || info.Name != "Test" {
+		t.Errorf("ABCD: got %+v, %v\n", info, ok)
+	}
+	list := Formats()
+	for i := 1; i < len(list); i++ {
+		if list[i-1].FourCC >= list[i].FourCC {
+			t.Errorf("Formats not sorted: %v, %v\n", list[i-1].FourCC, list[i].FourCC)
+		}
+	}
+}
+
+func TestFakeFourCC(t *testing.T) {
+	dev, err := OpenFake(FakeDevice{Configs: fakeConfigs})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	cfgs, err := dev.ListConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := cfgs[len(cfgs)-1].Format.String(); s != "MJPG" {
+		t.Errorf("got %s, want MJPG\n", s)
+	}
+}
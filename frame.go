@@ -0,0 +1,100 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A Frame is a captured frame. Unlike a Buffer, its contents stay available
+// after the next frame has been captured.
+//
+// Frames obtained from Buffer.Frame or a Stream come from a pool, and they are
+// reference counted. Whoever receives such a frame holds one reference to it,
+// and must call Release when done with it. Retain adds a reference, e.g. before
+// handing the frame over to another goroutine. When the last reference is
+// released, the memory is reused for a later frame, so Data must not be
+// accessed afterwards. Frames that are not released are garbage collected as
+// usual; they are just not recycled.
+type Frame struct {
+	// Data is the raw image data. It may be shared by several consumers, so it
+	// must not be modified.
+	Data []byte
+
+	// SeqNum is the sequence number of the frame as reported by the kernel.
+	SeqNum uint32
+
+	refs   int32
+	pooled bool
+}
+
+// framePool holds the data slices of released frames.
+var framePool sync.Pool
+
+// newFrame returns a pooled frame of n bytes with one reference.
+func newFrame(n int) *Frame {
+	var data []byte
+	if p, ok := framePool.Get().(*[]byte); ok && cap(*p) >= n {
+		data = (*p)[:n]
+	} else {
+		data = make([]byte, n)
+	}
+	return &Frame{Data: data, refs: 1, pooled: true}
+}
+
+// Retain adds a reference to the frame.
+func (f *Frame) Retain() {
+	if f.pooled {
+		atomic.AddInt32(&f.refs, 1)
+	}
+}
+
+// Release drops a reference to the frame. When the last one is dropped, the
+// memory of the frame goes back to the pool.
+func (f *Frame) Release() {
+	if !f.pooled {
+		return
+	}
+	switch n := atomic.AddInt32(&f.refs, -1); {
+	case n == 0:
+		data := f.Data[:0]
+		f.Data = nil
+		framePool.Put(&data)
+	case n < 0:
+		panic("v4l: Frame released too many times")
+	}
+}
+
+// Frame copies the contents of the buffer into a pooled Frame, which stays
+// valid after the next call to Capture. The caller holds the only reference to
+// the frame.
+func (b *Buffer) Frame() (*Frame, error) {
+	b.d.mu.Lock()
+	defer b.d.mu.Unlock()
+	src := b.source()
+	if src == nil {
+		return nil, ErrBufferGone
+	}
+	f := newFrame(len(src))
+	copy(f.Data, src)
+	f.SeqNum = b.seq
+	return f, nil
+}
@@ -0,0 +1,55 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import "testing"
+
+func TestFrameRefs(t *testing.T) {
+	f := newFrame(16)
+	if len(f.Data) != 16 {
+		t.Fatalf("got %d bytes, expected 16\n", len(f.Data))
+	}
+	f.Retain()
+	f.Release()
+	if f.Data == nil {
+		t.Errorf("frame recycled while still referenced\n")
+	}
+	f.Release()
+	if f.Data != nil {
+		t.Errorf("frame not recycled after the last Release\n")
+	}
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Errorf("extra Release didn't panic\n")
+			}
+		}()
+		f.Release()
+	}()
+
+	// Frames not from the pool are left alone.
+	g := &Frame{Data: []byte{1}}
+	g.Release()
+	g.Release()
+	if g.Data == nil {
+		t.Errorf("unpooled frame recycled\n")
+	}
+}
@@ -0,0 +1,235 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import "strings"
+
+// An arch describes the C ABI and the IOCTL encoding of an architecture.
+type arch struct {
+	name    string // GOARCH
+	long    int    // size and alignment of long and pointers
+	align64 int    // alignment of 64-bit integers
+	ioc     ioc
+}
+
+// An ioc describes how _IOC encodes IOCTL numbers. The number and the type
+// always take 8 bits each, followed by the size and the direction.
+type ioc struct {
+	sizeBits uint
+	none     uint32
+	read     uint32
+	write    uint32
+}
+
+var (
+	// iocGeneric is the encoding in asm-generic/ioctl.h.
+	iocGeneric = ioc{sizeBits: 14, none: 0, read: 2, write: 1}
+
+	// iocOld is the encoding used by MIPS and PowerPC.
+	iocOld = ioc{sizeBits: 13, none: 1, read: 2, write: 4}
+)
+
+// arches lists the supported architectures.
+var arches = []arch{
+	{"386", 4, 4, iocGeneric},
+	{"amd64", 8, 8, iocGeneric},
+	{"arm", 4, 8, iocGeneric},
+	{"arm64", 8, 8, iocGeneric},
+	{"loong64", 8, 8, iocGeneric},
+	{"mips", 4, 8, iocOld},
+	{"mipsle", 4, 8, iocOld},
+	{"mips64le", 8, 8, iocOld},
+	{"ppc64le", 8, 8, iocOld},
+	{"riscv64", 8, 8, iocGeneric},
+	{"s390x", 8, 8, iocGeneric},
+}
+
+// IOCTL directions.
+const (
+	dirNone = iota
+	dirR
+	dirW
+	dirRW
+)
+
+// number returns the IOCTL number _IOC(dir, typ, nr, size).
+func (a *arch) number(dir int, typ byte, nr byte, t ctype) uint32 {
+	var d uint32
+	switch dir {
+	case dirNone:
+		d = a.ioc.none
+	case dirR:
+		d = a.ioc.read
+	case dirW:
+		d = a.ioc.write
+	case dirRW:
+		d = a.ioc.read | a.ioc.write
+	}
+	size := uint32(0)
+	if t != nil {
+		size = uint32(t.size(a))
+	}
+	return uint32(nr) | uint32(typ)<<8 | size<<16 | d<<(16+a.ioc.sizeBits)
+}
+
+// A ctype is a C type.
+type ctype interface {
+	size(a *arch) int
+	align(a *arch) int
+}
+
+// scalar is an integer or pointer type.
+type scalar int
+
+const (
+	u8 scalar = iota
+	u16
+	u32
+	u64
+	long // also unsigned long and pointers
+)
+
+func (s scalar) size(a *arch) int {
+	switch s {
+	case u8:
+		return 1
+	case u16:
+		return 2
+	case u32:
+		return 4
+	case u64:
+		return 8
+	default:
+		return a.long
+	}
+}
+
+func (s scalar) align(a *arch) int {
+	if s == u64 {
+		return a.align64
+	}
+	return s.size(a)
+}
+
+// array is a fixed size array.
+type array struct {
+	elem ctype
+	n    int
+}
+
+func (t array) size(a *arch) int {
+	return t.elem.size(a) * t.n
+}
+
+func (t array) align(a *arch) int {
+	return t.elem.align(a)
+}
+
+// A field is a member of a struct or a union.
+type field struct {
+	name string
+	typ  ctype
+}
+
+// record is a struct or a union.
+type record struct {
+	union  bool
+	packed bool
+	fields []field
+}
+
+func structOf(fields ...field) *record {
+	return &record{fields: fields}
+}
+
+func packedStructOf(fields ...field) *record {
+	return &record{packed: true, fields: fields}
+}
+
+func unionOf(fields ...field) *record {
+	return &record{union: true, fields: fields}
+}
+
+// layout returns the offsets of the fields, and the size of the record.
+func (r *record) layout(a *arch) (offsets []int, size int) {
+	offsets = make([]int, len(r.fields))
+	for i, f := range r.fields {
+		if r.union {
+			if s := f.typ.size(a); s > size {
+				size = s
+			}
+			continue
+		}
+		if !r.packed {
+			size = roundUp(size, f.typ.align(a))
+		}
+		offsets[i] = size
+		size += f.typ.size(a)
+	}
+	return offsets, roundUp(size, r.align(a))
+}
+
+func (r *record) size(a *arch) int {
+	_, size := r.layout(a)
+	return size
+}
+
+func (r *record) align(a *arch) int {
+	if r.packed {
+		return 1
+	}
+	align := 1
+	for _, f := range r.fields {
+		if fa := f.typ.align(a); fa > align {
+			align = fa
+		}
+	}
+	return align
+}
+
+// offsetof returns the offset and the type of a member, which may be nested.
+// (e.g. "m.offset")
+func (r *record) offsetof(a *arch, member string) (int, ctype) {
+	var (
+		offset int
+		t      ctype = r
+	)
+	for _, name := range strings.Split(member, ".") {
+		r, ok := t.(*record)
+		if !ok {
+			panic("not a struct or union: " + member)
+		}
+		offsets, _ := r.layout(a)
+		found := false
+		for i, f := range r.fields {
+			if f.name == name {
+				offset += offsets[i]
+				t = f.typ
+				found = true
+				break
+			}
+		}
+		if !found {
+			panic("no such member: " + member)
+		}
+	}
+	return offset, t
+}
+
+func roundUp(n, align int) int {
+	return (n + align - 1) / align * align
+}
@@ -0,0 +1,149 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+import (
+	"bytes"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"testing"
+)
+
+// legacyArches are the architectures whose files were generated from the
+// kernel headers by a C program. Copies of those files, as they were before the
+// generator replaced them, are kept in testdata.
+var legacyArches = []string{"386", "amd64", "arm", "arm64"}
+
+// readConstants returns the integer constants declared in a Go file.
+func readConstants(path string) (map[string]int64, error) {
+	f, err := parser.ParseFile(token.NewFileSet(), path, nil, 0)
+	if err != nil {
+		return nil, err
+	}
+	consts := make(map[string]int64)
+	for _, decl := range f.Decls {
+		gd, ok := decl.(*ast.GenDecl)
+		if !ok || gd.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gd.Specs {
+			vs := spec.(*ast.ValueSpec)
+			for i, name := range vs.Names {
+				lit, ok := vs.Values[i].(*ast.BasicLit)
+				if !ok {
+					continue
+				}
+				v, err := strconv.ParseInt(lit.Value, 0, 64)
+				if err != nil {
+					return nil, err
+				}
+				consts[name.Name] = v
+			}
+		}
+	}
+	return consts, nil
+}
+
+func findArch(name string) *arch {
+	for i := range arches {
+		if arches[i].name == name {
+			return &arches[i]
+		}
+	}
+	return nil
+}
+
+// TestLegacyTables checks that the generator agrees with the C program on every
+// constant the latter produced.
+func TestLegacyTables(t *testing.T) {
+	for _, name := range legacyArches {
+		a := findArch(name)
+		want, err := readConstants(filepath.Join("testdata", "arch_"+name+".go"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := make(map[string]int64)
+		for _, group := range constants {
+			for _, c := range group {
+				got[c.name] = c.value(a)
+			}
+		}
+		for c, v := range want {
+			if g, ok := got[c]; !ok {
+				t.Errorf("%s: %s missing from the table\n", name, c)
+			} else if g != v {
+				t.Errorf("%s: %s: got: %#x, expected: %#x\n", name, c, g, v)
+			}
+		}
+	}
+}
+
+// TestKnownValues checks some values of the other architectures, as found in
+// their kernel headers.
+func TestKnownValues(t *testing.T) {
+	tests := []struct {
+		arch, name string
+		value      int64
+	}{
+		{"mips", "vidioc_querycap", 0x40685600},
+		{"mips", "vidioc_streamon", 0x80045612},
+		{"mips", "vidioc_qbuf", 0xc044560f},
+		{"mips64le", "vidioc_qbuf", 0xc058560f},
+		{"ppc64le", "vidioc_gFmt", 0xc0d05604},
+		{"ppc64le", "vidioc_streamoff", 0x80045613},
+		{"riscv64", "vidioc_dqbuf", 0xc0585611},
+		{"s390x", "vidioc_querymenu", 0xc02c5625},
+		{"loong64", "size_buffer", 88},
+		{"mipsle", "offs_standard_id", 8},
+		{"amd64", "vidioc_gJpegcomp", 0x808c563d},
+		{"ppc64le", "vidioc_sJpegcomp", 0x808c563e},
+	}
+	for _, test := range tests {
+		a := findArch(test.arch)
+		for _, group := range constants {
+			for _, c := range group {
+				if c.name == test.name && c.value(a) != test.value {
+					t.Errorf("%s: %s: got: %#x, expected: %#x\n",
+						test.arch, test.name, c.value(a), test.value)
+				}
+			}
+		}
+	}
+}
+
+// TestUpToDate checks that the generated files match the definitions.
+func TestUpToDate(t *testing.T) {
+	for i := range arches {
+		a := &arches[i]
+		got, err := ioutil.ReadFile(filepath.Join("..", "arch_"+a.name+".go"))
+		if err != nil {
+			t.Errorf("%s: %v\n", a.name, err)
+			continue
+		}
+		want, err := generate(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("arch_%s.go is out of date, run go generate\n", a.name)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+// Command generate writes the architecture dependent IOCTL numbers, struct
+// sizes, and struct offsets of package v4l into arch_GOARCH.go files, for all
+// supported architectures. Everything is computed from the definitions in
+// v4l2.go, so neither a C compiler nor kernel headers are needed.
+//
+// Usage (in the root of the package):
+//
+//	go run ./generate
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"log"
+	"path/filepath"
+)
+
+func main() {
+	dir := flag.String("dir", ".", "output directory")
+	flag.Parse()
+	for i := range arches {
+		a := &arches[i]
+		src, err := generate(a)
+		if err != nil {
+			log.Fatal(err)
+		}
+		path := filepath.Join(*dir, "arch_"+a.name+".go")
+		if err := ioutil.WriteFile(path, src, 0666); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// generate returns the contents of the file for an architecture.
+func generate(a *arch) ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "//go:build linux && %s\n", a.name)
+	fmt.Fprintf(&b, "// +build linux,%s\n", a.name)
+	b.WriteString("\n")
+	b.WriteString("/////////////////////////////////////////////////////\n")
+	b.WriteString("//                                                 //\n")
+	b.WriteString("//  !!! THIS IS A GENERATED FILE, DO NOT EDIT !!!  //\n")
+	b.WriteString("//                                                 //\n")
+	b.WriteString("/////////////////////////////////////////////////////\n")
+	b.WriteString("\n")
+	b.WriteString("package v4l\n")
+	for _, group := range constants {
+		b.WriteString("\nconst (\n")
+		for _, c := range group {
+			if c.hex {
+				fmt.Fprintf(&b, "\t%s = 0x%08x\n", c.name, c.value(a))
+			} else {
+				fmt.Fprintf(&b, "\t%s = %d\n", c.name, c.value(a))
+			}
+		}
+		b.WriteString(")\n")
+	}
+	return format.Source(b.Bytes())
+}
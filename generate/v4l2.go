@@ -0,0 +1,609 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+package main
+
+// The structs from linux/videodev2.h and linux/v4l2-subdev.h.
+
+var (
+	v4l2_capability = structOf(
+		field{"driver", array{u8, 16}},
+		field{"card", array{u8, 32}},
+		field{"bus_info", array{u8, 32}},
+		field{"version", u32},
+		field{"capabilities", u32},
+		field{"device_caps", u32},
+		field{"reserved", array{u32, 3}},
+	)
+
+	v4l2_rect = structOf(
+		field{"left", u32},
+		field{"top", u32},
+		field{"width", u32},
+		field{"height", u32},
+	)
+
+	v4l2_fract = structOf(
+		field{"numerator", u32},
+		field{"denominator", u32},
+	)
+
+	v4l2_pix_format = structOf(
+		field{"width", u32},
+		field{"height", u32},
+		field{"pixelformat", u32},
+		field{"field", u32},
+		field{"bytesperline", u32},
+		field{"sizeimage", u32},
+		field{"colorspace", u32},
+		field{"priv", u32},
+		field{"flags", u32},
+		field{"ycbcr_enc", u32},
+		field{"quantization", u32},
+		field{"xfer_func", u32},
+	)
+
+	// v4l2_window is only here because its pointers determine the alignment
+	// of v4l2_format.fmt.
+	v4l2_window = structOf(
+		field{"w", v4l2_rect},
+		field{"field", u32},
+		field{"chromakey", u32},
+		field{"clips", long},
+		field{"clipcount", u32},
+		field{"bitmap", long},
+		field{"global_alpha", u8},
+	)
+
+	v4l2_format = structOf(
+		field{"type", u32},
+		field{"fmt", unionOf(
+			field{"pix", v4l2_pix_format},
+			field{"win", v4l2_window},
+			field{"raw_data", array{u8, 200}},
+		)},
+	)
+
+	v4l2_captureparm = structOf(
+		field{"capability", u32},
+		field{"capturemode", u32},
+		field{"timeperframe", v4l2_fract},
+		field{"extendedmode", u32},
+		field{"readbuffers", u32},
+		field{"reserved", array{u32, 4}},
+	)
+
+	v4l2_streamparm = structOf(
+		field{"type", u32},
+		field{"parm", unionOf(
+			field{"capture", v4l2_captureparm},
+			field{"raw_data", array{u8, 200}},
+		)},
+	)
+
+	v4l2_requestbuffers = structOf(
+		field{"count", u32},
+		field{"type", u32},
+		field{"memory", u32},
+		field{"capabilities", u32},
+		field{"flags", u8},
+		field{"reserved", array{u8, 3}},
+	)
+
+	v4l2_timecode = structOf(
+		field{"type", u32},
+		field{"flags", u32},
+		field{"frames", u8},
+		field{"seconds", u8},
+		field{"minutes", u8},
+		field{"hours", u8},
+		field{"userbits", array{u8, 4}},
+	)
+
+	timeval = structOf(
+		field{"tv_sec", long},
+		field{"tv_usec", long},
+	)
+
+	v4l2_buffer = structOf(
+		field{"index", u32},
+		field{"type", u32},
+		field{"bytesused", u32},
+		field{"flags", u32},
+		field{"field", u32},
+		field{"timestamp", timeval},
+		field{"timecode", v4l2_timecode},
+		field{"sequence", u32},
+		field{"memory", u32},
+		field{"m", unionOf(
+			field{"offset", u32},
+			field{"userptr", long},
+			field{"planes", long},
+			field{"fd", u32},
+		)},
+		field{"length", u32},
+		field{"reserved2", u32},
+		field{"request_fd", u32},
+	)
+
+	v4l2_cropcap = structOf(
+		field{"type", u32},
+		field{"bounds", v4l2_rect},
+		field{"defrect", v4l2_rect},
+		field{"pixelaspect", v4l2_fract},
+	)
+
+	v4l2_crop = structOf(
+		field{"type", u32},
+		field{"c", v4l2_rect},
+	)
+
+	v4l2_standard = structOf(
+		field{"index", u32},
+		field{"id", u64},
+		field{"name", array{u8, 24}},
+		field{"frameperiod", v4l2_fract},
+		field{"framelines", u32},
+		field{"reserved", array{u32, 4}},
+	)
+
+	v4l2_fmtdesc = structOf(
+		field{"index", u32},
+		field{"type", u32},
+		field{"flags", u32},
+		field{"description", array{u8, 32}},
+		field{"pixelformat", u32},
+		field{"mbus_code", u32},
+		field{"reserved", array{u32, 3}},
+	)
+
+	v4l2_frmsize_discrete = structOf(
+		field{"width", u32},
+		field{"height", u32},
+	)
+
+	v4l2_frmsize_stepwise = structOf(
+		field{"min_width", u32},
+		field{"max_width", u32},
+		field{"step_width", u32},
+		field{"min_height", u32},
+		field{"max_height", u32},
+		field{"step_height", u32},
+	)
+
+	v4l2_frmsizeenum = structOf(
+		field{"index", u32},
+		field{"pixel_format", u32},
+		field{"type", u32},
+		field{"", unionOf(
+			field{"discrete", v4l2_frmsize_discrete},
+			field{"stepwise", v4l2_frmsize_stepwise},
+		)},
+		field{"reserved", array{u32, 2}},
+	)
+
+	v4l2_frmival_stepwise = structOf(
+		field{"min", v4l2_fract},
+		field{"max", v4l2_fract},
+		field{"step", v4l2_fract},
+	)
+
+	v4l2_frmivalenum = structOf(
+		field{"index", u32},
+		field{"pixel_format", u32},
+		field{"width", u32},
+		field{"height", u32},
+		field{"type", u32},
+		field{"", unionOf(
+			field{"discrete", v4l2_fract},
+			field{"stepwise", v4l2_frmival_stepwise},
+		)},
+		field{"reserved", array{u32, 2}},
+	)
+
+	v4l2_queryctrl = structOf(
+		field{"id", u32},
+		field{"type", u32},
+		field{"name", array{u8, 32}},
+		field{"minimum", u32},
+		field{"maximum", u32},
+		field{"step", u32},
+		field{"default_value", u32},
+		field{"flags", u32},
+		field{"reserved", array{u32, 2}},
+	)
+
+	v4l2_querymenu = packedStructOf(
+		field{"id", u32},
+		field{"index", u32},
+		field{"", unionOf(
+			field{"name", array{u8, 32}},
+			field{"value", u64},
+		)},
+		field{"reserved", u32},
+	)
+
+	v4l2_control = structOf(
+		field{"id", u32},
+		field{"value", u32},
+	)
+
+	v4l2_jpegcompression = structOf(
+		field{"quality", u32},
+		field{"APPn", u32},
+		field{"APP_len", u32},
+		field{"APP_data", array{u8, 60}},
+		field{"COM_len", u32},
+		field{"COM_data", array{u8, 60}},
+		field{"jpeg_markers", u32},
+	)
+
+	v4l2_mbus_framefmt = structOf(
+		field{"width", u32},
+		field{"height", u32},
+		field{"code", u32},
+		field{"field", u32},
+		field{"colorspace", u32},
+		field{"ycbcr_enc", u16},
+		field{"quantization", u16},
+		field{"xfer_func", u16},
+		field{"flags", u16},
+		field{"reserved", array{u16, 10}},
+	)
+
+	v4l2_subdev_format = structOf(
+		field{"which", u32},
+		field{"pad", u32},
+		field{"format", v4l2_mbus_framefmt},
+		field{"stream", u32},
+		field{"reserved", array{u32, 7}},
+	)
+
+	v4l2_subdev_frame_interval = structOf(
+		field{"pad", u32},
+		field{"interval", v4l2_fract},
+		field{"stream", u32},
+		field{"which", u32},
+		field{"reserved", array{u32, 7}},
+	)
+
+	v4l2_subdev_selection = structOf(
+		field{"which", u32},
+		field{"pad", u32},
+		field{"target", u32},
+		field{"flags", u32},
+		field{"r", v4l2_rect},
+		field{"stream", u32},
+		field{"reserved", array{u32, 7}},
+	)
+
+	v4l2_subdev_mbus_code_enum = structOf(
+		field{"pad", u32},
+		field{"index", u32},
+		field{"code", u32},
+		field{"which", u32},
+		field{"flags", u32},
+		field{"stream", u32},
+		field{"reserved", array{u32, 6}},
+	)
+)
+
+// A constant is a constant of the generated file.
+type constant struct {
+	name  string
+	hex   bool
+	value func(a *arch) int64
+}
+
+// iow, iowr, and ior return IOCTL number constants, like the macros in
+// linux/videodev2.h.
+func ior(name string, nr byte, t ctype) constant {
+	return ioctlConst(name, dirR, nr, t)
+}
+
+func iow(name string, nr byte, t ctype) constant {
+	return ioctlConst(name, dirW, nr, t)
+}
+
+func iowr(name string, nr byte, t ctype) constant {
+	return ioctlConst(name, dirRW, nr, t)
+}
+
+func ioctlConst(name string, dir int, nr byte, t ctype) constant {
+	return constant{name, true, func(a *arch) int64 {
+		return int64(a.number(dir, 'V', nr, t))
+	}}
+}
+
+// sizeof returns the size of a type.
+func sizeof(name string, t ctype) constant {
+	return constant{name, false, func(a *arch) int64 {
+		return int64(t.size(a))
+	}}
+}
+
+// offsetof returns the offset of a member of a struct.
+func offsetof(name string, r *record, member string) constant {
+	return constant{name, false, func(a *arch) int64 {
+		offset, _ := r.offsetof(a, member)
+		return int64(offset)
+	}}
+}
+
+// memberSize returns the size of a member of a struct.
+func memberSize(name string, r *record, member string) constant {
+	return constant{name, false, func(a *arch) int64 {
+		_, t := r.offsetof(a, member)
+		return int64(t.size(a))
+	}}
+}
+
+// constants lists the generated constants, grouped into const blocks.
+var constants = [][]constant{
+	{
+		ior("vidioc_querycap", 0, v4l2_capability),
+		iowr("vidioc_gFmt", 4, v4l2_format),
+		iowr("vidioc_sFmt", 5, v4l2_format),
+		iowr("vidioc_gParm", 21, v4l2_streamparm),
+		iowr("vidioc_sParm", 22, v4l2_streamparm),
+		iowr("vidioc_reqbufs", 8, v4l2_requestbuffers),
+		iowr("vidioc_querybuf", 9, v4l2_buffer),
+		iowr("vidioc_qbuf", 15, v4l2_buffer),
+		iowr("vidioc_dqbuf", 17, v4l2_buffer),
+		iow("vidioc_streamon", 18, u32),
+		iow("vidioc_streamoff", 19, u32),
+		iowr("vidioc_cropcap", 58, v4l2_cropcap),
+		iow("vidioc_sCrop", 60, v4l2_crop),
+		iowr("vidioc_enumstd", 25, v4l2_standard),
+		iowr("vidioc_enumFmt", 2, v4l2_fmtdesc),
+		iowr("vidioc_enumFramesizes", 74, v4l2_frmsizeenum),
+		iowr("vidioc_enumFrameintervals", 75, v4l2_frmivalenum),
+		iowr("vidioc_queryctrl", 36, v4l2_queryctrl),
+		iowr("vidioc_querymenu", 37, v4l2_querymenu),
+		iowr("vidioc_gCtrl", 27, v4l2_control),
+		iowr("vidioc_sCtrl", 28, v4l2_control),
+		ior("vidioc_gPriority", 67, u32),
+		iow("vidioc_sPriority", 68, u32),
+		ior("vidioc_gJpegcomp", 61, v4l2_jpegcompression),
+		iow("vidioc_sJpegcomp", 62, v4l2_jpegcompression),
+		iowr("vidioc_subdevGFmt", 4, v4l2_subdev_format),
+		iowr("vidioc_subdevSFmt", 5, v4l2_subdev_format),
+		iowr("vidioc_subdevGFrameInterval", 21, v4l2_subdev_frame_interval),
+		iowr("vidioc_subdevSFrameInterval", 22, v4l2_subdev_frame_interval),
+		iowr("vidioc_subdevGSelection", 61, v4l2_subdev_selection),
+		iowr("vidioc_subdevSSelection", 62, v4l2_subdev_selection),
+		iowr("vidioc_subdevEnumMbusCode", 2, v4l2_subdev_mbus_code_enum),
+	},
+	{
+		sizeof("size_capability", v4l2_capability),
+		sizeof("size_format", v4l2_format),
+		sizeof("size_streamparm", v4l2_streamparm),
+		sizeof("size_requestbuffers", v4l2_requestbuffers),
+		sizeof("size_buffer", v4l2_buffer),
+		sizeof("size_int", u32),
+		sizeof("size_cropcap", v4l2_cropcap),
+		sizeof("size_crop", v4l2_crop),
+		sizeof("size_standard", v4l2_standard),
+		sizeof("size_fmtdesc", v4l2_fmtdesc),
+		sizeof("size_frmsizeenum", v4l2_frmsizeenum),
+		sizeof("size_frmivalenum", v4l2_frmivalenum),
+		sizeof("size_queryctrl", v4l2_queryctrl),
+		sizeof("size_querymenu", v4l2_querymenu),
+		sizeof("size_control", v4l2_control),
+		sizeof("size_jpegcompression", v4l2_jpegcompression),
+		sizeof("size_subdevFormat", v4l2_subdev_format),
+		sizeof("size_subdevFrameInterval", v4l2_subdev_frame_interval),
+		sizeof("size_subdevSelection", v4l2_subdev_selection),
+		sizeof("size_subdevMbusCodeEnum", v4l2_subdev_mbus_code_enum),
+	},
+	{
+		offsetof("offs_capability_driver", v4l2_capability, "driver"),
+		memberSize("size_capability_driver", v4l2_capability, "driver"),
+		offsetof("offs_capability_card", v4l2_capability, "card"),
+		memberSize("size_capability_card", v4l2_capability, "card"),
+		offsetof("offs_capability_busInfo", v4l2_capability, "bus_info"),
+		memberSize("size_capability_busInfo", v4l2_capability, "bus_info"),
+		offsetof("offs_capability_version", v4l2_capability, "version"),
+		offsetof("offs_capability_capabilities", v4l2_capability, "capabilities"),
+		offsetof("offs_capability_deviceCaps", v4l2_capability, "device_caps"),
+	},
+	{
+		offsetof("offs_format_typ", v4l2_format, "type"),
+		offsetof("offs_format_fmt", v4l2_format, "fmt"),
+	},
+	{
+		offsetof("offs_pixFormat_width", v4l2_pix_format, "width"),
+		offsetof("offs_pixFormat_height", v4l2_pix_format, "height"),
+		offsetof("offs_pixFormat_pixelformat", v4l2_pix_format, "pixelformat"),
+		offsetof("offs_pixFormat_field", v4l2_pix_format, "field"),
+		offsetof("offs_pixFormat_bytesperline", v4l2_pix_format, "bytesperline"),
+		offsetof("offs_pixFormat_sizeimage", v4l2_pix_format, "sizeimage"),
+		offsetof("offs_pixFormat_colorspace", v4l2_pix_format, "colorspace"),
+		offsetof("offs_pixFormat_priv", v4l2_pix_format, "priv"),
+		offsetof("offs_pixFormat_flags", v4l2_pix_format, "flags"),
+		offsetof("offs_pixFormat_ycbcrEnc", v4l2_pix_format, "ycbcr_enc"),
+		offsetof("offs_pixFormat_quantization", v4l2_pix_format, "quantization"),
+		offsetof("offs_pixFormat_xferFunc", v4l2_pix_format, "xfer_func"),
+	},
+	{
+		offsetof("offs_streamparm_typ", v4l2_streamparm, "type"),
+		offsetof("offs_streamparm_parm", v4l2_streamparm, "parm"),
+	},
+	{
+		offsetof("offs_captureparm_capability", v4l2_captureparm, "capability"),
+		offsetof("offs_captureparm_capturemode", v4l2_captureparm, "capturemode"),
+		offsetof("offs_captureparm_timeperframe", v4l2_captureparm, "timeperframe"),
+		offsetof("offs_captureparm_extendedmode", v4l2_captureparm, "extendedmode"),
+		offsetof("offs_captureparm_readbuffers", v4l2_captureparm, "readbuffers"),
+	},
+	{
+		offsetof("offs_requestbuffers_count", v4l2_requestbuffers, "count"),
+		offsetof("offs_requestbuffers_typ", v4l2_requestbuffers, "type"),
+		offsetof("offs_requestbuffers_memory", v4l2_requestbuffers, "memory"),
+	},
+	{
+		offsetof("offs_buffer_index", v4l2_buffer, "index"),
+		offsetof("offs_buffer_typ", v4l2_buffer, "type"),
+		offsetof("offs_buffer_bytesused", v4l2_buffer, "bytesused"),
+		offsetof("offs_buffer_flags", v4l2_buffer, "flags"),
+		offsetof("offs_buffer_field", v4l2_buffer, "field"),
+		offsetof("offs_buffer_timecode", v4l2_buffer, "timecode"),
+		offsetof("offs_buffer_sequence", v4l2_buffer, "sequence"),
+		offsetof("offs_buffer_memory", v4l2_buffer, "memory"),
+		offsetof("offs_buffer_offset", v4l2_buffer, "m.offset"),
+		offsetof("offs_buffer_length", v4l2_buffer, "length"),
+	},
+	{
+		offsetof("offs_cropcap_typ", v4l2_cropcap, "type"),
+		offsetof("offs_cropcap_bounds", v4l2_cropcap, "bounds"),
+		offsetof("offs_cropcap_defrect", v4l2_cropcap, "defrect"),
+		offsetof("offs_cropcap_pixelaspect", v4l2_cropcap, "pixelaspect"),
+	},
+	{
+		offsetof("offs_crop_typ", v4l2_crop, "type"),
+		offsetof("offs_crop_c", v4l2_crop, "c"),
+	},
+	{
+		offsetof("offs_fract_numerator", v4l2_fract, "numerator"),
+		offsetof("offs_fract_denominator", v4l2_fract, "denominator"),
+	},
+	{
+		offsetof("offs_timecode_typ", v4l2_timecode, "type"),
+		offsetof("offs_timecode_flags", v4l2_timecode, "flags"),
+		offsetof("offs_timecode_frames", v4l2_timecode, "frames"),
+		offsetof("offs_timecode_seconds", v4l2_timecode, "seconds"),
+		offsetof("offs_timecode_minutes", v4l2_timecode, "minutes"),
+		offsetof("offs_timecode_hours", v4l2_timecode, "hours"),
+		offsetof("offs_timecode_userbits", v4l2_timecode, "userbits"),
+	},
+	{
+		offsetof("offs_rect_left", v4l2_rect, "left"),
+		offsetof("offs_rect_top", v4l2_rect, "top"),
+		offsetof("offs_rect_width", v4l2_rect, "width"),
+		offsetof("offs_rect_height", v4l2_rect, "height"),
+	},
+	{
+		offsetof("offs_standard_index", v4l2_standard, "index"),
+		offsetof("offs_standard_id", v4l2_standard, "id"),
+		offsetof("offs_standard_name", v4l2_standard, "name"),
+		memberSize("size_standard_name", v4l2_standard, "name"),
+		offsetof("offs_standard_frameperiod", v4l2_standard, "frameperiod"),
+		offsetof("offs_standard_framelines", v4l2_standard, "framelines"),
+	},
+	{
+		offsetof("offs_fmtdesc_index", v4l2_fmtdesc, "index"),
+		offsetof("offs_fmtdesc_typ", v4l2_fmtdesc, "type"),
+		offsetof("offs_fmtdesc_flags", v4l2_fmtdesc, "flags"),
+		offsetof("offs_fmtdesc_description", v4l2_fmtdesc, "description"),
+		memberSize("size_fmtdesc_description", v4l2_fmtdesc, "description"),
+		offsetof("offs_fmtdesc_pixelformat", v4l2_fmtdesc, "pixelformat"),
+	},
+	{
+		offsetof("offs_frmsizeenum_index", v4l2_frmsizeenum, "index"),
+		offsetof("offs_frmsizeenum_pixelFormat", v4l2_frmsizeenum, "pixel_format"),
+		offsetof("offs_frmsizeenum_typ", v4l2_frmsizeenum, "type"),
+		offsetof("offs_frmsizeenum_discrete", v4l2_frmsizeenum, ".discrete"),
+		offsetof("offs_frmsizeenum_stepwise", v4l2_frmsizeenum, ".stepwise"),
+	},
+	{
+		offsetof("offs_frmsizeDiscrete_width", v4l2_frmsize_discrete, "width"),
+		offsetof("offs_frmsizeDiscrete_height", v4l2_frmsize_discrete, "height"),
+	},
+	{
+		offsetof("offs_frmsizeStepwise_minWidth", v4l2_frmsize_stepwise, "min_width"),
+		offsetof("offs_frmsizeStepwise_maxWidth", v4l2_frmsize_stepwise, "max_width"),
+		offsetof("offs_frmsizeStepwise_stepWidth", v4l2_frmsize_stepwise, "step_width"),
+		offsetof("offs_frmsizeStepwise_minHeight", v4l2_frmsize_stepwise, "min_height"),
+		offsetof("offs_frmsizeStepwise_maxHeight", v4l2_frmsize_stepwise, "max_height"),
+		offsetof("offs_frmsizeStepwise_stepHeight", v4l2_frmsize_stepwise, "step_height"),
+	},
+	{
+		offsetof("offs_frmivalenum_index", v4l2_frmivalenum, "index"),
+		offsetof("offs_frmivalenum_pixelFormat", v4l2_frmivalenum, "pixel_format"),
+		offsetof("offs_frmivalenum_width", v4l2_frmivalenum, "width"),
+		offsetof("offs_frmivalenum_height", v4l2_frmivalenum, "height"),
+		offsetof("offs_frmivalenum_typ", v4l2_frmivalenum, "type"),
+		offsetof("offs_frmivalenum_discrete", v4l2_frmivalenum, ".discrete"),
+		offsetof("offs_frmivalenum_stepwise", v4l2_frmivalenum, ".stepwise"),
+	},
+	{
+		offsetof("offs_frmivalStepwise_min", v4l2_frmival_stepwise, "min"),
+		offsetof("offs_frmivalStepwise_max", v4l2_frmival_stepwise, "max"),
+		offsetof("offs_frmivalStepwise_step", v4l2_frmival_stepwise, "step"),
+	},
+	{
+		offsetof("offs_queryctrl_id", v4l2_queryctrl, "id"),
+		offsetof("offs_queryctrl_typ", v4l2_queryctrl, "type"),
+		offsetof("offs_queryctrl_name", v4l2_queryctrl, "name"),
+		memberSize("size_queryctrl_name", v4l2_queryctrl, "name"),
+		offsetof("offs_queryctrl_minimum", v4l2_queryctrl, "minimum"),
+		offsetof("offs_queryctrl_maximum", v4l2_queryctrl, "maximum"),
+		offsetof("offs_queryctrl_step", v4l2_queryctrl, "step"),
+		offsetof("offs_queryctrl_defaultValue", v4l2_queryctrl, "default_value"),
+		offsetof("offs_queryctrl_flags", v4l2_queryctrl, "flags"),
+	},
+	{
+		offsetof("offs_querymenu_id", v4l2_querymenu, "id"),
+		offsetof("offs_querymenu_index", v4l2_querymenu, "index"),
+		offsetof("offs_querymenu_name", v4l2_querymenu, ".name"),
+		memberSize("size_querymenu_name", v4l2_querymenu, ".name"),
+		offsetof("offs_querymenu_value", v4l2_querymenu, ".value"),
+	},
+	{
+		offsetof("offs_control_id", v4l2_control, "id"),
+		offsetof("offs_control_value", v4l2_control, "value"),
+	},
+	{
+		offsetof("offs_jpegcompression_quality", v4l2_jpegcompression, "quality"),
+		offsetof("offs_jpegcompression_appn", v4l2_jpegcompression, "APPn"),
+		offsetof("offs_jpegcompression_appLen", v4l2_jpegcompression, "APP_len"),
+		offsetof("offs_jpegcompression_appData", v4l2_jpegcompression, "APP_data"),
+		memberSize("size_jpegcompression_appData", v4l2_jpegcompression, "APP_data"),
+		offsetof("offs_jpegcompression_comLen", v4l2_jpegcompression, "COM_len"),
+		offsetof("offs_jpegcompression_comData", v4l2_jpegcompression, "COM_data"),
+		memberSize("size_jpegcompression_comData", v4l2_jpegcompression, "COM_data"),
+		offsetof("offs_jpegcompression_jpegMarkers", v4l2_jpegcompression, "jpeg_markers"),
+	},
+	{
+		offsetof("offs_subdevFormat_which", v4l2_subdev_format, "which"),
+		offsetof("offs_subdevFormat_pad", v4l2_subdev_format, "pad"),
+		offsetof("offs_subdevFormat_format", v4l2_subdev_format, "format"),
+	},
+	{
+		offsetof("offs_mbusFramefmt_width", v4l2_mbus_framefmt, "width"),
+		offsetof("offs_mbusFramefmt_height", v4l2_mbus_framefmt, "height"),
+		offsetof("offs_mbusFramefmt_code", v4l2_mbus_framefmt, "code"),
+		offsetof("offs_mbusFramefmt_field", v4l2_mbus_framefmt, "field"),
+		offsetof("offs_mbusFramefmt_colorspace", v4l2_mbus_framefmt, "colorspace"),
+	},
+	{
+		offsetof("offs_subdevFrameInterval_pad", v4l2_subdev_frame_interval, "pad"),
+		offsetof("offs_subdevFrameInterval_interval", v4l2_subdev_frame_interval, "interval"),
+	},
+	{
+		offsetof("offs_subdevSelection_which", v4l2_subdev_selection, "which"),
+		offsetof("offs_subdevSelection_pad", v4l2_subdev_selection, "pad"),
+		offsetof("offs_subdevSelection_target", v4l2_subdev_selection, "target"),
+		offsetof("offs_subdevSelection_flags", v4l2_subdev_selection, "flags"),
+		offsetof("offs_subdevSelection_r", v4l2_subdev_selection, "r"),
+	},
+	{
+		offsetof("offs_subdevMbusCodeEnum_pad", v4l2_subdev_mbus_code_enum, "pad"),
+		offsetof("offs_subdevMbusCodeEnum_index", v4l2_subdev_mbus_code_enum, "index"),
+		offsetof("offs_subdevMbusCodeEnum_code", v4l2_subdev_mbus_code_enum, "code"),
+		offsetof("offs_subdevMbusCodeEnum_which", v4l2_subdev_mbus_code_enum, "which"),
+	},
+}
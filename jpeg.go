@@ -0,0 +1,223 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"errors"
+	"syscall"
+)
+
+// JPEG markers, for JPEGParams.Markers. They tell which segments the driver
+// includes in the images.
+const (
+	JPEGMarkerDHT = 1 << 3 // Huffman tables
+	JPEGMarkerDQT = 1 << 4 // quantization tables
+	JPEGMarkerDRI = 1 << 5 // restart interval
+	JPEGMarkerCOM = 1 << 6 // comment
+	JPEGMarkerAPP = 1 << 7 // application data
+)
+
+// maxJPEGSegment is the maximum length of JPEGParams.APPData and
+// JPEGParams.Comment.
+const maxJPEGSegment = 60
+
+// JPEGParams are the compression parameters of a device configured for MJPEG
+// (mjpeg.FourCC). The configuration should be set before the parameters, since
+// changing it may reset them. The quality alone can also be set along with the
+// configuration, through DeviceConfig.JPEGQuality.
+type JPEGParams struct {
+	// Quality is the compression quality, from 1 (smallest images) to 100
+	// (best images).
+	Quality int
+
+	// Markers is a combination of JPEGMarker flags.
+	Markers uint32
+
+	// APPn is the number of the APP segment, from 0 to 15, and APPData is its
+	// contents. It's only included if Markers has JPEGMarkerAPP.
+	APPn    int
+	APPData []byte
+
+	// Comment is the contents of the COM segment. It's only included if
+	// Markers has JPEGMarkerCOM.
+	Comment []byte
+}
+
+// JPEGParams returns the JPEG compression parameters of the device. Drivers
+// support them through VIDIOC_G_JPEGCOMP, or just the quality through the
+// CtrlJPEGCompressionQuality control, in which case the other fields are left
+// empty. If there is neither, it fails with ErrUnsupported.
+func (d *Device) JPEGParams() (_ JPEGParams, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return JPEGParams{}, err
+	}
+	defer d.mu.Unlock()
+	return d.jpegParams()
+}
+
+// jpegParams is JPEGParams without locking.
+func (d *device) jpegParams() (JPEGParams, error) {
+	var (
+		p         JPEGParams
+		supported bool
+	)
+	jc := v4l_jpegcompression{}
+	switch err := ioctl_gJpegcomp(d.dev, &jc); {
+	case err == nil:
+		p = JPEGParams{
+			Quality: int(jc.quality),
+			Markers: jc.jpegMarkers,
+			APPn:    int(jc.appn),
+			APPData: jpegSegment(jc.appData, jc.appLen),
+			Comment: jpegSegment(jc.comData, jc.comLen),
+		}
+		supported = true
+	case errors.Is(err, syscall.ENOTTY), errors.Is(err, syscall.EINVAL):
+		// No VIDIOC_G_JPEGCOMP.
+	default:
+		return JPEGParams{}, err
+	}
+
+	// The control takes precedence, as drivers that have both tend to leave
+	// the quality in VIDIOC_G_JPEGCOMP alone.
+	switch q, err := d.getControl(CtrlJPEGCompressionQuality); {
+	case err == nil:
+		p.Quality = int(q)
+		supported = true
+	case errors.Is(err, syscall.ENOTTY), errors.Is(err, syscall.EINVAL):
+		// No quality control.
+	default:
+		return JPEGParams{}, err
+	}
+
+	if !supported {
+		return JPEGParams{}, ErrUnsupported
+	}
+	return p, nil
+}
+
+// SetJPEGParams sets the JPEG compression parameters of the device. The driver
+// may adjust them, e.g. round the quality to the few levels it supports, so
+// JPEGParams should be called to find out what was actually set. If the
+// driver only has CtrlJPEGCompressionQuality, then only the quality is set,
+// unless p has other parameters, which makes it fail with EINVAL. It fails with
+// ErrUnsupported if the driver has no JPEG parameters at all.
+func (d *Device) SetJPEGParams(p JPEGParams) (err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return err
+	}
+	defer d.mu.Unlock()
+
+	return d.setJPEGParams(p)
+}
+
+// setJPEGParams is SetJPEGParams without locking.
+func (d *device) setJPEGParams(p JPEGParams) error {
+	if len(p.APPData) > maxJPEGSegment || len(p.Comment) > maxJPEGSegment {
+		return Error("JPEG segment too long")
+	}
+
+	supported := false
+	jc := v4l_jpegcompression{
+		quality:     int32(p.Quality),
+		appn:        int32(p.APPn),
+		appLen:      int32(len(p.APPData)),
+		appData:     p.APPData,
+		comLen:      int32(len(p.Comment)),
+		comData:     p.Comment,
+		jpegMarkers: p.Markers,
+	}
+	switch err := ioctl_sJpegcomp(d.dev, &jc); {
+	case err == nil:
+		supported = true
+	case errors.Is(err, syscall.ENOTTY):
+		// No VIDIOC_S_JPEGCOMP.
+	case errors.Is(err, syscall.EINVAL) && p.Markers == 0 && p.APPn == 0 &&
+		len(p.APPData) == 0 && len(p.Comment) == 0:
+		// Some drivers reject VIDIOC_S_JPEGCOMP, and only have the
+		// quality control, which is enough when only the quality is set.
+	default:
+		return err
+	}
+
+	ok, err := d.setQualityControl(p.Quality)
+	if err != nil {
+		return err
+	}
+	if !supported && !ok {
+		return ErrUnsupported
+	}
+	return nil
+}
+
+// setQualityControl sets CtrlJPEGCompressionQuality, if the device has it.
+func (d *device) setQualityControl(quality int) (ok bool, err error) {
+	switch err := d.setControl(CtrlJPEGCompressionQuality, int32(quality)); {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, syscall.ENOTTY), errors.Is(err, syscall.EINVAL):
+		// No quality control.
+		return false, nil
+	default:
+		return false, err
+	}
+}
+
+// jpegSegment returns the first n bytes of data, guarding against drivers that
+// report nonsense lengths.
+func jpegSegment(data []byte, n int32) []byte {
+	if n <= 0 {
+		return nil
+	}
+	if int(n) > len(data) {
+		n = int32(len(data))
+	}
+	return data[:n]
+}
+
+// isJPEG tells if f is one of the JPEG based formats, which have JPEG
+// compression parameters.
+func isJPEG(f FourCC) bool {
+	return f == MakeFourCC('M', 'J', 'P', 'G') || f == MakeFourCC('J', 'P', 'E', 'G')
+}
+
+// setJPEGQuality sets the quality of a device configured for a JPEG format,
+// keeping the other parameters. Devices without JPEG parameters are left
+// alone, like drivers ignore other parts of the configuration they can't
+// apply.
+func (d *device) setJPEGQuality(quality int) error {
+	p, err := d.jpegParams()
+	if err == ErrUnsupported {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	p.Quality = quality
+	err = d.setJPEGParams(p)
+	if errors.Is(err, syscall.EINVAL) {
+		// VIDIOC_S_JPEGCOMP rejected the parameters read from the driver
+		// itself, so only the quality control can be used.
+		_, err = d.setQualityControl(quality)
+	}
+	return err
+}
@@ -0,0 +1,176 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"bytes"
+	"errors"
+	"syscall"
+	"testing"
+)
+
+func TestJPEGParams(t *testing.T) {
+	dev, err := OpenFake(FakeDevice{
+		Configs: []DeviceConfig{{Format: fourcc("MJPG"), Width: 640, Height: 480, FPS: Frac{30, 1}}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	p, err := dev.JPEGParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Quality != 75 || p.Markers != JPEGMarkerDHT|JPEGMarkerDQT {
+		t.Errorf("JPEGParams: got: %+v\n", p)
+	}
+
+	set := JPEGParams{
+		Quality: 90,
+		Markers: JPEGMarkerDHT | JPEGMarkerDQT | JPEGMarkerCOM | JPEGMarkerAPP,
+		APPn:    1,
+		APPData: []byte("Exif"),
+		Comment: []byte("hello"),
+	}
+	if err := dev.SetJPEGParams(set); err != nil {
+		t.Fatal(err)
+	}
+	p, err = dev.JPEGParams()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Quality != set.Quality || p.Markers != set.Markers || p.APPn != set.APPn ||
+		!bytes.Equal(p.APPData, set.APPData) || !bytes.Equal(p.Comment, set.Comment) {
+		t.Errorf("JPEGParams: got: %+v, expected: %+v\n", p, set)
+	}
+
+	set.Comment = make([]byte, maxJPEGSegment+1)
+	if err := dev.SetJPEGParams(set); err == nil {
+		t.Errorf("SetJPEGParams succeeded with a %d byte comment\n", len(set.Comment))
+	}
+}
+
+func TestJPEGQualityControl(t *testing.T) {
+	noJPEGComp := func(op string) error {
+		if op == "VIDIOC_G_JPEGCOMP" || op == "VIDIOC_S_JPEGCOMP" {
+			return syscall.ENOTTY
+		}
+		return nil
+	}
+
+	dev, err := OpenFake(FakeDevice{
+		Controls: []ControlInfo{{
+			CID: CtrlJPEGCompressionQuality, Name: "Compression Quality",
+			Type: "int", Min: 0, Max: 100, Step: 5, Default: 80,
+		}},
+		Err: noJPEGComp,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	if err := dev.SetJPEGParams(JPEGParams{Quality: 83}); err != nil {
+		t.Fatal(err)
+	}
+	if p, err := dev.JPEGParams(); err != nil || p.Quality != 85 {
+		t.Errorf("JPEGParams: got: %+v (%v), expected quality 85\n", p, err)
+	}
+
+	// Neither VIDIOC_G_JPEGCOMP nor the control.
+	dev2, err := OpenFake(FakeDevice{Err: noJPEGComp})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev2.Close()
+	if _, err := dev2.JPEGParams(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("JPEGParams: got: %v, expected: %v\n", err, ErrUnsupported)
+	}
+	if err := dev2.SetJPEGParams(JPEGParams{Quality: 50}); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("SetJPEGParams: got: %v, expected: %v\n", err, ErrUnsupported)
+	}
+
+	// Nor does SetConfig, but the rest of the configuration is set.
+	cfg := DeviceConfig{Format: fourcc("YUYV"), Width: 640, Height: 480, FPS: Frac{30, 1}, JPEGQuality: 50}
+	if err := dev2.SetConfig(cfg); err != nil {
+		t.Errorf("SetConfig: %v\n", err)
+	}
+
+	// VIDIOC_S_JPEGCOMP is read-only for some drivers.
+	mjpg := DeviceConfig{Format: fourcc("MJPG"), Width: 640, Height: 480, FPS: Frac{30, 1}}
+	dev3, err := OpenFake(FakeDevice{
+		Configs: []DeviceConfig{mjpg},
+		Controls: []ControlInfo{{
+			CID: CtrlJPEGCompressionQuality, Name: "Compression Quality",
+			Type: "int", Min: 0, Max: 100, Step: 1, Default: 80,
+		}},
+		Err: func(op string) error {
+			if op == "VIDIOC_S_JPEGCOMP" {
+				return syscall.EINVAL
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev3.Close()
+	if err := dev3.SetJPEGParams(JPEGParams{Quality: 60}); err != nil {
+		t.Errorf("SetJPEGParams: %v\n", err)
+	}
+	if q, err := dev3.GetControl(CtrlJPEGCompressionQuality); err != nil || q != 60 {
+		t.Errorf("GetControl: got: %v (%v), expected: 60\n", q, err)
+	}
+
+	// The other parameters can't be set with the control.
+	err = dev3.SetJPEGParams(JPEGParams{Quality: 60, Markers: JPEGMarkerCOM, Comment: []byte("x")})
+	if !errors.Is(err, syscall.EINVAL) {
+		t.Errorf("SetJPEGParams: got: %v, expected: %v\n", err, syscall.EINVAL)
+	}
+
+	// SetConfig only asks for the quality.
+	mjpg.JPEGQuality = 70
+	if err := dev3.SetConfig(mjpg); err != nil {
+		t.Errorf("SetConfig: %v\n", err)
+	}
+	if q, err := dev3.GetControl(CtrlJPEGCompressionQuality); err != nil || q != 70 {
+		t.Errorf("GetControl: got: %v (%v), expected: 70\n", q, err)
+	}
+}
+
+func TestMarshalJPEGComp(t *testing.T) {
+	jc := v4l_jpegcompression{
+		quality:     60,
+		appn:        3,
+		appLen:      2,
+		appData:     []byte{0xde, 0xad},
+		comLen:      3,
+		comData:     []byte("abc"),
+		jpegMarkers: JPEGMarkerCOM,
+	}
+	var got v4l_jpegcompression
+	unmarshal(&got, marshal(&jc))
+	if got.quality != jc.quality || got.appn != jc.appn || got.jpegMarkers != jc.jpegMarkers ||
+		!bytes.Equal(jpegSegment(got.appData, got.appLen), jc.appData) ||
+		!bytes.Equal(jpegSegment(got.comData, got.comLen), jc.comData) {
+		t.Errorf("got: %+v, expected: %+v\n", got, jc)
+	}
+}
@@ -0,0 +1,352 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package media
+
+import (
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// IOCTL numbers. Unlike the V4L structs, the media controller structs have the
+// same layout on every architecture.
+const (
+	media_iocDeviceInfo = 0xc1007c00
+	media_iocSetupLink  = 0xc0347c03
+	media_iocGTopology  = 0xc0487c04
+)
+
+// Struct sizes and offsets.
+
+const (
+	size_deviceInfo               = 256
+	offs_deviceInfo_driver        = 0
+	size_deviceInfo_driver        = 16
+	offs_deviceInfo_model         = 16
+	size_deviceInfo_model         = 32
+	offs_deviceInfo_serial        = 48
+	size_deviceInfo_serial        = 40
+	offs_deviceInfo_busInfo       = 88
+	size_deviceInfo_busInfo       = 32
+	offs_deviceInfo_mediaVersion  = 120
+	offs_deviceInfo_hwRevision    = 124
+	offs_deviceInfo_driverVersion = 128
+	size_topology                 = 72
+	offs_topology_topologyVersion = 0
+	offs_topology_numEntities     = 8
+	offs_topology_ptrEntities     = 16
+	offs_topology_numInterfaces   = 24
+	offs_topology_ptrInterfaces   = 32
+	offs_topology_numPads         = 40
+	offs_topology_ptrPads         = 48
+	offs_topology_numLinks        = 56
+	offs_topology_ptrLinks        = 64
+	size_entity                   = 96
+	offs_entity_id                = 0
+	offs_entity_name              = 4
+	size_entity_name              = 64
+	offs_entity_function          = 68
+	offs_entity_flags             = 72
+	size_interface                = 112
+	offs_interface_id             = 0
+	offs_interface_intfType       = 4
+	offs_interface_flags          = 8
+	offs_interface_devnode_major  = 48
+	offs_interface_devnode_minor  = 52
+	size_pad                      = 32
+	offs_pad_id                   = 0
+	offs_pad_entityID             = 4
+	offs_pad_flags                = 8
+	offs_pad_index                = 12
+	size_link                     = 40
+	offs_link_id                  = 0
+	offs_link_sourceID            = 4
+	offs_link_sinkID              = 8
+	offs_link_flags               = 12
+	size_linkDesc                 = 52
+	offs_linkDesc_source          = 0
+	offs_linkDesc_sink            = 20
+	offs_linkDesc_flags           = 40
+	offs_padDesc_entity           = 0
+	offs_padDesc_index            = 4
+	offs_padDesc_flags            = 8
+)
+
+// Structs.
+
+type media_deviceInfo struct {
+	driver        string
+	model         string
+	serial        string
+	busInfo       string
+	mediaVersion  uint32
+	hwRevision    uint32
+	driverVersion uint32
+}
+
+// media_topology is struct media_v2_topology. The arrays are allocated by the
+// caller; their lengths are passed in the num* fields.
+type media_topology struct {
+	topologyVersion uint64
+	numEntities     uint32
+	entities        []byte
+	numInterfaces   uint32
+	interfaces      []byte
+	numPads         uint32
+	pads            []byte
+	numLinks        uint32
+	links           []byte
+}
+
+type media_entity struct {
+	id       uint32
+	name     string
+	function uint32
+	flags    uint32
+}
+
+type media_interface struct {
+	id       uint32
+	intfType uint32
+	flags    uint32
+	major    uint32
+	minor    uint32
+}
+
+type media_pad struct {
+	id       uint32
+	entityID uint32
+	flags    uint32
+	index    uint32
+}
+
+type media_link struct {
+	id       uint32
+	sourceID uint32
+	sinkID   uint32
+	flags    uint32
+}
+
+type media_padDesc struct {
+	entity uint32
+	index  uint16
+	flags  uint32
+}
+
+type media_linkDesc struct {
+	source media_padDesc
+	sink   media_padDesc
+	flags  uint32
+}
+
+// IOCTLs.
+
+func ioctl_deviceInfo(fd int, argp *media_deviceInfo) error {
+	return ioctl(fd, media_iocDeviceInfo, argp)
+}
+
+func ioctl_gTopology(fd int, argp *media_topology) error {
+	err := ioctl(fd, media_iocGTopology, argp)
+	runtime.KeepAlive(argp)
+	return err
+}
+
+func ioctl_setupLink(fd int, argp *media_linkDesc) error {
+	return ioctl(fd, media_iocSetupLink, argp)
+}
+
+func ioctl(fd int, request uint, argp ioctlArg) error {
+	buf := make([]uint64, (argp.size()+7)/8)
+	p := unsafe.Pointer(&buf[0])
+	argp.put(p)
+	_, _, err := syscall.Syscall(syscall.SYS_IOCTL,
+		uintptr(fd), uintptr(request), uintptr(p))
+	if err != 0 {
+		return &OpError{Op: ioctlNames[request], Err: err}
+	}
+	argp.get(p)
+	return nil
+}
+
+// ioctlNames maps IOCTL numbers to their names, for error messages.
+var ioctlNames = map[uint]string{
+	media_iocDeviceInfo: "MEDIA_IOC_DEVICE_INFO",
+	media_iocSetupLink:  "MEDIA_IOC_SETUP_LINK",
+	media_iocGTopology:  "MEDIA_IOC_G_TOPOLOGY",
+}
+
+// Getters and putters.
+
+type ioctlArg interface {
+	get(unsafe.Pointer)
+	put(unsafe.Pointer)
+	size() int
+}
+
+func (p *media_deviceInfo) get(q unsafe.Pointer) {
+	p.driver = getString(q, offs_deviceInfo_driver, size_deviceInfo_driver)
+	p.model = getString(q, offs_deviceInfo_model, size_deviceInfo_model)
+	p.serial = getString(q, offs_deviceInfo_serial, size_deviceInfo_serial)
+	p.busInfo = getString(q, offs_deviceInfo_busInfo, size_deviceInfo_busInfo)
+	p.mediaVersion = getUint32(q, offs_deviceInfo_mediaVersion)
+	p.hwRevision = getUint32(q, offs_deviceInfo_hwRevision)
+	p.driverVersion = getUint32(q, offs_deviceInfo_driverVersion)
+}
+
+func (p *media_deviceInfo) put(q unsafe.Pointer) {}
+
+func (p *media_deviceInfo) size() int {
+	return size_deviceInfo
+}
+
+func (p *media_topology) get(q unsafe.Pointer) {
+	p.topologyVersion = getUint64(q, offs_topology_topologyVersion)
+	p.numEntities = getUint32(q, offs_topology_numEntities)
+	p.numInterfaces = getUint32(q, offs_topology_numInterfaces)
+	p.numPads = getUint32(q, offs_topology_numPads)
+	p.numLinks = getUint32(q, offs_topology_numLinks)
+}
+
+func (p *media_topology) put(q unsafe.Pointer) {
+	putUint64(q, offs_topology_topologyVersion, p.topologyVersion)
+	putUint32(q, offs_topology_numEntities, p.numEntities)
+	putUint64(q, offs_topology_ptrEntities, arrayPtr(p.entities))
+	putUint32(q, offs_topology_numInterfaces, p.numInterfaces)
+	putUint64(q, offs_topology_ptrInterfaces, arrayPtr(p.interfaces))
+	putUint32(q, offs_topology_numPads, p.numPads)
+	putUint64(q, offs_topology_ptrPads, arrayPtr(p.pads))
+	putUint32(q, offs_topology_numLinks, p.numLinks)
+	putUint64(q, offs_topology_ptrLinks, arrayPtr(p.links))
+}
+
+func (p *media_topology) size() int {
+	return size_topology
+}
+
+// arrayPtr returns the address of the first element of b, or 0 if b is empty.
+// The caller must keep b alive until the kernel is done with it.
+func arrayPtr(b []byte) uint64 {
+	if len(b) == 0 {
+		return 0
+	}
+	return uint64(uintptr(unsafe.Pointer(&b[0])))
+}
+
+func (p *media_entity) get(q unsafe.Pointer) {
+	p.id = getUint32(q, offs_entity_id)
+	p.name = getString(q, offs_entity_name, size_entity_name)
+	p.function = getUint32(q, offs_entity_function)
+	p.flags = getUint32(q, offs_entity_flags)
+}
+
+func (p *media_interface) get(q unsafe.Pointer) {
+	p.id = getUint32(q, offs_interface_id)
+	p.intfType = getUint32(q, offs_interface_intfType)
+	p.flags = getUint32(q, offs_interface_flags)
+	p.major = getUint32(q, offs_interface_devnode_major)
+	p.minor = getUint32(q, offs_interface_devnode_minor)
+}
+
+func (p *media_pad) get(q unsafe.Pointer) {
+	p.id = getUint32(q, offs_pad_id)
+	p.entityID = getUint32(q, offs_pad_entityID)
+	p.flags = getUint32(q, offs_pad_flags)
+	p.index = getUint32(q, offs_pad_index)
+}
+
+func (p *media_link) get(q unsafe.Pointer) {
+	p.id = getUint32(q, offs_link_id)
+	p.sourceID = getUint32(q, offs_link_sourceID)
+	p.sinkID = getUint32(q, offs_link_sinkID)
+	p.flags = getUint32(q, offs_link_flags)
+}
+
+func (p *media_padDesc) get(q unsafe.Pointer) {
+	p.entity = getUint32(q, offs_padDesc_entity)
+	p.index = getUint16(q, offs_padDesc_index)
+	p.flags = getUint32(q, offs_padDesc_flags)
+}
+
+func (p *media_padDesc) put(q unsafe.Pointer) {
+	putUint32(q, offs_padDesc_entity, p.entity)
+	putUint16(q, offs_padDesc_index, p.index)
+	putUint32(q, offs_padDesc_flags, p.flags)
+}
+
+func (p *media_linkDesc) get(q unsafe.Pointer) {
+	p.source.get(unsafe.Pointer(uintptr(q) + offs_linkDesc_source))
+	p.sink.get(unsafe.Pointer(uintptr(q) + offs_linkDesc_sink))
+	p.flags = getUint32(q, offs_linkDesc_flags)
+}
+
+func (p *media_linkDesc) put(q unsafe.Pointer) {
+	p.source.put(unsafe.Pointer(uintptr(q) + offs_linkDesc_source))
+	p.sink.put(unsafe.Pointer(uintptr(q) + offs_linkDesc_sink))
+	putUint32(q, offs_linkDesc_flags, p.flags)
+}
+
+func (p *media_linkDesc) size() int {
+	return size_linkDesc
+}
+
+// Getters and putters for built-in types.
+
+func getUint64(base unsafe.Pointer, offset int) uint64 {
+	ptr := (*uint64)(unsafe.Pointer(uintptr(base) + uintptr(offset)))
+	return *ptr
+}
+
+func putUint64(base unsafe.Pointer, offset int, value uint64) {
+	ptr := (*uint64)(unsafe.Pointer(uintptr(base) + uintptr(offset)))
+	*ptr = value
+}
+
+func getUint32(base unsafe.Pointer, offset int) uint32 {
+	ptr := (*uint32)(unsafe.Pointer(uintptr(base) + uintptr(offset)))
+	return *ptr
+}
+
+func putUint32(base unsafe.Pointer, offset int, value uint32) {
+	ptr := (*uint32)(unsafe.Pointer(uintptr(base) + uintptr(offset)))
+	*ptr = value
+}
+
+func getUint16(base unsafe.Pointer, offset int) uint16 {
+	ptr := (*uint16)(unsafe.Pointer(uintptr(base) + uintptr(offset)))
+	return *ptr
+}
+
+func putUint16(base unsafe.Pointer, offset int, value uint16) {
+	ptr := (*uint16)(unsafe.Pointer(uintptr(base) + uintptr(offset)))
+	*ptr = value
+}
+
+func getString(base unsafe.Pointer, offset, maxLen int) string {
+	buf := make([]byte, 0, maxLen)
+	for i := 0; i < maxLen; i++ {
+		ptr := (*byte)(unsafe.Pointer(uintptr(base) + uintptr(offset+i)))
+		ch := *ptr
+		if ch == 0 {
+			break
+		}
+		buf = append(buf, ch)
+	}
+	return string(buf)
+}
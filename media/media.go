@@ -0,0 +1,575 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+// Package media provides access to the Linux media controller API.
+//
+// Camera pipelines on SoCs are made up of several hardware blocks (sensor, CSI
+// receiver, ISP, etc.), each of which is represented by an entity in the media
+// graph. Before the video node at the end of the pipeline can deliver frames,
+// the links between the entities usually need to be set up. This package lets
+// you inspect the graph, enable and disable links, and find the device nodes
+// (/dev/videoN and /dev/v4l-subdevN) that belong to the entities.
+package media
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+// An Error is simply an error message.
+type Error string
+
+// Error returns e as a string.
+func (e Error) Error() string {
+	return string(e)
+}
+
+const (
+	// ErrWrongDevice is returned by Open when attempting to open a file that
+	// is not a media controller device.
+	ErrWrongDevice = Error("not a media controller device")
+
+	// ErrNotDataLink is returned by SetupLink for links other than data links.
+	ErrNotDataLink = Error("not a data link")
+
+	// ErrTopologyUnstable is returned by Topology when the graph keeps
+	// changing while it's being read.
+	ErrTopologyUnstable = Error("media graph keeps changing")
+)
+
+// An OpError records a failed operation on a device, like the OpError of the
+// v4l package. The underlying error can be tested with errors.Is.
+type OpError struct {
+	// Op is the operation that failed. It's usually the name of an IOCTL.
+	// (e.g. "MEDIA_IOC_G_TOPOLOGY")
+	Op string
+
+	// Path is the device path. (e.g. /dev/media0)
+	Path string
+
+	// Err is the underlying error, usually a syscall.Errno.
+	Err error
+}
+
+// Error returns e as a string. (e.g. "MEDIA_IOC_SETUP_LINK /dev/media0:
+// device or resource busy")
+func (e *OpError) Error() string {
+	if e.Path == "" {
+		return e.Op + ": " + e.Err.Error()
+	}
+	return e.Op + " " + e.Path + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *OpError) Unwrap() error {
+	return e.Err
+}
+
+// withPath sets the Path of err to path, if err is an OpError without one.
+func withPath(err error, path string) error {
+	if e, ok := err.(*OpError); ok && e.Path == "" {
+		e.Path = path
+	}
+	return err
+}
+
+// Entity functions. Drivers may report other functions than these.
+const (
+	FuncUnknown        = 0x00000000
+	FuncIOV4L          = 0x00010001
+	FuncCamSensor      = 0x00020001
+	FuncFlash          = 0x00020002
+	FuncLens           = 0x00020003
+	FuncVidIFBridge    = 0x00005002
+	FuncVidMux         = 0x00005001
+	FuncProcScaler     = 0x00004005
+	FuncProcStatistics = 0x00004006
+	FuncProcISP        = 0x00004009
+)
+
+// Entity flags.
+const (
+	// EntityDefault marks the default entity of its kind, e.g. the main video
+	// capture node.
+	EntityDefault = 1 << 0
+
+	// EntityConnector marks entities representing physical connectors.
+	EntityConnector = 1 << 1
+)
+
+// Interface types.
+const (
+	IntfV4LVideo  = 0x00000200
+	IntfV4LVBI    = 0x00000201
+	IntfV4LRadio  = 0x00000202
+	IntfV4LSubdev = 0x00000203
+	IntfV4LTouch  = 0x00000205
+)
+
+// Pad flags.
+const (
+	PadSink        = 1 << 0
+	PadSource      = 1 << 1
+	PadMustConnect = 1 << 2
+)
+
+// Link flags.
+const (
+	LinkEnabled   = 1 << 0
+	LinkImmutable = 1 << 1
+	LinkDynamic   = 1 << 2
+
+	// The link type is stored in the upper bits of the flags.
+	LinkTypeMask      = 0xf << 28
+	LinkTypeData      = 0 << 28
+	LinkTypeInterface = 1 << 28
+	LinkTypeAncillary = 2 << 28
+)
+
+// A Device is an open media controller device.
+type Device struct {
+	*device
+}
+
+// device is the real representation of Device.
+type device struct {
+	path string
+	fd   int
+}
+
+// A DeviceInfo provides information about a media controller device.
+type DeviceInfo struct {
+	// Path is the device path. (e.g. /dev/media0)
+	Path string
+
+	// Driver is the name of the driver. (e.g. "uvcvideo")
+	Driver string
+
+	// Model is the device model name. (e.g. "HD Pro Webcam C920")
+	Model string
+
+	// Serial is the serial number of the device, if known.
+	Serial string
+
+	// BusInfo is the location of the device in the system.
+	// (e.g. "usb-0000:00:14.0-1")
+	BusInfo string
+
+	// HWRevision is the hardware revision in a driver specific format.
+	HWRevision uint32
+
+	// DriverVersion contains the three components of the driver's version
+	// number.
+	DriverVersion [3]int
+}
+
+// A Topology is a snapshot of the media graph.
+type Topology struct {
+	// Version is incremented by the kernel every time the graph changes.
+	Version uint64
+
+	Entities   []Entity
+	Interfaces []Interface
+	Pads       []Pad
+	Links      []Link
+}
+
+// An Entity is a hardware block or a device node in the media graph.
+type Entity struct {
+	// ID identifies the entity within the graph.
+	ID uint32
+
+	// Name is the unique name of the entity. (e.g. "imx219 10-0010")
+	Name string
+
+	// Function tells what the entity does. (e.g. FuncCamSensor)
+	Function uint32
+
+	// Flags holds the entity flags. (e.g. EntityDefault)
+	Flags uint32
+
+	// Path is the device node through which the entity can be controlled
+	// (e.g. /dev/video0 or /dev/v4l-subdev2), or "" if there is none.
+	Path string
+}
+
+// An Interface is a device node through which entities can be controlled.
+type Interface struct {
+	// ID identifies the interface within the graph.
+	ID uint32
+
+	// Type is the type of the interface. (e.g. IntfV4LSubdev)
+	Type uint32
+
+	// Flags is reserved for future use.
+	Flags uint32
+
+	// Major and Minor are the device number of the device node.
+	Major uint32
+	Minor uint32
+
+	// Path is the device node, or "" if it can't be found.
+	Path string
+}
+
+// A Pad is a connection endpoint of an entity.
+type Pad struct {
+	// ID identifies the pad within the graph.
+	ID uint32
+
+	// EntityID is the ID of the entity the pad belongs to.
+	EntityID uint32
+
+	// Flags tells the direction of the pad. (PadSink or PadSource)
+	Flags uint32
+
+	// Index is the number of the pad within its entity.
+	Index uint32
+}
+
+// A Link connects either two pads (data links), an interface and an entity
+// (interface links), or two entities (ancillary links).
+type Link struct {
+	// ID identifies the link within the graph.
+	ID uint32
+
+	// SourceID and SinkID identify the two ends of the link. For data links
+	// they are pad IDs, for interface links they are an interface and an
+	// entity ID, and for ancillary links they are entity IDs.
+	SourceID uint32
+	SinkID   uint32
+
+	// Flags holds the link flags and the link type.
+	Flags uint32
+}
+
+// Type returns the type of l. (LinkTypeData, LinkTypeInterface, or
+// LinkTypeAncillary)
+func (l Link) Type() uint32 {
+	return l.Flags & LinkTypeMask
+}
+
+// Enabled tells if the link is enabled.
+func (l Link) Enabled() bool {
+	return l.Flags&LinkEnabled != 0
+}
+
+// Open opens the media controller device named by path.
+func Open(path string) (*Device, error) {
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &OpError{Op: "open", Path: path, Err: err}
+	}
+	var info media_deviceInfo
+	if err := ioctl_deviceInfo(fd, &info); err != nil {
+		syscall.Close(fd)
+		if errors.Is(err, syscall.ENOTTY) {
+			return nil, ErrWrongDevice
+		}
+		return nil, withPath(err, path)
+	}
+	return &Device{&device{path: path, fd: fd}}, nil
+}
+
+// Close closes the device.
+func (d *Device) Close() {
+	syscall.Close(d.fd)
+	d.fd = -1
+}
+
+// DeviceInfo returns information about the device.
+func (d *Device) DeviceInfo() (DeviceInfo, error) {
+	var mi media_deviceInfo
+	if err := ioctl_deviceInfo(d.fd, &mi); err != nil {
+		return DeviceInfo{}, withPath(err, d.path)
+	}
+	info := DeviceInfo{
+		Path:       d.path,
+		Driver:     mi.driver,
+		Model:      mi.model,
+		Serial:     mi.serial,
+		BusInfo:    mi.busInfo,
+		HWRevision: mi.hwRevision,
+		DriverVersion: [3]int{
+			int(mi.driverVersion>>16) & 0xff,
+			int(mi.driverVersion>>8) & 0xff,
+			int(mi.driverVersion) & 0xff,
+		},
+	}
+	return info, nil
+}
+
+// topologyRetries is how many times Topology tries to read a changing graph.
+const topologyRetries = 10
+
+// gTopology is ioctl_gTopology. It's a variable so that tests can replace it.
+var gTopology = ioctl_gTopology
+
+// Topology returns the current media graph.
+func (d *Device) Topology() (*Topology, error) {
+	for i := 0; i < topologyRetries; i++ {
+		// Get the number of elements.
+		var t media_topology
+		if err := gTopology(d.fd, &t); err != nil {
+			return nil, withPath(err, d.path)
+		}
+		version := t.topologyVersion
+		n := [4]uint32{t.numEntities, t.numInterfaces, t.numPads, t.numLinks}
+
+		// Get the elements.
+		t.entities = make([]byte, int(t.numEntities)*size_entity)
+		t.interfaces = make([]byte, int(t.numInterfaces)*size_interface)
+		t.pads = make([]byte, int(t.numPads)*size_pad)
+		t.links = make([]byte, int(t.numLinks)*size_link)
+		err := gTopology(d.fd, &t)
+		if errors.Is(err, syscall.ENOSPC) {
+			// Some array was too small, as the graph grew. Try again.
+			continue
+		}
+		if err != nil {
+			return nil, withPath(err, d.path)
+		}
+		if t.topologyVersion != version ||
+			n != [4]uint32{t.numEntities, t.numInterfaces, t.numPads, t.numLinks} {
+			// The graph changed between the two calls. Try again.
+			continue
+		}
+		return newTopology(&t), nil
+	}
+	return nil, &OpError{Op: "MEDIA_IOC_G_TOPOLOGY", Path: d.path, Err: ErrTopologyUnstable}
+}
+
+// newTopology decodes the arrays filled in by MEDIA_IOC_G_TOPOLOGY.
+func newTopology(t *media_topology) *Topology {
+	topo := &Topology{Version: t.topologyVersion}
+	for i := 0; i < int(t.numEntities); i++ {
+		var e media_entity
+		e.get(elem(t.entities, i, size_entity))
+		topo.Entities = append(topo.Entities, Entity{
+			ID:       e.id,
+			Name:     e.name,
+			Function: e.function,
+			Flags:    e.flags,
+		})
+	}
+	for i := 0; i < int(t.numInterfaces); i++ {
+		var f media_interface
+		f.get(elem(t.interfaces, i, size_interface))
+		topo.Interfaces = append(topo.Interfaces, Interface{
+			ID:    f.id,
+			Type:  f.intfType,
+			Flags: f.flags,
+			Major: f.major,
+			Minor: f.minor,
+			Path:  devnodePath(f.major, f.minor),
+		})
+	}
+	for i := 0; i < int(t.numPads); i++ {
+		var p media_pad
+		p.get(elem(t.pads, i, size_pad))
+		topo.Pads = append(topo.Pads, Pad{
+			ID:       p.id,
+			EntityID: p.entityID,
+			Flags:    p.flags,
+			Index:    p.index,
+		})
+	}
+	for i := 0; i < int(t.numLinks); i++ {
+		var l media_link
+		l.get(elem(t.links, i, size_link))
+		topo.Links = append(topo.Links, Link{
+			ID:       l.id,
+			SourceID: l.sourceID,
+			SinkID:   l.sinkID,
+			Flags:    l.flags,
+		})
+	}
+	topo.resolvePaths()
+	return topo
+}
+
+// resolvePaths sets the Path of every entity that is connected to an
+// interface.
+func (t *Topology) resolvePaths() {
+	for _, l := range t.Links {
+		if l.Type() != LinkTypeInterface {
+			continue
+		}
+		f := t.Interface(l.SourceID)
+		e := t.Entity(l.SinkID)
+		if f != nil && e != nil && e.Path == "" {
+			e.Path = f.Path
+		}
+	}
+}
+
+// Entity returns the entity with the given ID, or nil if there is none.
+func (t *Topology) Entity(id uint32) *Entity {
+	for i := range t.Entities {
+		if t.Entities[i].ID == id {
+			return &t.Entities[i]
+		}
+	}
+	return nil
+}
+
+// EntityByName returns the entity with the given name, or nil if there is none.
+func (t *Topology) EntityByName(name string) *Entity {
+	for i := range t.Entities {
+		if t.Entities[i].Name == name {
+			return &t.Entities[i]
+		}
+	}
+	return nil
+}
+
+// Interface returns the interface with the given ID, or nil if there is none.
+func (t *Topology) Interface(id uint32) *Interface {
+	for i := range t.Interfaces {
+		if t.Interfaces[i].ID == id {
+			return &t.Interfaces[i]
+		}
+	}
+	return nil
+}
+
+// Pad returns the pad with the given ID, or nil if there is none.
+func (t *Topology) Pad(id uint32) *Pad {
+	for i := range t.Pads {
+		if t.Pads[i].ID == id {
+			return &t.Pads[i]
+		}
+	}
+	return nil
+}
+
+// EntityPad returns the pad of the entity with the given index, or nil if there
+// is none.
+func (t *Topology) EntityPad(entityID, index uint32) *Pad {
+	for i := range t.Pads {
+		if t.Pads[i].EntityID == entityID && t.Pads[i].Index == index {
+			return &t.Pads[i]
+		}
+	}
+	return nil
+}
+
+// DataLinks returns the data links of the entity, both incoming and outgoing.
+func (t *Topology) DataLinks(entityID uint32) []Link {
+	var links []Link
+	for _, l := range t.Links {
+		if l.Type() != LinkTypeData {
+			continue
+		}
+		src, sink := t.Pad(l.SourceID), t.Pad(l.SinkID)
+		if src != nil && src.EntityID == entityID ||
+			sink != nil && sink.EntityID == entityID {
+			links = append(links, l)
+		}
+	}
+	return links
+}
+
+// FindDataLink returns the data link going from pad sourcePad of entity source
+// to pad sinkPad of entity sink, or nil if there is none.
+func (t *Topology) FindDataLink(source, sourcePad, sink, sinkPad uint32) *Link {
+	src, dst := t.EntityPad(source, sourcePad), t.EntityPad(sink, sinkPad)
+	if src == nil || dst == nil {
+		return nil
+	}
+	for i := range t.Links {
+		l := &t.Links[i]
+		if l.Type() == LinkTypeData && l.SourceID == src.ID && l.SinkID == dst.ID {
+			return l
+		}
+	}
+	return nil
+}
+
+// SetupLink enables or disables a data link. Immutable links cannot be
+// changed, and some drivers don't allow changing links while streaming. The
+// topology t is used to look up the pads at the two ends of the link.
+func (d *Device) SetupLink(t *Topology, l Link, enable bool) error {
+	if l.Type() != LinkTypeData {
+		return ErrNotDataLink
+	}
+	src, sink := t.Pad(l.SourceID), t.Pad(l.SinkID)
+	if src == nil || sink == nil {
+		return &OpError{Op: "MEDIA_IOC_SETUP_LINK", Path: d.path, Err: syscall.EINVAL}
+	}
+	ld := media_linkDesc{
+		source: media_padDesc{
+			entity: src.EntityID,
+			index:  uint16(src.Index),
+			flags:  src.Flags,
+		},
+		sink: media_padDesc{
+			entity: sink.EntityID,
+			index:  uint16(sink.Index),
+			flags:  sink.Flags,
+		},
+		flags: l.Flags &^ (LinkEnabled | LinkTypeMask),
+	}
+	if enable {
+		ld.flags |= LinkEnabled
+	}
+	return withPath(ioctl_setupLink(d.fd, &ld), d.path)
+}
+
+// FindDevices returns the paths of all media controller devices in the system.
+func FindDevices() []string {
+	paths, _ := filepath.Glob(filepath.Join(devRoot, "media[0-9]*"))
+	return paths
+}
+
+// sysfsRoot and devRoot are where sysfs and the device nodes are found.
+var (
+	sysfsRoot = "/sys"
+	devRoot   = "/dev"
+)
+
+// devnodePath returns the path of the character device major:minor, or "" if
+// it can't be found.
+func devnodePath(major, minor uint32) string {
+	if major == 0 && minor == 0 {
+		return ""
+	}
+	uevent := filepath.Join(sysfsRoot, "dev", "char",
+		strconv.Itoa(int(major))+":"+strconv.Itoa(int(minor)), "uevent")
+	b, err := ioutil.ReadFile(uevent)
+	if err != nil {
+		return ""
+	}
+	for _, line := range strings.Split(string(b), "\n") {
+		if strings.HasPrefix(line, "DEVNAME=") {
+			return filepath.Join(devRoot, line[len("DEVNAME="):])
+		}
+	}
+	return ""
+}
+
+// elem returns a pointer to the ith element of an array of structs.
+func elem(b []byte, i, size int) unsafe.Pointer {
+	return unsafe.Pointer(&b[i*size])
+}
@@ -0,0 +1,222 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package media
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"unsafe"
+)
+
+// A sensor (entity 1) connected to a CSI receiver (entity 2) connected to a
+// video node (entity 3). The sensor and the video node have interfaces.
+var testTopology = media_topology{
+	topologyVersion: 7,
+	numEntities:     3,
+	entities: concat(size_entity,
+		entity(1, "imx219 10-0010", FuncCamSensor),
+		entity(2, "csi2", FuncVidIFBridge),
+		entity(3, "capture", FuncIOV4L),
+	),
+	numInterfaces: 2,
+	interfaces: concat(size_interface,
+		intf(10, IntfV4LSubdev),
+		intf(11, IntfV4LVideo),
+	),
+	numPads: 4,
+	pads: concat(size_pad,
+		pad(20, 1, PadSource, 0),
+		pad(21, 2, PadSink, 0),
+		pad(22, 2, PadSource, 1),
+		pad(23, 3, PadSink, 0),
+	),
+	numLinks: 4,
+	links: concat(size_link,
+		link(30, 20, 21, LinkEnabled|LinkImmutable),
+		link(31, 22, 23, 0),
+		link(32, 10, 1, LinkTypeInterface|LinkEnabled),
+		link(33, 11, 3, LinkTypeInterface|LinkEnabled),
+	),
+}
+
+func TestTopology(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	defer func(s, d string) { sysfsRoot, devRoot = s, d }(sysfsRoot, devRoot)
+	sysfsRoot = filepath.Join(tmp, "sys")
+	devRoot = filepath.Join(tmp, "dev")
+	for name, data := range map[string]string{
+		"81:10": "MAJOR=81\nMINOR=10\nDEVNAME=v4l-subdev0\n",
+		"81:11": "MAJOR=81\nMINOR=11\nDEVNAME=video0\n",
+	} {
+		dir := filepath.Join(sysfsRoot, "dev", "char", name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		err := ioutil.WriteFile(filepath.Join(dir, "uevent"), []byte(data), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	topo := newTopology(&testTopology)
+
+	if topo.Version != 7 || len(topo.Entities) != 3 || len(topo.Interfaces) != 2 ||
+		len(topo.Pads) != 4 || len(topo.Links) != 4 {
+		t.Errorf("bad topology: %+v\n", topo)
+		return
+	}
+	e := topo.EntityByName("csi2")
+	if e == nil || e.ID != 2 || e.Function != FuncVidIFBridge {
+		t.Errorf("bad entity: %+v\n", e)
+		return
+	}
+	if p := topo.EntityPad(2, 1); p == nil || p.ID != 22 {
+		t.Errorf("bad pad: %+v\n", p)
+	}
+	if links := topo.DataLinks(2); len(links) != 2 {
+		t.Errorf("got %d data links, expected: 2\n", len(links))
+	}
+	l := topo.FindDataLink(2, 1, 3, 0)
+	if l == nil || l.ID != 31 || l.Enabled() || l.Type() != LinkTypeData {
+		t.Errorf("bad link: %+v\n", l)
+	}
+	if l := topo.FindDataLink(1, 0, 3, 0); l != nil {
+		t.Errorf("found non-existent link: %+v\n", l)
+	}
+	for _, x := range []struct {
+		id   uint32
+		path string
+	}{
+		{1, "v4l-subdev0"},
+		{2, ""},
+		{3, "video0"},
+	} {
+		path := ""
+		if x.path != "" {
+			path = filepath.Join(devRoot, x.path)
+		}
+		if e := topo.Entity(x.id); e.Path != path {
+			t.Errorf("got: %q, expected: %q (id=%d)\n", e.Path, path, x.id)
+		}
+	}
+}
+
+func concat(size int, elems ...[]byte) []byte {
+	var b []byte
+	for _, e := range elems {
+		b = append(b, e[:size]...)
+	}
+	return b
+}
+
+func entity(id uint32, name string, function uint32) []byte {
+	b := make([]byte, size_entity)
+	q := unsafe.Pointer(&b[0])
+	putUint32(q, offs_entity_id, id)
+	copy(b[offs_entity_name:offs_entity_name+size_entity_name-1], name)
+	putUint32(q, offs_entity_function, function)
+	return b
+}
+
+func intf(id, typ uint32) []byte {
+	b := make([]byte, size_interface)
+	q := unsafe.Pointer(&b[0])
+	putUint32(q, offs_interface_id, id)
+	putUint32(q, offs_interface_intfType, typ)
+	putUint32(q, offs_interface_devnode_major, 81)
+	putUint32(q, offs_interface_devnode_minor, id)
+	return b
+}
+
+func pad(id, entityID, flags, index uint32) []byte {
+	b := make([]byte, size_pad)
+	q := unsafe.Pointer(&b[0])
+	putUint32(q, offs_pad_id, id)
+	putUint32(q, offs_pad_entityID, entityID)
+	putUint32(q, offs_pad_flags, flags)
+	putUint32(q, offs_pad_index, index)
+	return b
+}
+
+func link(id, source, sink, flags uint32) []byte {
+	b := make([]byte, size_link)
+	q := unsafe.Pointer(&b[0])
+	putUint32(q, offs_link_id, id)
+	putUint32(q, offs_link_sourceID, source)
+	putUint32(q, offs_link_sinkID, sink)
+	putUint32(q, offs_link_flags, flags)
+	return b
+}
+
+func TestOpenErrors(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "media")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	path := filepath.Join(tmp, "media0")
+	_, err = Open(path)
+	var e *OpError
+	if !errors.As(err, &e) || e.Op != "open" || e.Path != path ||
+		!errors.Is(err, syscall.ENOENT) {
+		t.Errorf("got: %v, expected an open error with ENOENT\n", err)
+	}
+
+	if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Open(path); err != ErrWrongDevice {
+		t.Errorf("got: %v, expected: %v\n", err, ErrWrongDevice)
+	}
+
+	d := &Device{&device{path: path, fd: -1}}
+	_, err = d.Topology()
+	if !errors.As(err, &e) || e.Op != "MEDIA_IOC_G_TOPOLOGY" || e.Path != path ||
+		!errors.Is(err, syscall.EBADF) {
+		t.Errorf("got: %v, expected a MEDIA_IOC_G_TOPOLOGY error with EBADF\n", err)
+	}
+}
+
+func TestTopologyUnstable(t *testing.T) {
+	defer func(f func(int, *media_topology) error) { gTopology = f }(gTopology)
+	version := uint64(0)
+	gTopology = func(fd int, argp *media_topology) error {
+		version++
+		argp.topologyVersion = version
+		return nil
+	}
+
+	d := &Device{&device{path: "media0", fd: -1}}
+	if _, err := d.Topology(); !errors.Is(err, ErrTopologyUnstable) {
+		t.Errorf("got: %v, expected: %v\n", err, ErrTopologyUnstable)
+	}
+	if version != 2*topologyRetries {
+		t.Errorf("read the topology %d times, expected %d\n", version, 2*topologyRetries)
+	}
+}
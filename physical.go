@@ -0,0 +1,324 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"github.com/korandiz/v4l/media"
+)
+
+// A PhysicalDevice is a group of device nodes that belong to the same piece of
+// hardware. A single UVC camera, for example, typically has a video capture
+// node and a metadata capture node, while a camera pipeline on a SoC may have
+// many video nodes and sub-devices behind a single media controller.
+type PhysicalDevice struct {
+	// Name is the name of the device. (e.g. "HD Pro Webcam C920")
+	Name string
+
+	// BusInfo is the location of the device in the system, as reported by the
+	// driver. (e.g. "usb-0000:00:14.0-1")
+	BusInfo string
+
+	// MediaNode is the path of the media controller device, if there is one.
+	// (e.g. /dev/media0)
+	MediaNode string
+
+	// Serial and Port are the same as in DeviceInfo.
+	Serial string
+	Port   string
+
+	// Nodes lists the device nodes, ordered by their index, with sub-devices
+	// after video nodes.
+	Nodes []NodeInfo
+}
+
+// A NodeInfo describes a device node of a PhysicalDevice.
+type NodeInfo struct {
+	// Path is the device path. (e.g. /dev/video1)
+	Path string
+
+	// Type tells what the node is for. It's one of "capture",
+	// "capture-mplane" (multi-planar capture), "metadata", "output",
+	// "metadata-output", "m2m" (memory-to-memory, e.g. a hardware codec),
+	// "subdev", or "other". Only "capture" nodes can be passed to Open.
+	Type string
+
+	// Index is the same as in DeviceInfo.
+	Index int
+}
+
+// NodesOfType returns the nodes of p that are of the given type.
+func (p *PhysicalDevice) NodesOfType(typ string) []NodeInfo {
+	var nodes []NodeInfo
+	for _, n := range p.Nodes {
+		if n.Type == typ {
+			nodes = append(nodes, n)
+		}
+	}
+	return nodes
+}
+
+// CapturePath returns the path of the main video capture node, or "" if the
+// device has none.
+func (p *PhysicalDevice) CapturePath() string {
+	if nodes := p.NodesOfType("capture"); len(nodes) > 0 {
+		return nodes[0].Path
+	}
+	return ""
+}
+
+// FindPhysicalDevices returns every device found in the system that has at
+// least one video4linux node, with the nodes grouped by the hardware they
+// belong to. Nodes are grouped together if they are part of the topology of
+// the same media controller, or failing that, if their drivers report the same
+// bus info.
+func FindPhysicalDevices() []PhysicalDevice {
+	dir := filepath.Join(sysfsRoot, "class", "video4linux")
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+	var nodes []physNode
+	for _, e := range entries {
+		n, ok := probeNode(filepath.Join(dir, e.Name()), e.Name())
+		if ok {
+			nodes = append(nodes, n)
+		}
+	}
+	return groupNodes(nodes, mediaGroups())
+}
+
+// FindPhysicalDevice returns the PhysicalDevice the node at path belongs to.
+// It fails with ErrNoDevice if path is not a video4linux device node.
+func FindPhysicalDevice(path string) (PhysicalDevice, error) {
+	target, err := filepath.EvalSymlinks(path)
+	if err != nil {
+		return PhysicalDevice{}, err
+	}
+	for _, p := range FindPhysicalDevices() {
+		for _, n := range p.Nodes {
+			if dst, err := filepath.EvalSymlinks(n.Path); err == nil && dst == target {
+				return p, nil
+			}
+		}
+	}
+	return PhysicalDevice{}, ErrNoDevice
+}
+
+// physNode holds everything needed to group a node.
+type physNode struct {
+	NodeInfo
+	dev     devNum
+	name    string
+	busInfo string
+	info    DeviceInfo
+}
+
+// probeNode collects information about the node described by the sysfs
+// directory dir.
+func probeNode(dir, name string) (physNode, bool) {
+	var major, minor int
+	dev := strings.SplitN(readSysfsString(filepath.Join(dir, "dev")), ":", 2)
+	if len(dev) != 2 {
+		return physNode{}, false
+	}
+	major, _ = strconv.Atoi(dev[0])
+	minor, _ = strconv.Atoi(dev[1])
+
+	n := physNode{
+		NodeInfo: NodeInfo{
+			Path: filepath.Join(devRoot, name),
+			Type: "other",
+		},
+		dev:  devNum{major, minor},
+		name: readSysfsString(filepath.Join(dir, "name")),
+		info: DeviceInfo{Path: filepath.Join(devRoot, name)},
+	}
+	readSysfsInfo(&n.info, major, minor)
+	n.Index = n.info.Index
+
+	if strings.HasPrefix(name, "v4l-subdev") {
+		n.Type = "subdev"
+		return n, true
+	}
+
+	fd, err := syscall.Open(n.Path, syscall.O_RDWR|syscall.O_NONBLOCK|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return n, true
+	}
+	defer syscall.Close(fd)
+	var c v4l_capability
+	if err := ioctl_querycap(fileBackend(fd), &c); err != nil {
+		return n, true
+	}
+	n.name = c.card
+	n.busInfo = c.busInfo
+	caps := c.capabilities
+	if caps&v4l_capDeviceCaps != 0 {
+		caps = c.deviceCaps
+	}
+	n.Type = nodeType(caps)
+	return n, true
+}
+
+// nodeType classifies a node based on its device capabilities.
+func nodeType(caps uint32) string {
+	switch {
+	case caps&(v4l_capVideoM2M|v4l_capVideoM2MMplane) != 0:
+		return "m2m"
+	case caps&v4l_capVideoCapture != 0:
+		return "capture"
+	case caps&v4l_capVideoCaptureMplane != 0:
+		return "capture-mplane"
+	case caps&v4l_capMetaCapture != 0:
+		return "metadata"
+	case caps&(v4l_capVideoOutput|v4l_capVideoOutputMplane) != 0:
+		return "output"
+	case caps&v4l_capMetaOutput != 0:
+		return "metadata-output"
+	default:
+		return "other"
+	}
+}
+
+// A devNum is the device number of a node.
+type devNum struct {
+	major, minor int
+}
+
+// mediaGroups maps the device number of every interface of every media
+// controller in the system to the path of the controller.
+func mediaGroups() map[devNum]string {
+	groups := make(map[devNum]string)
+	for _, path := range media.FindDevices() {
+		d, err := media.Open(path)
+		if err != nil {
+			continue
+		}
+		t, err := d.Topology()
+		d.Close()
+		if err == nil {
+			addTopology(groups, path, t)
+		}
+	}
+	return groups
+}
+
+// addTopology adds the interfaces of the media controller at path to groups.
+func addTopology(groups map[devNum]string, path string, t *media.Topology) {
+	for _, f := range t.Interfaces {
+		groups[devNum{int(f.Major), int(f.Minor)}] = path
+	}
+}
+
+// groupNodes groups the nodes into physical devices. Nodes whose device number
+// is in mediaGroups go to the group of the media controller.
+func groupNodes(nodes []physNode, mediaGroups map[devNum]string) []PhysicalDevice {
+	var (
+		devs  []PhysicalDevice
+		named []bool // name taken from a sub-device
+		index = make(map[string]int)
+	)
+	for _, n := range nodes {
+		var key string
+		if m, ok := mediaGroups[n.dev]; ok {
+			n.info.MediaNode = m
+		}
+		switch {
+		case n.info.MediaNode != "":
+			key = "media:" + n.info.MediaNode
+		case n.busInfo != "":
+			key = "bus:" + n.busInfo
+		default:
+			key = "node:" + n.Path
+		}
+		i, ok := index[key]
+		if !ok {
+			i = len(devs)
+			index[key] = i
+			devs = append(devs, PhysicalDevice{})
+			named = append(named, false)
+		}
+		p := &devs[i]
+		if p.Name == "" || named[i] && n.Type != "subdev" {
+			// Prefer the name of a video node over that of a sensor.
+			p.Name = n.name
+			named[i] = n.Type == "subdev"
+		}
+		if p.BusInfo == "" {
+			p.BusInfo = n.busInfo
+		}
+		if p.MediaNode == "" {
+			p.MediaNode = n.info.MediaNode
+		}
+		if p.Serial == "" {
+			p.Serial = n.info.Serial
+		}
+		if p.Port == "" {
+			p.Port = n.info.Port
+		}
+		p.Nodes = append(p.Nodes, n.NodeInfo)
+	}
+
+	for i := range devs {
+		nodes := devs[i].Nodes
+		sort.Slice(nodes, func(i, j int) bool {
+			si, sj := nodes[i].Type == "subdev", nodes[j].Type == "subdev"
+			if si != sj {
+				return sj
+			}
+			if nodes[i].Index != nodes[j].Index {
+				return nodes[i].Index < nodes[j].Index
+			}
+			return lessPath(nodes[i].Path, nodes[j].Path)
+		})
+	}
+	sort.Slice(devs, func(i, j int) bool {
+		return lessPath(devs[i].Nodes[0].Path, devs[j].Nodes[0].Path)
+	})
+	return devs
+}
+
+// lessPath compares device paths so that numbered nodes are ordered
+// numerically. (e.g. /dev/video2 < /dev/video10)
+func lessPath(a, b string) bool {
+	pa, na := splitNumber(a)
+	pb, nb := splitNumber(b)
+	if pa != pb {
+		return pa < pb
+	}
+	return na < nb
+}
+
+// splitNumber splits the trailing decimal number off s.
+func splitNumber(s string) (string, int) {
+	i := len(s)
+	for i > 0 && s[i-1] >= '0' && s[i-1] <= '9' {
+		i--
+	}
+	n, _ := strconv.Atoi(s[i:])
+	return s[:i], n
+}
@@ -0,0 +1,158 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/korandiz/v4l/media"
+)
+
+func TestGroupNodes(t *testing.T) {
+	node := func(path, typ string, index int, name, bus, media string) physNode {
+		return physNode{
+			NodeInfo: NodeInfo{Path: path, Type: typ, Index: index},
+			name:     name,
+			busInfo:  bus,
+			info:     DeviceInfo{Path: path, Index: index, MediaNode: media},
+		}
+	}
+	nodes := []physNode{
+		node("/dev/v4l-subdev0", "subdev", 0, "imx219", "", "/dev/media1"),
+		node("/dev/video11", "metadata", 1, "Cam B", "usb-0000:00:14.0-2", "/dev/media1"),
+		node("/dev/video2", "metadata", 1, "Cam A", "usb-0000:00:14.0-1", ""),
+		node("/dev/video1", "capture", 0, "Cam A", "usb-0000:00:14.0-1", ""),
+		node("/dev/video10", "capture", 0, "Cam B", "usb-0000:00:14.0-2", "/dev/media1"),
+		node("/dev/video3", "other", 0, "", "", ""),
+	}
+	expected := []PhysicalDevice{
+		{
+			Name:    "Cam A",
+			BusInfo: "usb-0000:00:14.0-1",
+			Nodes: []NodeInfo{
+				{"/dev/video1", "capture", 0},
+				{"/dev/video2", "metadata", 1},
+			},
+		},
+		{
+			Nodes: []NodeInfo{
+				{"/dev/video3", "other", 0},
+			},
+		},
+		{
+			Name:      "Cam B",
+			BusInfo:   "usb-0000:00:14.0-2",
+			MediaNode: "/dev/media1",
+			Nodes: []NodeInfo{
+				{"/dev/video10", "capture", 0},
+				{"/dev/video11", "metadata", 1},
+				{"/dev/v4l-subdev0", "subdev", 0},
+			},
+		},
+	}
+	devs := groupNodes(nodes, nil)
+	if !reflect.DeepEqual(devs, expected) {
+		t.Errorf("got: %+v, expected: %+v\n", devs, expected)
+		return
+	}
+	if p := devs[2].CapturePath(); p != "/dev/video10" {
+		t.Errorf("got: %q, expected: %q\n", p, "/dev/video10")
+	}
+	if p := devs[1].CapturePath(); p != "" {
+		t.Errorf("got: %q, expected: %q\n", p, "")
+	}
+}
+
+func TestGroupNodesTopology(t *testing.T) {
+	// An ISP pipeline: the sensor and the CSI receiver sit under I2C and
+	// platform parents, so sysfs doesn't link them to the media controller,
+	// and sub-devices have no bus info.
+	node := func(path, typ string, minor int, name, bus string) physNode {
+		return physNode{
+			NodeInfo: NodeInfo{Path: path, Type: typ},
+			dev:      devNum{81, minor},
+			name:     name,
+			busInfo:  bus,
+			info:     DeviceInfo{Path: path},
+		}
+	}
+	nodes := []physNode{
+		node("/dev/v4l-subdev0", "subdev", 3, "imx219 10-0010", ""),
+		node("/dev/v4l-subdev1", "subdev", 4, "csi2", ""),
+		node("/dev/video0", "capture", 0, "unicam-image", "platform:fe801000.csi"),
+		node("/dev/video1", "metadata", 1, "unicam-embedded", "platform:fe801000.csi"),
+		node("/dev/video2", "capture", 2, "UVC Camera", "usb-0000:01:00.0-1"),
+	}
+	groups := make(map[devNum]string)
+	addTopology(groups, "/dev/media0", &media.Topology{
+		Interfaces: []media.Interface{
+			{ID: 1, Major: 81, Minor: 0},
+			{ID: 2, Major: 81, Minor: 1},
+			{ID: 3, Major: 81, Minor: 3},
+			{ID: 4, Major: 81, Minor: 4},
+		},
+	})
+
+	expected := []PhysicalDevice{
+		{
+			Name:      "unicam-image",
+			BusInfo:   "platform:fe801000.csi",
+			MediaNode: "/dev/media0",
+			Nodes: []NodeInfo{
+				{"/dev/video0", "capture", 0},
+				{"/dev/video1", "metadata", 0},
+				{"/dev/v4l-subdev0", "subdev", 0},
+				{"/dev/v4l-subdev1", "subdev", 0},
+			},
+		},
+		{
+			Name:    "UVC Camera",
+			BusInfo: "usb-0000:01:00.0-1",
+			Nodes: []NodeInfo{
+				{"/dev/video2", "capture", 0},
+			},
+		},
+	}
+	if devs := groupNodes(nodes, groups); !reflect.DeepEqual(devs, expected) {
+		t.Errorf("got: %+v, expected: %+v\n", devs, expected)
+	}
+}
+
+func TestNodeType(t *testing.T) {
+	for _, x := range []struct {
+		caps uint32
+		typ  string
+	}{
+		{v4l_capVideoCapture, "capture"},
+		{v4l_capVideoCaptureMplane, "capture-mplane"},
+		{v4l_capVideoCapture | v4l_capVideoCaptureMplane, "capture"},
+		{v4l_capMetaCapture, "metadata"},
+		{v4l_capVideoOutput, "output"},
+		{v4l_capMetaOutput, "metadata-output"},
+		{v4l_capVideoM2M, "m2m"},
+		{v4l_capVideoCapture | v4l_capVideoOutput | v4l_capVideoM2MMplane, "m2m"},
+		{0, "other"},
+	} {
+		if typ := nodeType(x.caps); typ != x.typ {
+			t.Errorf("got: %q, expected: %q (caps=%#x)\n", typ, x.typ, x.caps)
+		}
+	}
+}
@@ -0,0 +1,210 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// Access priorities. Every open handle of a device has a priority, which is
+// PriorityInteractive by default. While some handle has a higher priority than
+// the others, those others can't change the configuration of the device.
+const (
+	// PriorityBackground is for handles that only monitor the device.
+	PriorityBackground = 1
+
+	// PriorityInteractive is the default priority.
+	PriorityInteractive = 2
+
+	// PriorityRecord is for handles that need the configuration to stay the
+	// same, e.g. while recording. Only one handle can have it at a time.
+	PriorityRecord = 3
+)
+
+// Priority returns the access priority of d. (see also DevicePriority)
+func (d *Device) Priority() (prio int, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return 0, err
+	}
+	defer d.mu.Unlock()
+	return d.prio, nil
+}
+
+// DevicePriority returns the highest access priority among all the open
+// handles of the device, including d.
+func (d *Device) DevicePriority() (prio int, err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return 0, err
+	}
+	defer d.mu.Unlock()
+
+	var p v4l_int
+	if err := ioctl_gPriority(d.dev, &p); err != nil {
+		return 0, err
+	}
+	return int(p), nil
+}
+
+// SetPriority sets the access priority of d, which must be one of the Priority
+// constants. Asking for PriorityRecord fails with ErrBusy if some other handle
+// already has it.
+func (d *Device) SetPriority(prio int) (err error) {
+	defer d.annotate(&err)
+	if err := d.lock(); err != nil {
+		return err
+	}
+	defer d.mu.Unlock()
+
+	if err := ioctl_sPriority(d.dev, v4l_int(prio)); err != nil {
+		return err
+	}
+	d.prio = prio
+	d.prevPrio = 0
+	return nil
+}
+
+// SetExclusive turns exclusive mode on or off. In exclusive mode, TurnOn takes
+// PriorityRecord for the duration of the capture session, and fails with
+// ErrBusy if someone else already has it. It takes effect on the next call to
+// TurnOn.
+func (d *Device) SetExclusive(exclusive bool) error {
+	if err := d.lock(); err != nil {
+		return err
+	}
+	defer d.mu.Unlock()
+	d.exclusive = exclusive
+	return nil
+}
+
+// raisePriority switches to PriorityRecord, remembering the previous priority
+// for restorePriority. It must be called with d.mu held.
+//
+// VIDIOC_G_PRIORITY reports the highest priority of all the handles, so it
+// can't tell whether this handle has PriorityRecord. VIDIOC_S_PRIORITY is
+// always issued instead, which fails with EBUSY if some other handle has it.
+func (d *device) raisePriority() error {
+	if err := ioctl_sPriority(d.dev, PriorityRecord); err != nil {
+		return err
+	}
+	if d.prio != PriorityRecord {
+		d.prevPrio = d.prio
+		d.prio = PriorityRecord
+	}
+	return nil
+}
+
+// restorePriority undoes raisePriority, if there is anything to undo. It must
+// be called with d.mu held.
+func (d *device) restorePriority() {
+	if d.prevPrio == 0 {
+		return
+	}
+	if ioctl_sPriority(d.dev, v4l_int(d.prevPrio)) == nil {
+		d.prio = d.prevPrio
+	}
+	d.prevPrio = 0
+}
+
+// A Process is a process that has a device open.
+type Process struct {
+	// PID is the process ID.
+	PID int
+
+	// Command is the name of the executable. (e.g. "ffmpeg")
+	Command string
+}
+
+// procRoot is where procfs is found. It's a variable so that tests can replace
+// it.
+var procRoot = "/proc"
+
+// OpenedBy returns the processes other than the caller that have the device at
+// path open, in order of PID. It's meant for explaining ErrBusy errors. Only the
+// processes whose file descriptors the caller is allowed to inspect are found,
+// so the list may be incomplete unless running as root.
+func OpenedBy(path string) ([]Process, error) {
+	target, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	names, err := readDirNames(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	self := os.Getpid()
+	var procs []Process
+	for _, name := range names {
+		pid, err := strconv.Atoi(name)
+		if err != nil || pid == self {
+			continue
+		}
+		dir := filepath.Join(procRoot, name)
+		fds, err := readDirNames(filepath.Join(dir, "fd"))
+		if err != nil {
+			// The process is gone, or it belongs to somebody else.
+			continue
+		}
+		for _, fd := range fds {
+			fi, err := os.Stat(filepath.Join(dir, "fd", fd))
+			if err != nil || !sameNode(fi, target) {
+				continue
+			}
+			comm, _ := ioutil.ReadFile(filepath.Join(dir, "comm"))
+			procs = append(procs, Process{
+				PID:     pid,
+				Command: strings.TrimSpace(string(comm)),
+			})
+			break
+		}
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].PID < procs[j].PID })
+	return procs, nil
+}
+
+// readDirNames returns the names of the entries of a directory.
+func readDirNames(dir string) ([]string, error) {
+	f, err := os.Open(dir)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return f.Readdirnames(-1)
+}
+
+// sameNode tells if a and b are the same file. Character devices are compared
+// by device number, so that different nodes for the same device match.
+func sameNode(a, b os.FileInfo) bool {
+	sa, ok1 := a.Sys().(*syscall.Stat_t)
+	sb, ok2 := b.Sys().(*syscall.Stat_t)
+	if ok1 && ok2 && a.Mode()&os.ModeCharDevice != 0 &&
+		b.Mode()&os.ModeCharDevice != 0 {
+		return sa.Rdev == sb.Rdev
+	}
+	return os.SameFile(a, b)
+}
@@ -0,0 +1,199 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+	"testing"
+)
+
+func TestExclusive(t *testing.T) {
+	busy := false
+	dev, err := OpenFake(FakeDevice{
+		Configs: fakeConfigs,
+		Err: func(op string) error {
+			if busy && op == "VIDIOC_S_PRIORITY" {
+				return syscall.EBUSY
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+
+	checkPriority := func(when string, expected int) {
+		t.Helper()
+		if p, err := dev.Priority(); err != nil {
+			t.Errorf("Priority %s: %v\n", when, err)
+		} else if p != expected {
+			t.Errorf("Priority %s: got: %d, expected: %d\n", when, p, expected)
+		}
+	}
+
+	checkPriority("after Open", PriorityInteractive)
+	if err := dev.SetExclusive(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+	checkPriority("after TurnOn", PriorityRecord)
+	dev.TurnOff()
+	checkPriority("after TurnOff", PriorityInteractive)
+
+	// A priority set explicitly is kept.
+	if err := dev.SetPriority(PriorityBackground); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+	dev.TurnOff()
+	checkPriority("after second session", PriorityBackground)
+
+	// Someone else is recording.
+	busy = true
+	if err := dev.TurnOn(); !errors.Is(err, ErrBusy) {
+		t.Errorf("TurnOn: got: %v, expected: %v\n", err, ErrBusy)
+	}
+	if _, err := dev.Capture(); err == nil {
+		t.Errorf("Capture succeeded after failed TurnOn\n")
+	}
+
+	// Without exclusive mode, priorities aren't touched.
+	if err := dev.SetExclusive(false); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+	dev.TurnOff()
+}
+
+func TestExclusiveOtherHandle(t *testing.T) {
+	// Someone else is recording. VIDIOC_G_PRIORITY reports their priority.
+	dev, err := OpenFake(FakeDevice{Configs: fakeConfigs, OtherPriority: PriorityRecord})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	if p, err := dev.Priority(); err != nil || p != PriorityInteractive {
+		t.Errorf("Priority: got: %d (%v), expected: %d\n", p, err, PriorityInteractive)
+	}
+	if p, err := dev.DevicePriority(); err != nil || p != PriorityRecord {
+		t.Errorf("DevicePriority: got: %d (%v), expected: %d\n", p, err, PriorityRecord)
+	}
+	if err := dev.SetExclusive(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev.TurnOn(); !errors.Is(err, ErrBusy) {
+		t.Errorf("TurnOn: got: %v, expected: %v\n", err, ErrBusy)
+	}
+
+	// The priority restored is this handle's, not the highest one.
+	dev2, err := OpenFake(FakeDevice{Configs: fakeConfigs, OtherPriority: PriorityInteractive})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev2.Close()
+	if err := dev2.SetPriority(PriorityBackground); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev2.SetExclusive(true); err != nil {
+		t.Fatal(err)
+	}
+	if err := dev2.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+	dev2.TurnOff()
+	if p, err := dev2.Priority(); err != nil || p != PriorityBackground {
+		t.Errorf("Priority: got: %d (%v), expected: %d\n", p, err, PriorityBackground)
+	}
+	if p, err := dev2.DevicePriority(); err != nil || p != PriorityInteractive {
+		t.Errorf("DevicePriority: got: %d (%v), expected: %d\n", p, err, PriorityInteractive)
+	}
+}
+
+func TestOpenedBy(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "v4l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+
+	defer func(p string) { procRoot = p }(procRoot)
+	procRoot = filepath.Join(tmp, "proc")
+
+	video := filepath.Join(tmp, "video0")
+	other := filepath.Join(tmp, "video1")
+	for _, path := range []string{video, other} {
+		if err := ioutil.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	procs := []struct {
+		pid  int
+		comm string
+		fds  []string
+	}{
+		{4242, "ffmpeg", []string{"/dev/null", video, video}},
+		{17, "cheese", []string{video}},
+		{99, "vlc", []string{other}},
+		{os.Getpid(), "self", []string{video}},
+	}
+	for _, p := range procs {
+		dir := filepath.Join(procRoot, strconv.Itoa(p.pid))
+		if err := os.MkdirAll(filepath.Join(dir, "fd"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, "comm"), []byte(p.comm+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		for i, target := range p.fds {
+			if err := os.Symlink(target, filepath.Join(dir, "fd", strconv.Itoa(i))); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(procRoot, "sys"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := OpenedBy(video)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []Process{{17, "cheese"}, {4242, "ffmpeg"}}
+	if len(got) != len(expected) {
+		t.Fatalf("OpenedBy: got: %v, expected: %v\n", got, expected)
+	}
+	for i := range got {
+		if got[i] != expected[i] {
+			t.Errorf("OpenedBy: got: %v, expected: %v\n", got[i], expected[i])
+		}
+	}
+}
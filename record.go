@@ -0,0 +1,377 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"io"
+	"os"
+	"runtime"
+	"syscall"
+	"unsafe"
+)
+
+// A recording starts with recordingMagic, followed by a gob encoded
+// recordingHeader, and one recordingEntry per operation.
+const recordingMagic = "v4l recording\n"
+
+// recordingVersion is the version of the recording format.
+const recordingVersion = 1
+
+type recordingHeader struct {
+	Version int
+	Arch    string
+	Path    string
+}
+
+// Kinds of recording entries.
+const (
+	entryIoctl = 1
+	entrySysfs = 2
+)
+
+// A recordingEntry records an operation. For IOCTLs, In and Out are the raw
+// argument before and after the call; for VIDIOC_DQBUF, Frame is the image
+// data of the dequeued buffer.
+type recordingEntry struct {
+	Kind    int
+	Request uint64
+	In      []byte
+	Out     []byte
+	Errno   uint64
+	Frame   []byte
+	Info    DeviceInfo
+}
+
+// Errors reported by replayed devices.
+const (
+	errRecordingEnd      = Error("end of recording")
+	errRecordingDiverged = Error("replay diverged from the recording")
+)
+
+// OpenAndRecord opens the capture device named by path, just like Open, and
+// records every IOCTL and every captured frame into the file named by
+// recording. Opening the recording with Open later replays the session: the
+// device answers the same requests with the same results and frames, as long
+// as they come in the same order. Recordings can only be replayed on the same
+// architecture.
+//
+// Every operation is written to the recording before it returns, so if
+// writing the recording fails, the operation fails, and so does the device
+// from then on.
+func OpenAndRecord(path, recording string) (*Device, error) {
+	b, err := openFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r, err := newRecordBackend(b, path, recording)
+	if err != nil {
+		return nil, err
+	}
+	return openBackend(path, r)
+}
+
+// newRecordBackend starts recording the operations of b, the backend of the
+// device named by path. It takes ownership of b, even if it fails.
+func newRecordBackend(b backend, path, recording string) (*recordBackend, error) {
+	f, err := os.Create(recording)
+	if err != nil {
+		b.close()
+		return nil, err
+	}
+	r := &recordBackend{
+		b:    b,
+		f:    f,
+		w:    bufio.NewWriter(f),
+		bufs: make(map[int64][]byte),
+	}
+	r.enc = gob.NewEncoder(r.w)
+	r.w.WriteString(recordingMagic)
+	r.err = r.enc.Encode(recordingHeader{recordingVersion, runtime.GOARCH, path})
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	if r.err != nil {
+		r.close()
+		return nil, r.err
+	}
+	return r, nil
+}
+
+// recordBackend records the operations of another backend.
+type recordBackend struct {
+	b    backend
+	f    *os.File
+	w    *bufio.Writer
+	enc  *gob.Encoder
+	err  error
+	bufs map[int64][]byte // mmapped buffers by offset
+}
+
+func (r *recordBackend) ioctl(request uint, argp ioctlArg) error {
+	if r.err != nil {
+		return r.err
+	}
+	e := recordingEntry{
+		Kind:    entryIoctl,
+		Request: uint64(request),
+		In:      marshal(argp),
+	}
+	err := r.b.ioctl(request, argp)
+	if err != nil {
+		e.Errno = uint64(errnoOf(err))
+	} else {
+		e.Out = marshal(argp)
+		if b, ok := argp.(*v4l_buffer); ok && request == vidioc_dqbuf {
+			if buf, ok := r.bufs[int64(b.offset)]; ok && int(b.bytesused) <= len(buf) {
+				e.Frame = buf[:b.bytesused]
+			}
+		}
+	}
+	if err := r.write(&e); err != nil {
+		return err
+	}
+	return err
+}
+
+// write writes e to the recording. After the first failure, it keeps
+// returning the same error.
+func (r *recordBackend) write(e *recordingEntry) error {
+	if r.err == nil {
+		r.err = r.enc.Encode(e)
+	}
+	if r.err == nil {
+		r.err = r.w.Flush()
+	}
+	return r.err
+}
+
+func (r *recordBackend) mmap(offset int64, length int) ([]byte, error) {
+	buf, err := r.b.mmap(offset, length)
+	if err == nil {
+		r.bufs[offset] = buf
+	}
+	return buf, err
+}
+
+func (r *recordBackend) munmap(b []byte) {
+	// b may have been resliced, but it still ends where the mapping does.
+	for offset, buf := range r.bufs {
+		if cap(buf) > 0 && cap(b) > 0 && &buf[:cap(buf)][cap(buf)-1] == &b[:cap(b)][cap(b)-1] {
+			delete(r.bufs, offset)
+		}
+	}
+	r.b.munmap(b)
+}
+
+func (r *recordBackend) pollFd() int {
+	return r.b.pollFd()
+}
+
+func (r *recordBackend) sysfsInfo(info *DeviceInfo) error {
+	if r.err != nil {
+		return r.err
+	}
+	err := r.b.sysfsInfo(info)
+	e := recordingEntry{
+		Kind:  entrySysfs,
+		Errno: uint64(errnoOf(err)),
+		Info:  *info,
+	}
+	if err := r.write(&e); err != nil {
+		return err
+	}
+	return err
+}
+
+// close closes the recording. Every entry has already been flushed, so only
+// the error of closing the file itself, which is rare, is kept in r.err.
+func (r *recordBackend) close() {
+	r.b.close()
+	if err := r.f.Close(); r.err == nil {
+		r.err = err
+	}
+}
+
+// openReplay opens a recording for replay. If the file is not a recording, it
+// fails with ErrWrongDevice.
+func openReplay(path string) (backend, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if e, ok := err.(*os.PathError); ok {
+			err = e.Err
+		}
+		return nil, &OpError{Op: "open", Path: path, Err: err}
+	}
+	rd := bufio.NewReader(f)
+	magic := make([]byte, len(recordingMagic))
+	if _, err := io.ReadFull(rd, magic); err != nil || string(magic) != recordingMagic {
+		f.Close()
+		return nil, ErrWrongDevice
+	}
+	dec := gob.NewDecoder(rd)
+	var h recordingHeader
+	if err := dec.Decode(&h); err != nil {
+		f.Close()
+		return nil, &OpError{Op: "read recording", Path: path, Err: err}
+	}
+	if h.Version != recordingVersion || h.Arch != runtime.GOARCH {
+		f.Close()
+		return nil, &OpError{Op: "read recording", Path: path,
+			Err: Error("recording made on " + h.Arch + " with an unsupported format")}
+	}
+
+	// The ready pipe is always readable, so that Capture never waits.
+	var ready [2]int
+	if err := syscall.Pipe2(ready[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		f.Close()
+		return nil, &OpError{Op: "pipe2", Path: path, Err: err}
+	}
+	syscall.Write(ready[1], []byte{0})
+
+	r := &replayBackend{
+		f:      f,
+		dec:    dec,
+		bufs:   make(map[int64][]byte),
+		readyR: ready[0],
+		readyW: ready[1],
+	}
+	return r, nil
+}
+
+// replayBackend replays a recording.
+type replayBackend struct {
+	f      *os.File
+	dec    *gob.Decoder
+	err    error
+	bufs   map[int64][]byte
+	readyR int
+	readyW int
+}
+
+// next returns the next entry of the recording, and checks that it records the
+// expected operation.
+func (r *replayBackend) next(kind int, request uint) (*recordingEntry, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+	var e recordingEntry
+	if err := r.dec.Decode(&e); err != nil {
+		if err == io.EOF {
+			r.err = errRecordingEnd
+		} else {
+			r.err = err
+		}
+		return nil, r.err
+	}
+	if e.Kind != kind || e.Request != uint64(request) {
+		r.err = errRecordingDiverged
+		return nil, r.err
+	}
+	return &e, nil
+}
+
+func (r *replayBackend) ioctl(request uint, argp ioctlArg) error {
+	e, err := r.next(entryIoctl, request)
+	if err != nil {
+		return err
+	}
+	// The same request with another argument would have had another result.
+	if !bytes.Equal(e.In, marshal(argp)) {
+		r.err = errRecordingDiverged
+		return r.err
+	}
+	if e.Errno != 0 {
+		return syscall.Errno(e.Errno)
+	}
+	unmarshal(argp, e.Out)
+	if b, ok := argp.(*v4l_buffer); ok && request == vidioc_dqbuf {
+		if buf, ok := r.bufs[int64(b.offset)]; ok {
+			copy(buf, e.Frame)
+		}
+	}
+	return nil
+}
+
+func (r *replayBackend) mmap(offset int64, length int) ([]byte, error) {
+	buf := make([]byte, length)
+	r.bufs[offset] = buf
+	return buf, nil
+}
+
+func (r *replayBackend) munmap(b []byte) {}
+
+func (r *replayBackend) pollFd() int {
+	return r.readyR
+}
+
+func (r *replayBackend) sysfsInfo(info *DeviceInfo) error {
+	e, err := r.next(entrySysfs, 0)
+	if err != nil {
+		return err
+	}
+	if e.Errno != 0 {
+		return &OpError{Op: "fstat", Err: syscall.Errno(e.Errno)}
+	}
+	path := info.Path
+	*info = e.Info
+	info.Path = path
+	return nil
+}
+
+func (r *replayBackend) close() {
+	r.f.Close()
+	syscall.Close(r.readyR)
+	syscall.Close(r.readyW)
+}
+
+// marshal returns the raw representation of argp.
+func marshal(argp ioctlArg) []byte {
+	n := argp.size()
+	buf := make([]uint64, (n+7)/8)
+	p := unsafe.Pointer(&buf[0])
+	argp.put(p)
+	out := make([]byte, n)
+	copy(out, (*[1 << 30]byte)(p)[:n:n])
+	return out
+}
+
+// unmarshal sets argp from its raw representation.
+func unmarshal(argp ioctlArg, data []byte) {
+	buf := make([]uint64, (argp.size()+7)/8)
+	p := unsafe.Pointer(&buf[0])
+	copy((*[1 << 30]byte)(p)[:len(buf)*8], data)
+	argp.get(p)
+}
+
+// errnoOf returns the errno in err, EIO if err has none, or 0 if err is nil.
+func errnoOf(err error) syscall.Errno {
+	if err == nil {
+		return 0
+	}
+	var errno syscall.Errno
+	if errors.As(err, &errno) {
+		return errno
+	}
+	return syscall.EIO
+}
@@ -0,0 +1,159 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+)
+
+// session uses a device the way an application would, and returns what it
+// observed.
+func session(dev *Device) (info DeviceInfo, cfg DeviceConfig, frames [][]byte, err error) {
+	if info, err = dev.DeviceInfo(); err != nil {
+		return
+	}
+	if err = dev.SetConfig(DeviceConfig{Format: fourcc("YUYV"), Width: 320, Height: 240, FPS: Frac{15, 1}}); err != nil {
+		return
+	}
+	if cfg, err = dev.GetConfig(); err != nil {
+		return
+	}
+	if err = dev.TurnOn(); err != nil {
+		return
+	}
+	for i := 0; i < 3; i++ {
+		var buf *Buffer
+		if buf, err = dev.Capture(); err != nil {
+			return
+		}
+		frame := make([]byte, buf.Size())
+		buf.ReadAt(frame, 0)
+		frames = append(frames, frame)
+	}
+	return
+}
+
+func TestRecordReplay(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "v4l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	recording := filepath.Join(tmp, "recording")
+
+	// Record a session on a fake device.
+	fb, err := newFakeBackend(FakeDevice{
+		Info: DeviceInfo{Path: "/dev/video0", DeviceName: "Test camera", Serial: "1234"},
+		Configs: []DeviceConfig{
+			{Format: fourcc("YUYV"), Width: 640, Height: 480, FPS: Frac{30, 1}},
+			{Format: fourcc("YUYV"), Width: 320, Height: 240, FPS: Frac{15, 1}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rb, err := newRecordBackend(fb, "/dev/video0", recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev, err := openBackend("/dev/video0", rb)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info, cfg, frames, err := session(dev)
+	dev.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Replay it.
+	dev, err = Open(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info2, cfg2, frames2, err := session(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	info2.Path = info.Path
+	if info2 != info {
+		t.Errorf("DeviceInfo: got: %+v, expected: %+v\n", info2, info)
+	}
+	if cfg2 != cfg {
+		t.Errorf("GetConfig: got: %v, expected: %v\n", cfg2, cfg)
+	}
+	for i := range frames {
+		if !bytes.Equal(frames2[i], frames[i]) {
+			t.Errorf("frame %d differs\n", i)
+		}
+	}
+
+	// Anything not in the recording fails.
+	if _, err := dev.GetControl(CtrlBrightness); !errors.Is(err, errRecordingDiverged) {
+		t.Errorf("GetControl: got: %v, expected: %v\n", err, errRecordingDiverged)
+	}
+	dev.Close()
+
+	// Nor is the same request with another argument.
+	dev, err = Open(recording)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dev.DeviceInfo(); err != nil {
+		t.Fatal(err)
+	}
+	err = dev.SetConfig(DeviceConfig{Format: fourcc("YUYV"), Width: 640, Height: 480, FPS: Frac{15, 1}})
+	if !errors.Is(err, errRecordingDiverged) {
+		t.Errorf("SetConfig: got: %v, expected: %v\n", err, errRecordingDiverged)
+	}
+	dev.Close()
+
+	// Other regular files are not devices.
+	other := filepath.Join(tmp, "other")
+	ioutil.WriteFile(other, []byte("hello"), 0666)
+	if _, err := Open(other); err != ErrWrongDevice {
+		t.Errorf("Open: got: %v, expected: %v\n", err, ErrWrongDevice)
+	}
+	empty := filepath.Join(tmp, "empty")
+	ioutil.WriteFile(empty, nil, 0666)
+	if _, err := Open(empty); err != ErrWrongDevice {
+		t.Errorf("Open: got: %v, expected: %v\n", err, ErrWrongDevice)
+	}
+}
+
+func TestRecordWriteError(t *testing.T) {
+	if _, err := os.Stat("/dev/full"); err != nil {
+		t.Skip(err)
+	}
+	fb, err := newFakeBackend(FakeDevice{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Writing to /dev/full fails with ENOSPC.
+	if _, err := newRecordBackend(fb, "/dev/video0", "/dev/full"); !errors.Is(err, syscall.ENOSPC) {
+		t.Errorf("got: %v, expected: %v\n", err, syscall.ENOSPC)
+	}
+}
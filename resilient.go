@@ -0,0 +1,385 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"errors"
+	"sync"
+	"syscall"
+)
+
+// Connection states reported by a ResilientDevice.
+const (
+	// StateDisconnected means that the device has gone away, and the
+	// ResilientDevice is waiting for it to come back.
+	StateDisconnected = 1
+
+	// StateReconnected means that the device has been reopened, its
+	// configuration restored, and frames are being delivered again.
+	StateReconnected = 2
+)
+
+// A StateChange reports a change in the connection state of a ResilientDevice.
+type StateChange struct {
+	// State is either StateDisconnected or StateReconnected.
+	State int
+
+	// Err is the error that revealed the disconnection for StateDisconnected.
+	// For StateReconnected, it's the first error encountered while restoring
+	// the configuration and the controls, or nil if everything was restored.
+	Err error
+
+	// Info describes the device. After reconnecting, it may have a different
+	// path than before.
+	Info DeviceInfo
+}
+
+// A ResilientDevice is a capture device that survives being unplugged, or
+// being reset by its driver. When it detects that the device has gone away, it
+// waits for the same physical device to reappear, reopens it, restores the
+// configuration and the control values set through it, and resumes capturing.
+//
+// A ResilientDevice is not safe for concurrent use, except that Close may be
+// called from another goroutine to abort a Capture waiting for the device.
+type ResilientDevice struct {
+	mu      sync.Mutex
+	dev     *Device
+	info    DeviceInfo
+	cfg     *DeviceConfig
+	ctrls   map[uint32]int32
+	ctrlIDs []uint32 // in the order they were first set
+	on      bool
+	states  chan StateChange
+	done    chan struct{}
+	once    sync.Once
+}
+
+// watchDevices and openDevice are WatchDevices and Open. They are variables so
+// that tests can replace them.
+var (
+	watchDevices = WatchDevices
+	openDevice   = Open
+)
+
+// OpenResilient opens the capture device named by path, just like Open. The
+// device is later recognized by its USB serial number, or if it has none, by
+// the port it's plugged into.
+func OpenResilient(path string) (*ResilientDevice, error) {
+	dev, err := openDevice(path)
+	if err != nil {
+		return nil, err
+	}
+	info, err := dev.DeviceInfo()
+	if err != nil {
+		dev.Close()
+		return nil, err
+	}
+	r := &ResilientDevice{
+		dev:    dev,
+		info:   info,
+		ctrls:  make(map[uint32]int32),
+		states: make(chan StateChange, 16),
+		done:   make(chan struct{}),
+	}
+	return r, nil
+}
+
+// States returns the channel on which state changes are delivered. State
+// changes are dropped if the channel is full.
+func (r *ResilientDevice) States() <-chan StateChange {
+	return r.states
+}
+
+// Device returns the underlying Device, or nil if the device is disconnected.
+// The Device changes on every reconnection. Configuration and control changes
+// made directly on the Device are not restored after reconnecting.
+func (r *ResilientDevice) Device() *Device {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dev
+}
+
+// Info returns information about the device.
+func (r *ResilientDevice) Info() DeviceInfo {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.info
+}
+
+// SetConfig is like Device.SetConfig, and the configuration is restored after
+// reconnecting. While the device is disconnected, the configuration is only
+// recorded, and it's applied when the device is back.
+func (r *ResilientDevice) SetConfig(cfg DeviceConfig) error {
+	dev, err := r.device()
+	if err != nil {
+		return err
+	}
+	if dev != nil {
+		if err := dev.SetConfig(cfg); err != nil && !gone(dev, err) {
+			return err
+		}
+	}
+	r.mu.Lock()
+	r.cfg = &cfg
+	r.mu.Unlock()
+	return nil
+}
+
+// SetControl is like Device.SetControl, and the value is restored after
+// reconnecting. Like with SetConfig, the value is only recorded while the
+// device is disconnected.
+func (r *ResilientDevice) SetControl(cid uint32, value int32) error {
+	dev, err := r.device()
+	if err != nil {
+		return err
+	}
+	if dev != nil {
+		if err := dev.SetControl(cid, value); err != nil && !gone(dev, err) {
+			return err
+		}
+	}
+	r.mu.Lock()
+	if _, ok := r.ctrls[cid]; !ok {
+		r.ctrlIDs = append(r.ctrlIDs, cid)
+	}
+	r.ctrls[cid] = value
+	r.mu.Unlock()
+	return nil
+}
+
+// TurnOn is like Device.TurnOn. The capture session is restarted after
+// reconnecting. While the device is disconnected, it's only started when the
+// device is back.
+func (r *ResilientDevice) TurnOn() error {
+	dev, err := r.device()
+	if err != nil {
+		return err
+	}
+	if dev != nil {
+		if err := dev.TurnOn(); err != nil && !gone(dev, err) {
+			return err
+		}
+	}
+	r.mu.Lock()
+	r.on = true
+	r.mu.Unlock()
+	return nil
+}
+
+// TurnOff is like Device.TurnOff.
+func (r *ResilientDevice) TurnOff() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.on = false
+	if r.dev != nil {
+		r.dev.TurnOff()
+	}
+}
+
+// Capture is like Device.Capture, except that if the device goes away, it
+// blocks until the device is back, and then it returns the first frame
+// captured from it. It only fails with errors that are not caused by the
+// device going away, or with ErrClosed if the ResilientDevice is closed while
+// waiting.
+func (r *ResilientDevice) Capture() (*Buffer, error) {
+	for {
+		dev, err := r.device()
+		if err != nil {
+			return nil, err
+		}
+		if dev == nil {
+			// A previous reconnection attempt failed.
+			if err := r.reconnect(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		buf, err := dev.Capture()
+		if err == nil {
+			return buf, nil
+		}
+		if !gone(dev, err) {
+			return nil, err
+		}
+		r.disconnected(err)
+		if err := r.reconnect(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Close closes the device. It aborts any Capture waiting for the device to come
+// back.
+func (r *ResilientDevice) Close() {
+	r.once.Do(func() {
+		close(r.done)
+	})
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.dev != nil {
+		r.dev.Close()
+		r.dev = nil
+	}
+}
+
+// device returns the current Device, or nil while the device is disconnected.
+// It fails with ErrClosed if r has been closed.
+func (r *ResilientDevice) device() (*Device, error) {
+	select {
+	case <-r.done:
+		return nil, ErrClosed
+	default:
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.dev, nil
+}
+
+// gone tells if err, returned by dev, means that the device has gone away.
+// Some drivers first fail with EIO, and only later calls fail with ENODEV.
+func gone(dev *Device, err error) bool {
+	if errors.Is(err, ErrDisconnected) {
+		return true
+	}
+	if errors.Is(err, syscall.EIO) {
+		_, err := dev.DeviceInfo()
+		return errors.Is(err, ErrDisconnected)
+	}
+	return false
+}
+
+// disconnected closes the lost device, and reports the disconnection.
+func (r *ResilientDevice) disconnected(cause error) {
+	r.mu.Lock()
+	if r.dev != nil {
+		r.dev.Close()
+		r.dev = nil
+	}
+	info := r.info
+	r.mu.Unlock()
+	r.notify(StateChange{StateDisconnected, cause, info})
+}
+
+// reconnect waits for the device to come back, reopens it, and restores its
+// state.
+func (r *ResilientDevice) reconnect() error {
+	w, err := watchDevices()
+	if err != nil {
+		return err
+	}
+	defer w.Close()
+
+	for {
+		var ev DeviceEvent
+		select {
+		case <-r.done:
+			return ErrClosed
+		case e, ok := <-w.Events():
+			if !ok {
+				return Error("device watcher stopped")
+			}
+			ev = e
+		}
+		if ev.Type != DeviceAdded || !sameDevice(r.info, ev.Info) {
+			continue
+		}
+		dev, err := openDevice(ev.Info.Path)
+		if err != nil {
+			continue
+		}
+		restoreErr, err := r.restore(dev)
+		if err != nil {
+			dev.Close()
+			continue
+		}
+
+		r.mu.Lock()
+		select {
+		case <-r.done:
+			r.mu.Unlock()
+			dev.Close()
+			return ErrClosed
+		default:
+		}
+		r.dev = dev
+		r.info = ev.Info
+		r.mu.Unlock()
+		r.notify(StateChange{StateReconnected, restoreErr, ev.Info})
+		return nil
+	}
+}
+
+// restore applies the saved configuration and control values to dev, and
+// turns it on if needed. Failing to restore the configuration or a control is
+// reported in restoreErr, while failing to turn on the device is reported in
+// err, as the device is of no use in that case.
+func (r *ResilientDevice) restore(dev *Device) (restoreErr, err error) {
+	r.mu.Lock()
+	cfg, on := r.cfg, r.on
+	ids := append([]uint32(nil), r.ctrlIDs...)
+	values := make([]int32, len(ids))
+	for i, cid := range ids {
+		values[i] = r.ctrls[cid]
+	}
+	r.mu.Unlock()
+
+	if cfg != nil {
+		restoreErr = dev.SetConfig(*cfg)
+	}
+	for i, cid := range ids {
+		if err := dev.SetControl(cid, values[i]); err != nil && restoreErr == nil {
+			restoreErr = err
+		}
+	}
+	if on {
+		if err := dev.TurnOn(); err != nil {
+			return restoreErr, err
+		}
+	}
+	return restoreErr, nil
+}
+
+// notify delivers a state change, or drops it if the channel is full.
+func (r *ResilientDevice) notify(sc StateChange) {
+	select {
+	case r.states <- sc:
+	default:
+	}
+}
+
+// sameDevice tells if b is the same physical device (and the same node of it)
+// as a. Devices with a serial number are matched by the serial number,
+// otherwise by the port they are plugged into, or failing that, by their name
+// and bus info.
+func sameDevice(a, b DeviceInfo) bool {
+	switch {
+	case a.Serial != "":
+		return a.Serial == b.Serial && a.VendorID == b.VendorID &&
+			a.ProductID == b.ProductID && a.Interface == b.Interface &&
+			a.Index == b.Index
+	case a.Port != "":
+		return a.Port == b.Port && a.VendorID == b.VendorID &&
+			a.ProductID == b.ProductID && a.Interface == b.Interface &&
+			a.Index == b.Index
+	default:
+		return a.BusInfo != "" && a.BusInfo == b.BusInfo &&
+			a.DeviceName == b.DeviceName
+	}
+}
@@ -0,0 +1,204 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSameDevice(t *testing.T) {
+	usb := DeviceInfo{
+		Path:      "/dev/video0",
+		VendorID:  0x046d,
+		ProductID: 0x0825,
+		Serial:    "ABC123",
+		Port:      "1-1.2",
+	}
+	moved := usb
+	moved.Path = "/dev/video2"
+	moved.Port = "1-1.3"
+	other := usb
+	other.Serial = "XYZ789"
+	metadata := usb
+	metadata.Index = 1
+	noSerial := usb
+	noSerial.Serial = ""
+	pci := DeviceInfo{Path: "/dev/video0", DeviceName: "BT878 video", BusInfo: "PCI:0000:05:06.0"}
+
+	for i, x := range []struct {
+		a, b DeviceInfo
+		same bool
+	}{
+		{usb, usb, true},
+		{usb, moved, true},
+		{usb, other, false},
+		{usb, metadata, false},
+		{noSerial, moved, false},
+		{noSerial, usb, true},
+		{pci, pci, true},
+		{pci, DeviceInfo{DeviceName: "BT878 video"}, false},
+		{DeviceInfo{}, DeviceInfo{}, false},
+	} {
+		if same := sameDevice(x.a, x.b); same != x.same {
+			t.Errorf("%d: got: %v, expected: %v\n", i, same, x.same)
+		}
+	}
+}
+
+func TestResilientDeviceClose(t *testing.T) {
+	defer func(f func() (*DeviceWatcher, error)) { watchDevices = f }(watchDevices)
+	src := newTestUeventSource()
+	watchDevices = func() (*DeviceWatcher, error) {
+		return NewDeviceWatcher(src), nil
+	}
+
+	r := &ResilientDevice{
+		info:   DeviceInfo{Serial: "ABC123"},
+		ctrls:  make(map[uint32]int32),
+		states: make(chan StateChange, 16),
+		done:   make(chan struct{}),
+	}
+	errs := make(chan error)
+	go func() {
+		errs <- r.reconnect()
+	}()
+	time.Sleep(10 * time.Millisecond)
+	r.Close()
+	select {
+	case err := <-errs:
+		if err != ErrClosed {
+			t.Errorf("got: %v, expected: %v\n", err, ErrClosed)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("reconnect not aborted by Close\n")
+	}
+	if _, err := r.Capture(); err != ErrClosed {
+		t.Errorf("got: %v, expected: %v\n", err, ErrClosed)
+	}
+}
+
+func TestResilientDeviceReconnect(t *testing.T) {
+	defer func(w func() (*DeviceWatcher, error), o func(string) (*Device, error),
+		p func(string, <-chan struct{}) (DeviceInfo, error)) {
+		watchDevices, openDevice, probeDevice = w, o, p
+	}(watchDevices, openDevice, probeDevice)
+
+	src := newTestUeventSource()
+	watchDevices = func() (*DeviceWatcher, error) {
+		return NewDeviceWatcher(src), nil
+	}
+	info := func(path string) DeviceInfo {
+		return DeviceInfo{Path: path, VendorID: 0x046d, ProductID: 0x0825, Serial: "ABC123"}
+	}
+	probeDevice = func(path string, done <-chan struct{}) (DeviceInfo, error) {
+		return info(path), nil
+	}
+	var unplugged int32
+	openDevice = func(path string) (*Device, error) {
+		unplug := atomic.LoadInt32(&unplugged) == 0
+		return OpenFake(FakeDevice{
+			Info:    info(path),
+			Configs: fakeConfigs,
+			Controls: []ControlInfo{
+				{CID: CtrlBrightness, Name: "Brightness", Type: "int", Min: 0, Max: 100, Step: 1, Default: 50},
+				{CID: CtrlContrast, Name: "Contrast", Type: "int", Min: 0, Max: 100, Step: 1, Default: 50},
+			},
+			Err: func(op string) error {
+				if unplug && atomic.LoadInt32(&unplugged) != 0 {
+					return syscall.ENODEV
+				}
+				return nil
+			},
+		})
+	}
+
+	r, err := OpenResilient("/dev/video0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if err := r.SetConfig(fakeConfigs[2]); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.SetControl(CtrlBrightness, 20); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.Capture(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Unplug the device, and capture until it's back.
+	atomic.StoreInt32(&unplugged, 1)
+	errs := make(chan error)
+	go func() {
+		_, err := r.Capture()
+		errs <- err
+	}()
+	select {
+	case sc := <-r.States():
+		if sc.State != StateDisconnected {
+			t.Fatalf("got: %+v, expected a disconnection\n", sc)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("disconnection not reported\n")
+	}
+
+	// Settings made during the outage are applied after reconnecting.
+	if err := r.SetControl(CtrlContrast, 70); err != nil {
+		t.Errorf("SetControl while disconnected: %v\n", err)
+	}
+	src.ch <- Uevent{"add", "video4linux", "video2"}
+
+	select {
+	case err := <-errs:
+		if err != nil {
+			t.Fatalf("Capture: %v\n", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Capture not resumed\n")
+	}
+	select {
+	case sc := <-r.States():
+		if sc.State != StateReconnected || sc.Err != nil || sc.Info.Path != "/dev/video2" {
+			t.Errorf("got: %+v, expected a clean reconnection\n", sc)
+		}
+	default:
+		t.Errorf("reconnection not reported\n")
+	}
+
+	dev := r.Device()
+	if cfg, err := dev.GetConfig(); err != nil || cfg != fakeConfigs[2] {
+		t.Errorf("got: %v, %v, expected: %v\n", cfg, err, fakeConfigs[2])
+	}
+	for cid, want := range map[uint32]int32{CtrlBrightness: 20, CtrlContrast: 70} {
+		if v, err := dev.GetControl(cid); err != nil || v != want {
+			t.Errorf("control %#x: got: %v, %v, expected: %v\n", cid, v, err, want)
+		}
+	}
+	if _, err := r.Capture(); err != nil {
+		t.Errorf("Capture after reconnecting: %v\n", err)
+	}
+}
@@ -0,0 +1,301 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"context"
+	"sync"
+	"syscall"
+)
+
+// Backpressure policies. They tell what happens when a new frame is captured,
+// but a subscriber hasn't received the previous ones yet.
+const (
+	// PolicyBlock makes the stream wait for the subscriber. A slow subscriber
+	// slows down all the others.
+	PolicyBlock = 1
+
+	// PolicyDropOldest discards the oldest frame not yet received by the
+	// subscriber.
+	PolicyDropOldest = 2
+
+	// PolicyKeepLatest only keeps the most recent frame for the subscriber.
+	// It's the same as PolicyDropOldest with a queue length of 1.
+	PolicyKeepLatest = 3
+)
+
+// A Stream runs a capture loop in a goroutine, and delivers the frames to any
+// number of subscribers over channels. Each frame is copied out of the device
+// once, and shared by the subscribers. Subscribers must Release the frames they
+// receive.
+type Stream struct {
+	mu      sync.Mutex
+	subs    []*Subscription
+	stopped bool
+	err     error
+	done    chan struct{}
+	cancelR int
+	cancelW int
+	capture func(cancel int) (*Frame, error)
+}
+
+// A Subscription receives the frames of a Stream.
+type Subscription struct {
+	s      *Stream
+	policy int
+	done   chan struct{}
+	once   sync.Once
+
+	// mu is held while sending on ch, so that ch is not closed under a
+	// blocked send.
+	mu     sync.Mutex
+	ch     chan *Frame
+	closed bool
+}
+
+// NewStream starts capturing frames from dev until ctx is done or Capture
+// fails. The device must be turned on, and while the stream is running, Capture
+// must not be called by anyone else. The device is not turned off or closed
+// when the stream stops.
+func NewStream(ctx context.Context, dev *Device) (*Stream, error) {
+	return newStream(ctx, func(cancel int) (*Frame, error) {
+		buf, err := dev.capture(cancel)
+		if err != nil {
+			return nil, err
+		}
+		return buf.Frame()
+	})
+}
+
+// newStream starts a stream with the given capture function. The function
+// must fail with errCanceled when cancel becomes readable. The stream releases
+// the reference to the frames returned by the function once they have been
+// delivered.
+func newStream(ctx context.Context, capture func(cancel int) (*Frame, error)) (*Stream, error) {
+	var cancel [2]int
+	if err := syscall.Pipe2(cancel[:], syscall.O_NONBLOCK|syscall.O_CLOEXEC); err != nil {
+		return nil, &OpError{Op: "pipe2", Err: err}
+	}
+	s := &Stream{
+		done:    make(chan struct{}),
+		cancelR: cancel[0],
+		cancelW: cancel[1],
+		capture: capture,
+	}
+	go s.run(ctx)
+	return s, nil
+}
+
+// Subscribe adds a subscriber to the stream. The subscriber receives the frames
+// captured after this call, and at most queueLen of them are kept for it. The
+// policy tells what to do when the queue is full. (e.g. PolicyDropOldest)
+// With PolicyBlock, a queueLen of 0 makes the stream wait until each frame is
+// received, while the other policies always keep at least one frame. Subscribe
+// fails with ErrInvalidPolicy if policy is not one of the Policy constants.
+func (s *Stream) Subscribe(policy int, queueLen int) (*Subscription, error) {
+	switch policy {
+	case PolicyBlock:
+		if queueLen < 0 {
+			queueLen = 0
+		}
+	case PolicyDropOldest:
+		if queueLen < 1 {
+			queueLen = 1
+		}
+	case PolicyKeepLatest:
+		queueLen = 1
+	default:
+		return nil, ErrInvalidPolicy
+	}
+	sub := &Subscription{
+		s:      s,
+		ch:     make(chan *Frame, queueLen),
+		policy: policy,
+		done:   make(chan struct{}),
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.stopped {
+		sub.closeChan()
+		return sub, nil
+	}
+	s.subs = append(s.subs, sub)
+	return sub, nil
+}
+
+// Done returns a channel that's closed when the stream stops.
+func (s *Stream) Done() <-chan struct{} {
+	return s.done
+}
+
+// Err returns the reason the stream stopped: ctx.Err() if ctx is done, or the
+// error returned by Capture. It returns nil while the stream is running.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+// Frames returns the channel on which the frames are delivered. It's closed
+// when the stream stops or the subscription is closed. Every frame received
+// must be released.
+func (sub *Subscription) Frames() <-chan *Frame {
+	return sub.ch
+}
+
+// Close ends the subscription, and closes the channel. Frames still queued are
+// released, so they should not be received after calling Close.
+func (sub *Subscription) Close() {
+	sub.once.Do(func() {
+		close(sub.done)
+	})
+	s := sub.s
+	s.mu.Lock()
+	for i, x := range s.subs {
+		if x == sub {
+			s.subs = append(s.subs[:i], s.subs[i+1:]...)
+			break
+		}
+	}
+	s.mu.Unlock()
+	sub.closeChan()
+	for f := range sub.ch {
+		f.Release()
+	}
+}
+
+// closeChan closes the channel of sub, unless it's already closed.
+func (sub *Subscription) closeChan() {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if !sub.closed {
+		close(sub.ch)
+		sub.closed = true
+	}
+}
+
+// run is the capture loop.
+func (s *Stream) run(ctx context.Context) {
+	// The watcher must be gone before the pipe is closed, or it might write
+	// to a closed (or reused) file descriptor.
+	stopCancel := make(chan struct{})
+	watcherDone := make(chan struct{})
+	go func() {
+		defer close(watcherDone)
+		select {
+		case <-ctx.Done():
+			syscall.Write(s.cancelW, []byte{0})
+		case <-stopCancel:
+		}
+	}()
+
+	var err error
+	for {
+		// A device that always has a frame ready never blocks in capture,
+		// so cancellation must be checked here too.
+		if ctx.Err() != nil {
+			break
+		}
+		var f *Frame
+		f, err = s.capture(s.cancelR)
+		if err != nil {
+			break
+		}
+		ok := s.deliver(ctx, f)
+		f.Release()
+		if !ok {
+			break
+		}
+	}
+	if ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	close(stopCancel)
+	<-watcherDone
+	s.mu.Lock()
+	s.stopped = true
+	s.err = err
+	subs := s.subs
+	s.subs = nil
+	s.mu.Unlock()
+	for _, sub := range subs {
+		sub.closeChan()
+	}
+	syscall.Close(s.cancelR)
+	syscall.Close(s.cancelW)
+	close(s.done)
+}
+
+// deliver sends f to every subscriber according to their policies. It returns
+// false if ctx is done.
+func (s *Stream) deliver(ctx context.Context, f *Frame) bool {
+	s.mu.Lock()
+	subs := append([]*Subscription(nil), s.subs...)
+	s.mu.Unlock()
+
+	for _, sub := range subs {
+		if !sub.send(ctx, f) {
+			return false
+		}
+	}
+	return true
+}
+
+// send delivers f to sub, adding a reference to f if it's queued. It returns
+// false if ctx is done.
+func (sub *Subscription) send(ctx context.Context, f *Frame) bool {
+	sub.mu.Lock()
+	defer sub.mu.Unlock()
+	if sub.closed {
+		return true
+	}
+
+	if sub.policy == PolicyBlock {
+		// Close closes sub.done before waiting for sub.mu, which unblocks
+		// the send.
+		f.Retain()
+		select {
+		case sub.ch <- f:
+		case <-sub.done:
+			f.Release()
+		case <-ctx.Done():
+			f.Release()
+			return false
+		}
+		return true
+	}
+
+	f.Retain()
+	select {
+	case sub.ch <- f:
+	default:
+		// Make room for the new frame. Only this goroutine sends on the
+		// channel, and it has room for at least one frame, so the send
+		// below can't block.
+		select {
+		case old := <-sub.ch:
+			old.Release()
+		default:
+		}
+		sub.ch <- f
+	}
+	return true
+}
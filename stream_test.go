@@ -0,0 +1,277 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"context"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+// fakeCapture returns a capture function that delivers the frames sent on
+// frames, and fails with errCanceled when cancel becomes readable.
+func fakeCapture(frames <-chan *Frame) func(cancel int) (*Frame, error) {
+	return func(cancel int) (*Frame, error) {
+		var b [1]byte
+		for {
+			select {
+			case f := <-frames:
+				return f, nil
+			case <-time.After(time.Millisecond):
+			}
+			if n, _ := syscall.Read(cancel, b[:]); n > 0 {
+				return nil, errCanceled
+			}
+		}
+	}
+}
+
+// subscribe subscribes to s, failing the test on error.
+func subscribe(t *testing.T, s *Stream, policy, queueLen int) *Subscription {
+	t.Helper()
+	sub, err := s.Subscribe(policy, queueLen)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return sub
+}
+
+// seqNums receives the frames queued on sub until its channel is closed.
+func seqNums(sub *Subscription) []uint32 {
+	var seq []uint32
+	for f := range sub.Frames() {
+		seq = append(seq, f.SeqNum)
+		f.Release()
+	}
+	return seq
+}
+
+func equalSeqNums(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestStreamDrop(t *testing.T) {
+	frames := make(chan *Frame)
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := newStream(ctx, fakeCapture(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	oldest := subscribe(t, s, PolicyDropOldest, 2)
+	latest := subscribe(t, s, PolicyKeepLatest, 5)
+	var sent []*Frame
+	for i := uint32(1); i <= 3; i++ {
+		f := newFrame(1)
+		f.SeqNum = i
+		sent = append(sent, f)
+		frames <- f
+	}
+	cancel()
+	<-s.Done()
+	if sent[0].refs != 0 {
+		t.Errorf("dropped frame: got %d references, expected 0\n", sent[0].refs)
+	}
+
+	if got, exp := seqNums(oldest), []uint32{2, 3}; !equalSeqNums(got, exp) {
+		t.Errorf("PolicyDropOldest: got: %v, expected: %v\n", got, exp)
+	}
+	if got, exp := seqNums(latest), []uint32{3}; !equalSeqNums(got, exp) {
+		t.Errorf("PolicyKeepLatest: got: %v, expected: %v\n", got, exp)
+	}
+	if err := s.Err(); err != context.Canceled {
+		t.Errorf("Err: got: %v, expected: %v\n", err, context.Canceled)
+	}
+	if _, ok := <-subscribe(t, s, PolicyBlock, 0).Frames(); ok {
+		t.Errorf("Subscribe after stop: channel not closed\n")
+	}
+}
+
+func TestStreamBlock(t *testing.T) {
+	frames := make(chan *Frame)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := newStream(ctx, fakeCapture(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := subscribe(t, s, PolicyBlock, 0)
+
+	// The stream must not capture the second frame before the first one has
+	// been received.
+	frames <- &Frame{SeqNum: 1}
+	select {
+	case frames <- &Frame{SeqNum: 2}:
+		t.Fatalf("stream didn't wait for the subscriber\n")
+	case <-time.After(20 * time.Millisecond):
+	}
+	if f := <-sub.Frames(); f.SeqNum != 1 {
+		t.Errorf("got frame %d, expected 1\n", f.SeqNum)
+	}
+
+	// Closing the subscription must unblock the stream.
+	frames <- &Frame{SeqNum: 2}
+	time.Sleep(10 * time.Millisecond)
+	sub.Close()
+	if _, ok := <-sub.Frames(); ok {
+		t.Errorf("Close: channel not closed\n")
+	}
+	select {
+	case frames <- &Frame{SeqNum: 3}:
+	case <-time.After(time.Second):
+		t.Errorf("stream blocked by closed subscription\n")
+	}
+}
+
+func TestStreamCaptureError(t *testing.T) {
+	s, err := newStream(context.Background(), func(cancel int) (*Frame, error) {
+		return nil, ErrClosed
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := subscribe(t, s, PolicyDropOldest, 1)
+	<-s.Done()
+	if err := s.Err(); err != ErrClosed {
+		t.Errorf("Err: got: %v, expected: %v\n", err, ErrClosed)
+	}
+	for range sub.Frames() {
+	}
+}
+
+func TestStreamZeroQueue(t *testing.T) {
+	frames := make(chan *Frame)
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := newStream(ctx, fakeCapture(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := subscribe(t, s, PolicyDropOldest, 0)
+
+	// Nobody is receiving, so the frames must be dropped instead of blocking
+	// the stream.
+	for i := uint32(1); i <= 3; i++ {
+		f := newFrame(1)
+		f.SeqNum = i
+		select {
+		case frames <- f:
+		case <-time.After(time.Second):
+			t.Fatalf("stream blocked by subscription without a queue\n")
+		}
+	}
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("stream didn't stop\n")
+	}
+	if got, exp := seqNums(sub), []uint32{3}; !equalSeqNums(got, exp) {
+		t.Errorf("got: %v, expected: %v\n", got, exp)
+	}
+}
+
+func TestStreamCloseReleases(t *testing.T) {
+	frames := make(chan *Frame)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s, err := newStream(ctx, fakeCapture(frames))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := subscribe(t, s, PolicyDropOldest, 3)
+	var sent []*Frame
+	for i := uint32(1); i <= 2; i++ {
+		f := newFrame(1)
+		f.SeqNum = i
+		sent = append(sent, f)
+		frames <- f
+	}
+	for i := 0; len(sub.ch) < 2; i++ {
+		if i == 1000 {
+			t.Fatalf("frames not queued\n")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	sub.Close()
+	for _, f := range sent {
+		if refs := atomic.LoadInt32(&f.refs); refs != 0 {
+			t.Errorf("frame %d: got %d references, expected 0\n", f.SeqNum, refs)
+		}
+	}
+}
+
+func TestStreamInvalidPolicy(t *testing.T) {
+	s, err := newStream(context.Background(), func(cancel int) (*Frame, error) {
+		return nil, ErrClosed
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	<-s.Done()
+	if _, err := s.Subscribe(42, 1); err != ErrInvalidPolicy {
+		t.Errorf("got: %v, expected: %v\n", err, ErrInvalidPolicy)
+	}
+}
+
+func TestNewStreamCancel(t *testing.T) {
+	// Not RealTime, so a frame is always ready, and capture never waits.
+	dev, err := OpenFake(FakeDevice{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dev.Close()
+	if err := dev.TurnOn(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	s, err := NewStream(ctx, dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := subscribe(t, s, PolicyDropOldest, 2)
+	f, ok := <-sub.Frames()
+	if !ok {
+		t.Fatalf("no frame received: %v\n", s.Err())
+	}
+	f.Release()
+
+	cancel()
+	select {
+	case <-s.Done():
+	case <-time.After(time.Second):
+		t.Fatalf("stream didn't stop\n")
+	}
+	if err := s.Err(); err != context.Canceled {
+		t.Errorf("Err: got: %v, expected: %v\n", err, context.Canceled)
+	}
+	for f := range sub.Frames() {
+		f.Release()
+	}
+}
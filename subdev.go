@@ -0,0 +1,360 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Media bus formats. These describe how pixels travel between the blocks of a
+// pipeline, e.g. from a sensor to a CSI receiver. Drivers may support other
+// formats than these.
+const (
+	MbusFmtFixed        = 0x0001
+	MbusFmtRGB888_1X24  = 0x100a
+	MbusFmtY8_1X8       = 0x2001
+	MbusFmtY10_1X10     = 0x200a
+	MbusFmtUYVY8_2X8    = 0x2006
+	MbusFmtYUYV8_2X8    = 0x2008
+	MbusFmtUYVY8_1X16   = 0x200f
+	MbusFmtYUYV8_1X16   = 0x2011
+	MbusFmtSBGGR8_1X8   = 0x3001
+	MbusFmtSGBRG8_1X8   = 0x3013
+	MbusFmtSGRBG8_1X8   = 0x3002
+	MbusFmtSRGGB8_1X8   = 0x3014
+	MbusFmtSBGGR10_1X10 = 0x3007
+	MbusFmtSGBRG10_1X10 = 0x300e
+	MbusFmtSGRBG10_1X10 = 0x300a
+	MbusFmtSRGGB10_1X10 = 0x300f
+	MbusFmtSBGGR12_1X12 = 0x3008
+	MbusFmtSGBRG12_1X12 = 0x3010
+	MbusFmtSGRBG12_1X12 = 0x3011
+	MbusFmtSRGGB12_1X12 = 0x3012
+)
+
+// Selection targets.
+const (
+	// SelCrop is the crop rectangle.
+	SelCrop = 0x0000
+
+	// SelCropDefault is the default crop rectangle.
+	SelCropDefault = 0x0001
+
+	// SelCropBounds is the largest possible crop rectangle.
+	SelCropBounds = 0x0002
+
+	// SelNativeSize is the size of the pixel array of a sensor.
+	SelNativeSize = 0x0003
+
+	// SelCompose is the compose (scaling) rectangle.
+	SelCompose = 0x0100
+
+	// SelComposeBounds is the largest possible compose rectangle.
+	SelComposeBounds = 0x0102
+)
+
+// A Subdevice represents a V4L sub-device (/dev/v4l-subdevN), such as a camera
+// sensor or a CSI receiver. Sub-devices don't deliver frames themselves, but
+// the formats on their pads have to match the rest of the pipeline before the
+// video node at its end can be turned on. The media package can be used to find
+// out which sub-devices make up a pipeline.
+type Subdevice struct {
+	*device
+}
+
+// A PadFormat is the format on a pad of a sub-device.
+type PadFormat struct {
+	// Code is the media bus format. (e.g. MbusFmtSRGGB10_1X10)
+	Code uint32
+
+	// Width and Height are the size of the image in pixels.
+	Width  int
+	Height int
+}
+
+// A Rect is a rectangle on the image, e.g. a crop rectangle.
+type Rect struct {
+	Left   int
+	Top    int
+	Width  int
+	Height int
+}
+
+// OpenSubdevice opens the sub-device named by path. If the file is not a
+// sub-device, it fails with ErrNotSubdevice.
+func OpenSubdevice(path string) (*Subdevice, error) {
+	// Open the file.
+	fd, err := syscall.Open(path, syscall.O_RDWR|syscall.O_CLOEXEC, 0)
+	if err != nil {
+		return nil, &OpError{Op: "open", Path: path, Err: err}
+	}
+
+	// Check if it's a V4L device.
+	var stat syscall.Stat_t
+	if err := syscall.Fstat(fd, &stat); err != nil {
+		syscall.Close(fd)
+		return nil, &OpError{Op: "fstat", Path: path, Err: err}
+	}
+	if major, _ := devNumbers(uint64(stat.Rdev)); stat.Mode&syscall.S_IFCHR == 0 || major != 81 {
+		syscall.Close(fd)
+		return nil, ErrNotSubdevice
+	}
+
+	// Video nodes and sub-devices have the same major number, but only video
+	// nodes implement VIDIOC_QUERYCAP.
+	var c v4l_capability
+	if err := ioctl_querycap(fileBackend(fd), &c); !errors.Is(err, syscall.ENOTTY) {
+		syscall.Close(fd)
+		if err != nil {
+			return nil, withPath(err, path)
+		}
+		return nil, ErrNotSubdevice
+	}
+
+	d := &device{
+		path:     path,
+		dev:      fileBackend(fd),
+		wakeR:    -1,
+		wakeW:    -1,
+		bufIndex: noBuffer,
+	}
+	return &Subdevice{d}, nil
+}
+
+// Close closes the sub-device.
+func (s *Subdevice) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.closed {
+		s.close()
+	}
+}
+
+// PadFormat returns the current format on a pad.
+func (s *Subdevice) PadFormat(pad int) (_ PadFormat, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return PadFormat{}, err
+	}
+	defer s.mu.Unlock()
+
+	f := v4l_subdevFormat{
+		which: v4l_subdevFormatActive,
+		pad:   uint32(pad),
+	}
+	if err := ioctl_subdevGFmt(s.dev, &f); err != nil {
+		return PadFormat{}, err
+	}
+	return PadFormat{
+		Code:   f.format.code,
+		Width:  int(f.format.width),
+		Height: int(f.format.height),
+	}, nil
+}
+
+// SetPadFormat sets the format on a pad. The driver may adjust the format to
+// the closest one it supports; the format actually set is returned.
+func (s *Subdevice) SetPadFormat(pad int, pf PadFormat) (_ PadFormat, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return PadFormat{}, err
+	}
+	defer s.mu.Unlock()
+
+	f := v4l_subdevFormat{
+		which: v4l_subdevFormatActive,
+		pad:   uint32(pad),
+	}
+	if err := ioctl_subdevGFmt(s.dev, &f); err != nil {
+		return PadFormat{}, err
+	}
+	f.format.code = pf.Code
+	f.format.width = uint32(pf.Width)
+	f.format.height = uint32(pf.Height)
+	if err := ioctl_subdevSFmt(s.dev, &f); err != nil {
+		return PadFormat{}, err
+	}
+	return PadFormat{
+		Code:   f.format.code,
+		Width:  int(f.format.width),
+		Height: int(f.format.height),
+	}, nil
+}
+
+// ListPadCodes returns the media bus formats supported on a pad.
+func (s *Subdevice) ListPadCodes(pad int) (_ []uint32, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+
+	var codes []uint32
+	for i := uint32(0); ; i++ {
+		e := v4l_subdevMbusCodeEnum{
+			pad:   uint32(pad),
+			index: i,
+			which: v4l_subdevFormatActive,
+		}
+		if err := ioctl_subdevEnumMbusCode(s.dev, &e); err != nil {
+			if errors.Is(err, syscall.EINVAL) {
+				return codes, nil
+			}
+			return nil, err
+		}
+		codes = append(codes, e.code)
+	}
+}
+
+// FrameRate returns the frame rate on a pad. Only sub-devices that control
+// timing, typically sensors, support this.
+func (s *Subdevice) FrameRate(pad int) (_ Frac, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return Frac{}, err
+	}
+	defer s.mu.Unlock()
+
+	fi := v4l_subdevFrameInterval{pad: uint32(pad)}
+	if err := ioctl_subdevGFrameInterval(s.dev, &fi); err != nil {
+		return Frac{}, err
+	}
+	return Frac{fi.interval.denominator, fi.interval.numerator}, nil
+}
+
+// SetFrameRate sets the frame rate on a pad. The driver may adjust the frame
+// rate to the closest one it supports; the frame rate actually set is
+// returned.
+func (s *Subdevice) SetFrameRate(pad int, fps Frac) (_ Frac, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return Frac{}, err
+	}
+	defer s.mu.Unlock()
+
+	fps = fps.Reduce()
+	fi := v4l_subdevFrameInterval{
+		pad:      uint32(pad),
+		interval: v4l_fract{fps.D, fps.N},
+	}
+	if err := ioctl_subdevSFrameInterval(s.dev, &fi); err != nil {
+		return Frac{}, err
+	}
+	return Frac{fi.interval.denominator, fi.interval.numerator}, nil
+}
+
+// Selection returns a selection rectangle of a pad. The target selects which
+// rectangle. (e.g. SelCrop)
+func (s *Subdevice) Selection(pad int, target uint32) (_ Rect, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return Rect{}, err
+	}
+	defer s.mu.Unlock()
+
+	sel := v4l_subdevSelection{
+		which:  v4l_subdevFormatActive,
+		pad:    uint32(pad),
+		target: target,
+	}
+	if err := ioctl_subdevGSelection(s.dev, &sel); err != nil {
+		return Rect{}, err
+	}
+	return rect(sel.r), nil
+}
+
+// SetSelection sets a selection rectangle of a pad. Only SelCrop and
+// SelCompose can be set. The driver may adjust the rectangle; the rectangle
+// actually set is returned.
+func (s *Subdevice) SetSelection(pad int, target uint32, r Rect) (_ Rect, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return Rect{}, err
+	}
+	defer s.mu.Unlock()
+
+	sel := v4l_subdevSelection{
+		which:  v4l_subdevFormatActive,
+		pad:    uint32(pad),
+		target: target,
+		r: v4l_rect{
+			left:   int32(r.Left),
+			top:    int32(r.Top),
+			width:  uint32(r.Width),
+			height: uint32(r.Height),
+		},
+	}
+	if err := ioctl_subdevSSelection(s.dev, &sel); err != nil {
+		return Rect{}, err
+	}
+	return rect(sel.r), nil
+}
+
+// rect converts a v4l_rect to a Rect.
+func rect(r v4l_rect) Rect {
+	return Rect{
+		Left:   int(r.left),
+		Top:    int(r.top),
+		Width:  int(r.width),
+		Height: int(r.height),
+	}
+}
+
+// ControlInfo returns information about a control. Sensors typically have
+// controls such as CtrlExposure and CtrlGain.
+func (s *Subdevice) ControlInfo(cid uint32) (_ ControlInfo, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return ControlInfo{}, err
+	}
+	defer s.mu.Unlock()
+	return s.queryControl(cid)
+}
+
+// ListControls returns the ControlInfo for every control the sub-device has.
+func (s *Subdevice) ListControls() (_ []ControlInfo, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return nil, err
+	}
+	defer s.mu.Unlock()
+	return s.listControls()
+}
+
+// GetControl returns the current value of a control.
+func (s *Subdevice) GetControl(cid uint32) (_ int32, err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return 0, err
+	}
+	defer s.mu.Unlock()
+	return s.getControl(cid)
+}
+
+// SetControl sets the value of a control.
+func (s *Subdevice) SetControl(cid uint32, value int32) (err error) {
+	defer s.annotate(&err)
+	if err := s.lock(); err != nil {
+		return err
+	}
+	defer s.mu.Unlock()
+	return s.setControl(cid, value)
+}
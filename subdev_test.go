@@ -0,0 +1,79 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"testing"
+	"unsafe"
+)
+
+// The sub-device structs only contain 32-bit fields, so their layout is the
+// same on every architecture.
+func TestSubdevLayout(t *testing.T) {
+	f := v4l_subdevFormat{
+		which: 1,
+		pad:   2,
+		format: v4l_mbusFramefmt{
+			width:      3,
+			height:     4,
+			code:       5,
+			field:      6,
+			colorspace: 7,
+		},
+	}
+	testLayout(t, &f, []uint32{1, 2, 3, 4, 5, 6, 7})
+
+	fi := v4l_subdevFrameInterval{pad: 1, interval: v4l_fract{2, 3}}
+	testLayout(t, &fi, []uint32{1, 2, 3})
+
+	sel := v4l_subdevSelection{
+		which:  1,
+		pad:    2,
+		target: 3,
+		flags:  4,
+		r:      v4l_rect{5, 6, 7, 8},
+	}
+	testLayout(t, &sel, []uint32{1, 2, 3, 4, 5, 6, 7, 8})
+
+	e := v4l_subdevMbusCodeEnum{pad: 1, index: 2, code: 3, which: 4}
+	testLayout(t, &e, []uint32{1, 2, 3, 4})
+}
+
+func testLayout(t *testing.T, arg ioctlArg, words []uint32) {
+	buf := make([]uint32, arg.size()/4)
+	arg.put(unsafe.Pointer(&buf[0]))
+	for i, w := range words {
+		if buf[i] != w {
+			t.Errorf("%T: word %d is %d, expected: %d\n", arg, i, buf[i], w)
+		}
+	}
+	for i := len(words); i < len(buf); i++ {
+		if buf[i] != 0 {
+			t.Errorf("%T: reserved word %d is %d\n", arg, i, buf[i])
+		}
+	}
+}
+
+func TestOpenSubdeviceWrongDevice(t *testing.T) {
+	// A character device, but not a V4L one.
+	if _, err := OpenSubdevice("/dev/null"); err != ErrNotSubdevice {
+		t.Errorf("got: %v, expected: %v\n", err, ErrNotSubdevice)
+	}
+}
@@ -0,0 +1,166 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// sysfsRoot and devRoot are where sysfs and the device nodes are found. They
+// are variables so that tests can replace them.
+var (
+	sysfsRoot = "/sys"
+	devRoot   = "/dev"
+)
+
+// devNumbers splits a device number into its major and minor parts.
+func devNumbers(rdev uint64) (major, minor int) {
+	major = int((rdev>>8)&0xfff | (rdev>>32)&^0xfff)
+	minor = int(rdev&0xff | (rdev>>12)&^0xff)
+	return major, minor
+}
+
+// readSysfsInfo fills in the fields of info that come from sysfs for the
+// character device major:minor. Any information that cannot be found is left
+// unchanged.
+func readSysfsInfo(info *DeviceInfo, major, minor int) {
+	dir := filepath.Join(sysfsRoot, "dev", "char",
+		strconv.Itoa(major)+":"+strconv.Itoa(minor))
+	if n, ok := readSysfsInt(filepath.Join(dir, "index"), 10); ok {
+		info.Index = n
+	}
+
+	// The device link points to the parent device. For USB cameras that's the
+	// interface, whose parent in turn is the USB device itself.
+	parent, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+	if err != nil {
+		return
+	}
+	info.Port = filepath.Base(parent)
+	if n, ok := readSysfsInt(filepath.Join(parent, "bInterfaceNumber"), 16); ok {
+		info.Interface = n
+	}
+	if m, _ := filepath.Glob(filepath.Join(parent, "media[0-9]*")); len(m) > 0 {
+		info.MediaNode = filepath.Join(devRoot, filepath.Base(m[0]))
+	}
+	for usb := parent; strings.HasPrefix(usb, sysfsRoot+"/"); usb = filepath.Dir(usb) {
+		vid, ok := readSysfsInt(filepath.Join(usb, "idVendor"), 16)
+		if !ok {
+			continue
+		}
+		pid, _ := readSysfsInt(filepath.Join(usb, "idProduct"), 16)
+		info.VendorID = uint16(vid)
+		info.ProductID = uint16(pid)
+		info.Serial = readSysfsString(filepath.Join(usb, "serial"))
+		info.Manufacturer = readSysfsString(filepath.Join(usb, "manufacturer"))
+		info.Port = filepath.Base(usb)
+		break
+	}
+
+	info.ByID = findLink(filepath.Join(devRoot, "v4l", "by-id"), info.Path)
+	info.ByPath = findLink(filepath.Join(devRoot, "v4l", "by-path"), info.Path)
+}
+
+// readSysfsString returns the contents of a sysfs attribute without the
+// trailing newline, or "" if it cannot be read.
+func readSysfsString(path string) string {
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// readSysfsInt reads a sysfs attribute holding an integer in the given base.
+func readSysfsInt(path string, base int) (int, bool) {
+	s := readSysfsString(path)
+	if s == "" {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(s, base, 0)
+	if err != nil {
+		return 0, false
+	}
+	return int(n), true
+}
+
+// findLink returns the first symlink in dir that resolves to the same file as
+// target, or "" if there is none.
+func findLink(dir, target string) string {
+	target, err := filepath.EvalSymlinks(target)
+	if err != nil {
+		return ""
+	}
+	entries, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return ""
+	}
+	for _, e := range entries {
+		if e.Mode()&os.ModeSymlink == 0 {
+			continue
+		}
+		link := filepath.Join(dir, e.Name())
+		if dst, err := filepath.EvalSymlinks(link); err == nil && dst == target {
+			return link
+		}
+	}
+	return ""
+}
+
+// OpenBySerial opens the main capture node of the USB device with the given
+// serial number. If there are several such devices, it opens the one found
+// first. It fails with ErrNoDevice if there are none.
+func OpenBySerial(serial string) (*Device, error) {
+	return openMatching(func(info *DeviceInfo) bool {
+		return serial != "" && info.Serial == serial
+	})
+}
+
+// OpenByPort opens the main capture node of the device plugged into the given
+// port. (see DeviceInfo.Port) It fails with ErrNoDevice if there is no such
+// device.
+func OpenByPort(port string) (*Device, error) {
+	return openMatching(func(info *DeviceInfo) bool {
+		return port != "" && info.Port == port
+	})
+}
+
+// openMatching opens the capture device with the lowest index among those for
+// which match returns true.
+func openMatching(match func(*DeviceInfo) bool) (*Device, error) {
+	var best *DeviceInfo
+	infos := FindDevices()
+	for i := range infos {
+		if !match(&infos[i]) {
+			continue
+		}
+		if best == nil || infos[i].Index < best.Index {
+			best = &infos[i]
+		}
+	}
+	if best == nil {
+		return nil, ErrNoDevice
+	}
+	return Open(best.Path)
+}
@@ -0,0 +1,118 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadSysfsInfo(t *testing.T) {
+	tmp, err := ioutil.TempDir("", "v4l")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	tmp, _ = filepath.EvalSymlinks(tmp)
+
+	defer func(s, d string) { sysfsRoot, devRoot = s, d }(sysfsRoot, devRoot)
+	sysfsRoot = filepath.Join(tmp, "sys")
+	devRoot = filepath.Join(tmp, "dev")
+
+	const (
+		usb   = "sys/devices/pci0000:00/0000:00:14.0/usb1/1-1/1-1.2"
+		intf  = usb + "/1-1.2:1.0"
+		video = intf + "/video4linux/video0"
+	)
+	files := map[string]string{
+		"sys/devices/pci0000:00/0000:00:14.0/usb1/idVendor": "1d6b\n",
+		usb + "/idVendor":          "046d\n",
+		usb + "/idProduct":         "0825\n",
+		usb + "/serial":            "ABC123\n",
+		usb + "/manufacturer":      "Yoyodyne\n",
+		intf + "/bInterfaceNumber": "02\n",
+		intf + "/media3/dev":       "239:3\n",
+		video + "/index":           "1\n",
+		"dev/video0":               "",
+	}
+	links := map[string]string{
+		"sys/dev/char/81:0": "../../devices/pci0000:00/0000:00:14.0/usb1/1-1/1-1.2/1-1.2:1.0/video4linux/video0",
+		video + "/device":   "../../../1-1.2:1.0",
+		"dev/v4l/by-id/usb-Yoyodyne_Cam_ABC123-video-index1":          "../../video0",
+		"dev/v4l/by-path/pci-0000:00:14.0-usb-0:1.2:1.0-video-index1": "../../video0",
+	}
+	for name, data := range files {
+		path := filepath.Join(tmp, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for name, target := range links {
+		path := filepath.Join(tmp, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Symlink(target, path); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	info := DeviceInfo{Path: filepath.Join(devRoot, "video0")}
+	readSysfsInfo(&info, 81, 0)
+	expected := DeviceInfo{
+		Path:         filepath.Join(devRoot, "video0"),
+		VendorID:     0x046d,
+		ProductID:    0x0825,
+		Serial:       "ABC123",
+		Manufacturer: "Yoyodyne",
+		Port:         "1-1.2",
+		Interface:    2,
+		Index:        1,
+		MediaNode:    filepath.Join(devRoot, "media3"),
+		ByID:         filepath.Join(devRoot, "v4l/by-id/usb-Yoyodyne_Cam_ABC123-video-index1"),
+		ByPath:       filepath.Join(devRoot, "v4l/by-path/pci-0000:00:14.0-usb-0:1.2:1.0-video-index1"),
+	}
+	if info != expected {
+		t.Errorf("got: %+v, expected: %+v\n", info, expected)
+	}
+}
+
+func TestDevNumbers(t *testing.T) {
+	for _, x := range []struct {
+		rdev         uint64
+		major, minor int
+	}{
+		{0x5100, 81, 0},
+		{0x5103, 81, 3},
+		{0x1051ff, 81, 0x1ff},
+		{0xdeff00, 0xeff, 0xd00},
+	} {
+		major, minor := devNumbers(x.rdev)
+		if major != x.major || minor != x.minor {
+			t.Errorf("got: %d:%d, expected: %d:%d (rdev=%#x)\n",
+				major, minor, x.major, x.minor, x.rdev)
+		}
+	}
+}
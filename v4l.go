@@ -17,9 +17,12 @@
 //go:build linux
 // +build linux
 
+//go:generate go run ./generate
+
 package v4l
 
 import (
+	"strconv"
 	"syscall"
 	"unsafe"
 )
@@ -27,14 +30,26 @@ import (
 // Constants.
 
 const (
-	v4l_capVideoCapture = 0x00000001
-	v4l_capDeviceCaps   = 0x80000000
+	v4l_capVideoCapture       = 0x00000001
+	v4l_capVideoOutput        = 0x00000002
+	v4l_capVideoCaptureMplane = 0x00001000
+	v4l_capVideoOutputMplane  = 0x00002000
+	v4l_capVideoM2MMplane     = 0x00004000
+	v4l_capVideoM2M           = 0x00008000
+	v4l_capMetaCapture        = 0x00800000
+	v4l_capStreaming          = 0x04000000
+	v4l_capMetaOutput         = 0x08000000
+	v4l_capDeviceCaps         = 0x80000000
 )
 
 const (
 	v4l_bufTypeVideoCapture = 1
 )
 
+const (
+	v4l_capTimeperframe = 0x1000
+)
+
 const (
 	v4l_colorspaceDefault = 0
 )
@@ -72,6 +87,10 @@ const (
 	v4l_ctrlTypeIntegerMenu = 9
 )
 
+const (
+	v4l_subdevFormatActive = 1
+)
+
 const (
 	v4l_cidBase        = 0x00980900
 	v4l_cidLastp1      = 0x0098092b
@@ -177,6 +196,40 @@ type v4l_rect struct {
 	height uint32
 }
 
+type v4l_subdevFormat struct {
+	which  uint32
+	pad    uint32
+	format v4l_mbusFramefmt
+}
+
+type v4l_mbusFramefmt struct {
+	width      uint32
+	height     uint32
+	code       uint32
+	field      uint32
+	colorspace uint32
+}
+
+type v4l_subdevFrameInterval struct {
+	pad      uint32
+	interval v4l_fract
+}
+
+type v4l_subdevSelection struct {
+	which  uint32
+	pad    uint32
+	target uint32
+	flags  uint32
+	r      v4l_rect
+}
+
+type v4l_subdevMbusCodeEnum struct {
+	pad   uint32
+	index uint32
+	code  uint32
+	which uint32
+}
+
 type v4l_standard struct {
 	index       uint32
 	id          uint64
@@ -254,93 +307,156 @@ type v4l_control struct {
 	value int32
 }
 
+type v4l_jpegcompression struct {
+	quality     int32
+	appn        int32
+	appLen      int32
+	appData     []byte
+	comLen      int32
+	comData     []byte
+	jpegMarkers uint32
+}
+
 // IOCTLs.
 
-func ioctl_querycap(fd int, argp *v4l_capability) error {
-	return ioctl(fd, vidioc_querycap, argp)
+func ioctl_querycap(b backend, argp *v4l_capability) error {
+	return ioctl(b, vidioc_querycap, argp)
+}
+
+func ioctl_gFmt_pix(b backend, argp *v4l_format_pix) error {
+	return ioctl(b, vidioc_gFmt, argp)
+}
+
+func ioctl_sFmt_pix(b backend, argp *v4l_format_pix) error {
+	return ioctl(b, vidioc_sFmt, argp)
+}
+
+func ioctl_gParm_capture(b backend, argp *v4l_streamparm_capture) error {
+	return ioctl(b, vidioc_gParm, argp)
+}
+
+func ioctl_sParm_capture(b backend, argp *v4l_streamparm_capture) error {
+	return ioctl(b, vidioc_sParm, argp)
+}
+
+func ioctl_reqbufs(b backend, argp *v4l_requestbuffers) error {
+	return ioctl(b, vidioc_reqbufs, argp)
+}
+
+func ioctl_querybuf(b backend, argp *v4l_buffer) error {
+	return ioctl(b, vidioc_querybuf, argp)
+}
+
+func ioctl_qbuf(b backend, argp *v4l_buffer) error {
+	return ioctl(b, vidioc_qbuf, argp)
+}
+
+func ioctl_dqbuf(b backend, argp *v4l_buffer) error {
+	return ioctl(b, vidioc_dqbuf, argp)
+}
+
+func ioctl_streamon(b backend, typ v4l_int) error {
+	return ioctl(b, vidioc_streamon, &typ)
 }
 
-func ioctl_gFmt_pix(fd int, argp *v4l_format_pix) error {
-	return ioctl(fd, vidioc_gFmt, argp)
+func ioctl_streamoff(b backend, typ v4l_int) error {
+	return ioctl(b, vidioc_streamoff, &typ)
 }
 
-func ioctl_sFmt_pix(fd int, argp *v4l_format_pix) error {
-	return ioctl(fd, vidioc_sFmt, argp)
+func ioctl_cropcap(b backend, argp *v4l_cropcap) error {
+	return ioctl(b, vidioc_cropcap, argp)
 }
 
-func ioctl_gParm_capture(fd int, argp *v4l_streamparm_capture) error {
-	return ioctl(fd, vidioc_gParm, argp)
+func ioctl_sCrop(b backend, argp *v4l_crop) error {
+	return ioctl(b, vidioc_sCrop, argp)
 }
 
-func ioctl_sParm_capture(fd int, argp *v4l_streamparm_capture) error {
-	return ioctl(fd, vidioc_sParm, argp)
+func ioctl_enumstd(b backend, argp *v4l_standard) error {
+	return ioctl(b, vidioc_enumstd, argp)
 }
 
-func ioctl_reqbufs(fd int, argp *v4l_requestbuffers) error {
-	return ioctl(fd, vidioc_reqbufs, argp)
+func ioctl_enumFmt(b backend, argp *v4l_fmtdesc) error {
+	return ioctl(b, vidioc_enumFmt, argp)
 }
 
-func ioctl_querybuf(fd int, argp *v4l_buffer) error {
-	return ioctl(fd, vidioc_querybuf, argp)
+func ioctl_enumFramesizes(b backend, argp *v4l_frmsizeenum) error {
+	return ioctl(b, vidioc_enumFramesizes, argp)
 }
 
-func ioctl_qbuf(fd int, argp *v4l_buffer) error {
-	return ioctl(fd, vidioc_qbuf, argp)
+func ioctl_enumFrameintervals(b backend, argp *v4l_frmivalenum) error {
+	return ioctl(b, vidioc_enumFrameintervals, argp)
 }
 
-func ioctl_dqbuf(fd int, argp *v4l_buffer) error {
-	return ioctl(fd, vidioc_dqbuf, argp)
+func ioctl_queryctrl(b backend, argp *v4l_queryctrl) error {
+	return ioctl(b, vidioc_queryctrl, argp)
 }
 
-func ioctl_streamon(fd int, typ v4l_int) error {
-	return ioctl(fd, vidioc_streamon, &typ)
+func ioctl_querymenu(b backend, argp *v4l_querymenu) error {
+	return ioctl(b, vidioc_querymenu, argp)
 }
 
-func ioctl_streamoff(fd int, typ v4l_int) error {
-	return ioctl(fd, vidioc_streamoff, &typ)
+func ioctl_gCtrl(b backend, argp *v4l_control) error {
+	return ioctl(b, vidioc_gCtrl, argp)
 }
 
-func ioctl_cropcap(fd int, argp *v4l_cropcap) error {
-	return ioctl(fd, vidioc_cropcap, argp)
+func ioctl_sCtrl(b backend, argp *v4l_control) error {
+	return ioctl(b, vidioc_sCtrl, argp)
 }
 
-func ioctl_sCrop(fd int, argp *v4l_crop) error {
-	return ioctl(fd, vidioc_sCrop, argp)
+func ioctl_gJpegcomp(b backend, argp *v4l_jpegcompression) error {
+	return ioctl(b, vidioc_gJpegcomp, argp)
 }
 
-func ioctl_enumstd(fd int, argp *v4l_standard) error {
-	return ioctl(fd, vidioc_enumstd, argp)
+func ioctl_sJpegcomp(b backend, argp *v4l_jpegcompression) error {
+	return ioctl(b, vidioc_sJpegcomp, argp)
 }
 
-func ioctl_enumFmt(fd int, argp *v4l_fmtdesc) error {
-	return ioctl(fd, vidioc_enumFmt, argp)
+func ioctl_gPriority(b backend, argp *v4l_int) error {
+	return ioctl(b, vidioc_gPriority, argp)
 }
 
-func ioctl_enumFramesizes(fd int, argp *v4l_frmsizeenum) error {
-	return ioctl(fd, vidioc_enumFramesizes, argp)
+func ioctl_sPriority(b backend, prio v4l_int) error {
+	return ioctl(b, vidioc_sPriority, &prio)
 }
 
-func ioctl_enumFrameintervals(fd int, argp *v4l_frmivalenum) error {
-	return ioctl(fd, vidioc_enumFrameintervals, argp)
+func ioctl_subdevGFmt(b backend, argp *v4l_subdevFormat) error {
+	return ioctl(b, vidioc_subdevGFmt, argp)
 }
 
-func ioctl_queryctrl(fd int, argp *v4l_queryctrl) error {
-	return ioctl(fd, vidioc_queryctrl, argp)
+func ioctl_subdevSFmt(b backend, argp *v4l_subdevFormat) error {
+	return ioctl(b, vidioc_subdevSFmt, argp)
 }
 
-func ioctl_querymenu(fd int, argp *v4l_querymenu) error {
-	return ioctl(fd, vidioc_querymenu, argp)
+func ioctl_subdevGFrameInterval(b backend, argp *v4l_subdevFrameInterval) error {
+	return ioctl(b, vidioc_subdevGFrameInterval, argp)
 }
 
-func ioctl_gCtrl(fd int, argp *v4l_control) error {
-	return ioctl(fd, vidioc_gCtrl, argp)
+func ioctl_subdevSFrameInterval(b backend, argp *v4l_subdevFrameInterval) error {
+	return ioctl(b, vidioc_subdevSFrameInterval, argp)
 }
 
-func ioctl_sCtrl(fd int, argp *v4l_control) error {
-	return ioctl(fd, vidioc_sCtrl, argp)
+func ioctl_subdevGSelection(b backend, argp *v4l_subdevSelection) error {
+	return ioctl(b, vidioc_subdevGSelection, argp)
 }
 
-func ioctl(fd int, request uint, argp ioctlArg) error {
+func ioctl_subdevSSelection(b backend, argp *v4l_subdevSelection) error {
+	return ioctl(b, vidioc_subdevSSelection, argp)
+}
+
+func ioctl_subdevEnumMbusCode(b backend, argp *v4l_subdevMbusCodeEnum) error {
+	return ioctl(b, vidioc_subdevEnumMbusCode, argp)
+}
+
+// ioctl performs an IOCTL on b, and wraps any error in an OpError.
+func ioctl(b backend, request uint, argp ioctlArg) error {
+	if err := b.ioctl(request, argp); err != nil {
+		return &OpError{Op: ioctlName(request), Err: err}
+	}
+	return nil
+}
+
+// sysIoctl performs an IOCTL on a file descriptor.
+func sysIoctl(fd int, request uint, argp ioctlArg) error {
 	buf := make([]uint64, (argp.size()+7)/8)
 	p := unsafe.Pointer(&buf[0])
 	argp.put(p)
@@ -353,6 +469,85 @@ func ioctl(fd int, request uint, argp ioctlArg) error {
 	return nil
 }
 
+// pollFd is struct pollfd. Its layout is the same on every architecture.
+type pollFd struct {
+	fd      int32
+	events  int16
+	revents int16
+}
+
+const pollIn = 0x0001
+
+// poll blocks until one of the file descriptors becomes readable, or an error
+// condition occurs on one of them. It reports which ones are readable.
+// Negative file descriptors are ignored.
+func poll(fds ...int) ([]bool, error) {
+	pfds := make([]pollFd, len(fds))
+	for i, fd := range fds {
+		pfds[i] = pollFd{fd: int32(fd), events: pollIn}
+	}
+	for {
+		_, _, err := syscall.Syscall6(syscall.SYS_PPOLL,
+			uintptr(unsafe.Pointer(&pfds[0])), uintptr(len(pfds)), 0, 0, 0, 0)
+		switch err {
+		case 0:
+			ready := make([]bool, len(fds))
+			for i := range pfds {
+				ready[i] = pfds[i].revents&pollIn != 0
+			}
+			return ready, nil
+		case syscall.EINTR:
+			continue
+		default:
+			return nil, &OpError{Op: "ppoll", Err: err}
+		}
+	}
+}
+
+// ioctlNames maps IOCTL numbers to their names as used in OpError.
+var ioctlNames = map[uint]string{
+	vidioc_querycap:             "VIDIOC_QUERYCAP",
+	vidioc_gFmt:                 "VIDIOC_G_FMT",
+	vidioc_sFmt:                 "VIDIOC_S_FMT",
+	vidioc_gParm:                "VIDIOC_G_PARM",
+	vidioc_sParm:                "VIDIOC_S_PARM",
+	vidioc_reqbufs:              "VIDIOC_REQBUFS",
+	vidioc_querybuf:             "VIDIOC_QUERYBUF",
+	vidioc_qbuf:                 "VIDIOC_QBUF",
+	vidioc_dqbuf:                "VIDIOC_DQBUF",
+	vidioc_streamon:             "VIDIOC_STREAMON",
+	vidioc_streamoff:            "VIDIOC_STREAMOFF",
+	vidioc_cropcap:              "VIDIOC_CROPCAP",
+	vidioc_sCrop:                "VIDIOC_S_CROP",
+	vidioc_enumstd:              "VIDIOC_ENUMSTD",
+	vidioc_enumFmt:              "VIDIOC_ENUM_FMT",
+	vidioc_enumFramesizes:       "VIDIOC_ENUM_FRAMESIZES",
+	vidioc_enumFrameintervals:   "VIDIOC_ENUM_FRAMEINTERVALS",
+	vidioc_queryctrl:            "VIDIOC_QUERYCTRL",
+	vidioc_querymenu:            "VIDIOC_QUERYMENU",
+	vidioc_gCtrl:                "VIDIOC_G_CTRL",
+	vidioc_sCtrl:                "VIDIOC_S_CTRL",
+	vidioc_gPriority:            "VIDIOC_G_PRIORITY",
+	vidioc_sPriority:            "VIDIOC_S_PRIORITY",
+	vidioc_gJpegcomp:            "VIDIOC_G_JPEGCOMP",
+	vidioc_sJpegcomp:            "VIDIOC_S_JPEGCOMP",
+	vidioc_subdevGFmt:           "VIDIOC_SUBDEV_G_FMT",
+	vidioc_subdevSFmt:           "VIDIOC_SUBDEV_S_FMT",
+	vidioc_subdevGFrameInterval: "VIDIOC_SUBDEV_G_FRAME_INTERVAL",
+	vidioc_subdevSFrameInterval: "VIDIOC_SUBDEV_S_FRAME_INTERVAL",
+	vidioc_subdevGSelection:     "VIDIOC_SUBDEV_G_SELECTION",
+	vidioc_subdevSSelection:     "VIDIOC_SUBDEV_S_SELECTION",
+	vidioc_subdevEnumMbusCode:   "VIDIOC_SUBDEV_ENUM_MBUS_CODE",
+}
+
+// ioctlName returns the name of an IOCTL.
+func ioctlName(request uint) string {
+	if name, ok := ioctlNames[request]; ok {
+		return name
+	}
+	return "ioctl 0x" + strconv.FormatUint(uint64(request), 16)
+}
+
 // Getters and putters.
 
 type ioctlArg interface {
@@ -595,6 +790,90 @@ func (p *v4l_rect) put(q unsafe.Pointer) {
 	putUint32(q, offs_rect_height, p.height)
 }
 
+func (p *v4l_subdevFormat) get(q unsafe.Pointer) {
+	p.which = getUint32(q, offs_subdevFormat_which)
+	p.pad = getUint32(q, offs_subdevFormat_pad)
+	p.format.get(unsafe.Pointer(uintptr(q) + offs_subdevFormat_format))
+}
+
+func (p *v4l_subdevFormat) put(q unsafe.Pointer) {
+	putUint32(q, offs_subdevFormat_which, p.which)
+	putUint32(q, offs_subdevFormat_pad, p.pad)
+	p.format.put(unsafe.Pointer(uintptr(q) + offs_subdevFormat_format))
+}
+
+func (p *v4l_subdevFormat) size() int {
+	return size_subdevFormat
+}
+
+func (p *v4l_mbusFramefmt) get(q unsafe.Pointer) {
+	p.width = getUint32(q, offs_mbusFramefmt_width)
+	p.height = getUint32(q, offs_mbusFramefmt_height)
+	p.code = getUint32(q, offs_mbusFramefmt_code)
+	p.field = getUint32(q, offs_mbusFramefmt_field)
+	p.colorspace = getUint32(q, offs_mbusFramefmt_colorspace)
+}
+
+func (p *v4l_mbusFramefmt) put(q unsafe.Pointer) {
+	putUint32(q, offs_mbusFramefmt_width, p.width)
+	putUint32(q, offs_mbusFramefmt_height, p.height)
+	putUint32(q, offs_mbusFramefmt_code, p.code)
+	putUint32(q, offs_mbusFramefmt_field, p.field)
+	putUint32(q, offs_mbusFramefmt_colorspace, p.colorspace)
+}
+
+func (p *v4l_subdevFrameInterval) get(q unsafe.Pointer) {
+	p.pad = getUint32(q, offs_subdevFrameInterval_pad)
+	p.interval.get(unsafe.Pointer(uintptr(q) + offs_subdevFrameInterval_interval))
+}
+
+func (p *v4l_subdevFrameInterval) put(q unsafe.Pointer) {
+	putUint32(q, offs_subdevFrameInterval_pad, p.pad)
+	p.interval.put(unsafe.Pointer(uintptr(q) + offs_subdevFrameInterval_interval))
+}
+
+func (p *v4l_subdevFrameInterval) size() int {
+	return size_subdevFrameInterval
+}
+
+func (p *v4l_subdevSelection) get(q unsafe.Pointer) {
+	p.which = getUint32(q, offs_subdevSelection_which)
+	p.pad = getUint32(q, offs_subdevSelection_pad)
+	p.target = getUint32(q, offs_subdevSelection_target)
+	p.flags = getUint32(q, offs_subdevSelection_flags)
+	p.r.get(unsafe.Pointer(uintptr(q) + offs_subdevSelection_r))
+}
+
+func (p *v4l_subdevSelection) put(q unsafe.Pointer) {
+	putUint32(q, offs_subdevSelection_which, p.which)
+	putUint32(q, offs_subdevSelection_pad, p.pad)
+	putUint32(q, offs_subdevSelection_target, p.target)
+	putUint32(q, offs_subdevSelection_flags, p.flags)
+	p.r.put(unsafe.Pointer(uintptr(q) + offs_subdevSelection_r))
+}
+
+func (p *v4l_subdevSelection) size() int {
+	return size_subdevSelection
+}
+
+func (p *v4l_subdevMbusCodeEnum) get(q unsafe.Pointer) {
+	p.pad = getUint32(q, offs_subdevMbusCodeEnum_pad)
+	p.index = getUint32(q, offs_subdevMbusCodeEnum_index)
+	p.code = getUint32(q, offs_subdevMbusCodeEnum_code)
+	p.which = getUint32(q, offs_subdevMbusCodeEnum_which)
+}
+
+func (p *v4l_subdevMbusCodeEnum) put(q unsafe.Pointer) {
+	putUint32(q, offs_subdevMbusCodeEnum_pad, p.pad)
+	putUint32(q, offs_subdevMbusCodeEnum_index, p.index)
+	putUint32(q, offs_subdevMbusCodeEnum_code, p.code)
+	putUint32(q, offs_subdevMbusCodeEnum_which, p.which)
+}
+
+func (p *v4l_subdevMbusCodeEnum) size() int {
+	return size_subdevMbusCodeEnum
+}
+
 func (p *v4l_standard) get(q unsafe.Pointer) {
 	p.index = getUint32(q, offs_standard_index)
 	p.id = getUint64(q, offs_standard_id)
@@ -784,6 +1063,30 @@ func (p *v4l_control) size() int {
 	return size_control
 }
 
+func (p *v4l_jpegcompression) get(q unsafe.Pointer) {
+	p.quality = getInt32(q, offs_jpegcompression_quality)
+	p.appn = getInt32(q, offs_jpegcompression_appn)
+	p.appLen = getInt32(q, offs_jpegcompression_appLen)
+	p.appData = getBytes(q, offs_jpegcompression_appData, size_jpegcompression_appData)
+	p.comLen = getInt32(q, offs_jpegcompression_comLen)
+	p.comData = getBytes(q, offs_jpegcompression_comData, size_jpegcompression_comData)
+	p.jpegMarkers = getUint32(q, offs_jpegcompression_jpegMarkers)
+}
+
+func (p *v4l_jpegcompression) put(q unsafe.Pointer) {
+	putInt32(q, offs_jpegcompression_quality, p.quality)
+	putInt32(q, offs_jpegcompression_appn, p.appn)
+	putInt32(q, offs_jpegcompression_appLen, p.appLen)
+	putBytes(q, offs_jpegcompression_appData, size_jpegcompression_appData, p.appData)
+	putInt32(q, offs_jpegcompression_comLen, p.comLen)
+	putBytes(q, offs_jpegcompression_comData, size_jpegcompression_comData, p.comData)
+	putUint32(q, offs_jpegcompression_jpegMarkers, p.jpegMarkers)
+}
+
+func (p *v4l_jpegcompression) size() int {
+	return size_jpegcompression
+}
+
 // Getters and putters for built-in types.
 
 func getUint64(base unsafe.Pointer, offset int) uint64 {
@@ -883,3 +1186,22 @@ func putString(base unsafe.Pointer, offset, maxLen int, value string) {
 		*ptr = ch
 	}
 }
+
+func getBytes(base unsafe.Pointer, offset, n int) []byte {
+	buf := make([]byte, n)
+	for i := range buf {
+		buf[i] = *(*byte)(unsafe.Pointer(uintptr(base) + uintptr(offset+i)))
+	}
+	return buf
+}
+
+func putBytes(base unsafe.Pointer, offset, n int, value []byte) {
+	for i := 0; i < n; i++ {
+		ptr := (*byte)(unsafe.Pointer(uintptr(base) + uintptr(offset+i)))
+		var b byte
+		if i < len(value) {
+			b = value[i]
+		}
+		*ptr = b
+	}
+}
@@ -0,0 +1,365 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+	"unsafe"
+)
+
+// Device event types.
+const (
+	DeviceAdded   = 1
+	DeviceRemoved = 2
+)
+
+// A DeviceEvent reports that a capture device has appeared in or disappeared
+// from the system.
+type DeviceEvent struct {
+	// Type is either DeviceAdded or DeviceRemoved.
+	Type int
+
+	// Info describes the device. For removed devices, it's the same value
+	// that was reported when the device was added.
+	Info DeviceInfo
+}
+
+// A Uevent is a raw device node notification, as delivered by a UeventSource.
+type Uevent struct {
+	// Action is the kind of change. (e.g. "add", "remove", "change")
+	Action string
+
+	// Subsystem is the kernel subsystem of the device. (e.g. "video4linux")
+	Subsystem string
+
+	// DevName is the device node name relative to /dev. (e.g. "video0")
+	DevName string
+}
+
+// A UeventSource delivers device node notifications to a DeviceWatcher.
+// Implementations other than the ones in this package are mostly useful for
+// testing.
+type UeventSource interface {
+	// ReadUevent blocks until the next notification is available. It returns
+	// an error after the source has been closed.
+	ReadUevent() (Uevent, error)
+
+	// Close releases the resources associated with the source, and unblocks
+	// any ReadUevent call in progress.
+	Close() error
+}
+
+// A DeviceWatcher watches the system for capture devices being plugged in or
+// removed.
+type DeviceWatcher struct {
+	events chan DeviceEvent
+	done   chan struct{}
+	wg     sync.WaitGroup
+	src    UeventSource
+	known  map[string]DeviceInfo
+	once   sync.Once
+}
+
+// probeRetries and probeDelay control how long the watcher keeps trying to
+// open a newly created device node. Udev may need some time to set the final
+// permissions.
+const (
+	probeRetries = 10
+	probeDelay   = 100 * time.Millisecond
+)
+
+// probeDevice returns the DeviceInfo for the capture device at path. It gives
+// up retrying when done is closed, so that closing the watcher doesn't wait
+// for it. It's a variable so that tests can replace it.
+var probeDevice = func(path string, done <-chan struct{}) (DeviceInfo, error) {
+	var err error
+	for i := 0; i < probeRetries; i++ {
+		var dev *Device
+		dev, err = Open(path)
+		switch err {
+		case nil:
+			defer dev.Close()
+			return dev.DeviceInfo()
+		case ErrWrongDevice:
+			return DeviceInfo{}, err
+		}
+		select {
+		case <-time.After(probeDelay):
+		case <-done:
+			return DeviceInfo{}, err
+		}
+	}
+	return DeviceInfo{}, err
+}
+
+// WatchDevices starts watching for capture devices. It listens to kernel
+// uevents, and if that is not possible, it falls back to watching /dev with
+// inotify. Devices already present in the system are reported as DeviceAdded
+// events first.
+func WatchDevices() (*DeviceWatcher, error) {
+	src, err := NetlinkUeventSource()
+	if err != nil {
+		src, err = InotifyUeventSource("/dev")
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newDeviceWatcher(src, FindDevices()), nil
+}
+
+// NewDeviceWatcher returns a DeviceWatcher that takes its notifications from
+// src. Unlike WatchDevices, it does not report the devices already present in
+// the system.
+func NewDeviceWatcher(src UeventSource) *DeviceWatcher {
+	return newDeviceWatcher(src, nil)
+}
+
+func newDeviceWatcher(src UeventSource, initial []DeviceInfo) *DeviceWatcher {
+	w := &DeviceWatcher{
+		events: make(chan DeviceEvent, 16),
+		done:   make(chan struct{}),
+		src:    src,
+		known:  make(map[string]DeviceInfo),
+	}
+	w.wg.Add(1)
+	go w.run(initial)
+	return w
+}
+
+// Events returns the channel on which events are delivered. The channel is
+// closed when the watcher is closed or the event source fails.
+func (w *DeviceWatcher) Events() <-chan DeviceEvent {
+	return w.events
+}
+
+// Close stops watching and closes the event source. Events not yet received
+// are discarded.
+func (w *DeviceWatcher) Close() {
+	w.once.Do(func() {
+		close(w.done)
+		w.src.Close()
+	})
+	w.wg.Wait()
+}
+
+// run is the main loop of the watcher.
+func (w *DeviceWatcher) run(initial []DeviceInfo) {
+	defer w.wg.Done()
+	defer close(w.events)
+
+	for _, info := range initial {
+		w.known[info.Path] = info
+		if !w.send(DeviceEvent{DeviceAdded, info}) {
+			return
+		}
+	}
+
+	for {
+		ev, err := w.src.ReadUevent()
+		if err != nil {
+			return
+		}
+		if ev.Subsystem != "video4linux" || !strings.HasPrefix(ev.DevName, "video") {
+			continue
+		}
+		path := filepath.Join("/dev", ev.DevName)
+		switch ev.Action {
+		case "add":
+			if _, ok := w.known[path]; ok {
+				continue
+			}
+			info, err := probeDevice(path, w.done)
+			if err != nil {
+				continue
+			}
+			w.known[path] = info
+			if !w.send(DeviceEvent{DeviceAdded, info}) {
+				return
+			}
+		case "remove":
+			info, ok := w.known[path]
+			if !ok {
+				continue
+			}
+			delete(w.known, path)
+			if !w.send(DeviceEvent{DeviceRemoved, info}) {
+				return
+			}
+		}
+	}
+}
+
+// send delivers an event. It returns false if the watcher has been closed.
+func (w *DeviceWatcher) send(ev DeviceEvent) bool {
+	select {
+	case w.events <- ev:
+		return true
+	case <-w.done:
+		return false
+	}
+}
+
+// netlinkSource is a UeventSource listening to kernel uevents on a netlink
+// socket.
+type netlinkSource struct {
+	f   *os.File
+	buf []byte
+}
+
+// NetlinkUeventSource returns a UeventSource that listens to kernel uevents on
+// a netlink socket.
+func NetlinkUeventSource() (UeventSource, error) {
+	fd, err := syscall.Socket(syscall.AF_NETLINK,
+		syscall.SOCK_DGRAM|syscall.SOCK_CLOEXEC|syscall.SOCK_NONBLOCK,
+		syscall.NETLINK_KOBJECT_UEVENT)
+	if err != nil {
+		return nil, err
+	}
+	sa := syscall.SockaddrNetlink{
+		Family: syscall.AF_NETLINK,
+		Groups: 1, // kernel events
+	}
+	if err := syscall.Bind(fd, &sa); err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	src := &netlinkSource{
+		f:   os.NewFile(uintptr(fd), "uevent"),
+		buf: make([]byte, 8192),
+	}
+	return src, nil
+}
+
+func (s *netlinkSource) ReadUevent() (Uevent, error) {
+	for {
+		n, err := s.f.Read(s.buf)
+		if err != nil {
+			return Uevent{}, err
+		}
+		if ev, ok := parseUevent(s.buf[:n]); ok {
+			return ev, nil
+		}
+	}
+}
+
+func (s *netlinkSource) Close() error {
+	return s.f.Close()
+}
+
+// parseUevent parses a kernel uevent message of the form
+// "action@devpath\0KEY=VALUE\0KEY=VALUE\0...".
+func parseUevent(msg []byte) (Uevent, bool) {
+	fields := bytes.Split(msg, []byte{0})
+	if len(fields) == 0 || bytes.IndexByte(fields[0], '@') < 0 {
+		// Not a kernel message. (e.g. libudev)
+		return Uevent{}, false
+	}
+	var ev Uevent
+	for _, f := range fields[1:] {
+		i := bytes.IndexByte(f, '=')
+		if i < 0 {
+			continue
+		}
+		val := string(f[i+1:])
+		switch string(f[:i]) {
+		case "ACTION":
+			ev.Action = val
+		case "SUBSYSTEM":
+			ev.Subsystem = val
+		case "DEVNAME":
+			ev.DevName = val
+		}
+	}
+	if ev.Action == "" || ev.DevName == "" {
+		return Uevent{}, false
+	}
+	return ev, true
+}
+
+// inotifySource is a UeventSource watching a directory with inotify.
+type inotifySource struct {
+	f       *os.File
+	buf     []byte
+	pending []Uevent
+}
+
+// InotifyUeventSource returns a UeventSource that watches dir (normally /dev)
+// for device nodes being created or deleted. Since inotify does not tell the
+// subsystem, it reports every node whose name starts with "video" as a
+// video4linux device.
+func InotifyUeventSource(dir string) (UeventSource, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, err
+	}
+	_, err = syscall.InotifyAddWatch(fd, dir, syscall.IN_CREATE|syscall.IN_DELETE)
+	if err != nil {
+		syscall.Close(fd)
+		return nil, err
+	}
+	src := &inotifySource{
+		f:   os.NewFile(uintptr(fd), "inotify"),
+		buf: make([]byte, 4096),
+	}
+	return src, nil
+}
+
+func (s *inotifySource) ReadUevent() (Uevent, error) {
+	for len(s.pending) == 0 {
+		n, err := s.f.Read(s.buf)
+		if err != nil {
+			return Uevent{}, err
+		}
+		for i := 0; i+syscall.SizeofInotifyEvent <= n; {
+			ie := (*syscall.InotifyEvent)(unsafe.Pointer(&s.buf[i]))
+			name := s.buf[i+syscall.SizeofInotifyEvent : i+syscall.SizeofInotifyEvent+int(ie.Len)]
+			if j := bytes.IndexByte(name, 0); j >= 0 {
+				name = name[:j]
+			}
+			i += syscall.SizeofInotifyEvent + int(ie.Len)
+			ev := Uevent{
+				Subsystem: "video4linux",
+				DevName:   string(name),
+			}
+			switch {
+			case ie.Mask&syscall.IN_CREATE != 0:
+				ev.Action = "add"
+			case ie.Mask&syscall.IN_DELETE != 0:
+				ev.Action = "remove"
+			default:
+				continue
+			}
+			s.pending = append(s.pending, ev)
+		}
+	}
+	ev := s.pending[0]
+	s.pending = s.pending[1:]
+	return ev, nil
+}
+
+func (s *inotifySource) Close() error {
+	return s.f.Close()
+}
@@ -0,0 +1,138 @@
+// Package v4l, a facade to the Video4Linux video capture interface
+// Copyright (C) 2016 Zoltán Korándi <korandi.z@gmail.com>
+//
+// This program is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// This program is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with this program.  If not, see <http://www.gnu.org/licenses/>.
+
+//go:build linux
+// +build linux
+
+package v4l
+
+import (
+	"io"
+	"testing"
+	"time"
+)
+
+type testUeventSource struct {
+	ch   chan Uevent
+	done chan struct{}
+}
+
+func newTestUeventSource() *testUeventSource {
+	return &testUeventSource{
+		ch:   make(chan Uevent),
+		done: make(chan struct{}),
+	}
+}
+
+func (s *testUeventSource) ReadUevent() (Uevent, error) {
+	select {
+	case ev := <-s.ch:
+		return ev, nil
+	case <-s.done:
+		return Uevent{}, io.EOF
+	}
+}
+
+func (s *testUeventSource) Close() error {
+	close(s.done)
+	return nil
+}
+
+func TestDeviceWatcher(t *testing.T) {
+	defer func(f func(string, <-chan struct{}) (DeviceInfo, error)) { probeDevice = f }(probeDevice)
+	probeDevice = func(path string, done <-chan struct{}) (DeviceInfo, error) {
+		if path == "/dev/video1" {
+			return DeviceInfo{}, ErrWrongDevice
+		}
+		return DeviceInfo{Path: path, DeviceName: "cam " + path}, nil
+	}
+
+	src := newTestUeventSource()
+	w := NewDeviceWatcher(src)
+	defer w.Close()
+
+	for _, ev := range []Uevent{
+		{"add", "video4linux", "video0"},
+		{"add", "video4linux", "video1"},      // not a capture device
+		{"add", "sound", "controlC0"},         // wrong subsystem
+		{"add", "video4linux", "v4l-subdev0"}, // not a video node
+		{"remove", "video4linux", "video1"},   // never added
+		{"remove", "video4linux", "video0"},
+		{"add", "video4linux", "video2"},
+	} {
+		src.ch <- ev
+	}
+
+	expected := []DeviceEvent{
+		{DeviceAdded, DeviceInfo{Path: "/dev/video0", DeviceName: "cam /dev/video0"}},
+		{DeviceRemoved, DeviceInfo{Path: "/dev/video0", DeviceName: "cam /dev/video0"}},
+		{DeviceAdded, DeviceInfo{Path: "/dev/video2", DeviceName: "cam /dev/video2"}},
+	}
+	for i, e := range expected {
+		select {
+		case ev := <-w.Events():
+			if ev != e {
+				t.Errorf("got: %+v, expected: %+v (i=%d)\n", ev, e, i)
+				return
+			}
+		case <-time.After(time.Second):
+			t.Errorf("timeout waiting for event (i=%d)\n", i)
+			return
+		}
+	}
+
+	w.Close()
+	if _, ok := <-w.Events(); ok {
+		t.Error("event channel not closed")
+	}
+}
+
+func TestDeviceWatcherCloseWhileProbing(t *testing.T) {
+	// The real probeDevice keeps retrying, since the node doesn't exist.
+	src := newTestUeventSource()
+	w := NewDeviceWatcher(src)
+	src.ch <- Uevent{"add", "video4linux", "video4242"}
+	time.Sleep(2 * probeDelay)
+
+	start := time.Now()
+	w.Close()
+	if d := time.Since(start); d > probeDelay*probeRetries/2 {
+		t.Errorf("Close waited %v for the probe\n", d)
+	}
+}
+
+func TestParseUevent(t *testing.T) {
+	msg := "add@/devices/pci0000:00/usb1/1-1/1-1:1.0/video4linux/video0\x00" +
+		"ACTION=add\x00" +
+		"DEVPATH=/devices/pci0000:00/usb1/1-1/1-1:1.0/video4linux/video0\x00" +
+		"SUBSYSTEM=video4linux\x00" +
+		"MAJOR=81\x00" +
+		"MINOR=0\x00" +
+		"DEVNAME=video0\x00" +
+		"SEQNUM=4242\x00"
+	ev, ok := parseUevent([]byte(msg))
+	if !ok {
+		t.Error("failed to parse uevent")
+		return
+	}
+	if e := (Uevent{"add", "video4linux", "video0"}); ev != e {
+		t.Errorf("got: %+v, expected: %+v\n", ev, e)
+	}
+
+	if _, ok := parseUevent([]byte("libudev\x00\xfe\xed\xca\xfe")); ok {
+		t.Error("parsed libudev message")
+	}
+}